@@ -2,20 +2,25 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
 	"flag"
 	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg/client"
 	"github.com/StanislavStefanov/Battleships/pkg/game"
 	"github.com/StanislavStefanov/Battleships/pkg/web"
-	"github.com/gorilla/websocket"
+	"golang.org/x/term"
+	"io"
 	"log"
-	"net/url"
 	"os"
-	"strconv"
+	"sort"
 	"strings"
+	"sync"
 )
 
 var addr = flag.String("addr", "localhost:8080", "http service address")
+var encoding = flag.String("encoding", web.JSON, "encoding of the messages (json, msgpack)")
+var plain = flag.Bool("plain", false, "use the line based console instead of the full-screen terminal UI")
+var local = flag.String("local", "", "play without the server: hotseat for two players on one terminal, ai against the computer")
+var rules = flag.String("rules", game.Classic, "rule set of the local game")
 
 const (
 	Register     = "register"
@@ -34,230 +39,303 @@ const (
 	Created      = "created"
 	Join         = "join-room"
 	JoinRandom   = "join-random"
+	Spectate     = "spectate"
+	Chat         = "chat"
+	Mute         = "mute"
+	Rematch      = "rematch"
+	Lobby        = "lobby"
+	Ack          = "ack"
+	State        = "state"
 )
 
-type Client struct {
-	id    string
-	conn  *websocket.Conn
-	board *game.Board
+//console is the line based client. It prints every response together with the board and remembers
+//the last listed rooms, so their IDs can be completed.
+type console struct {
+	client  *client.Client
+	out     io.Writer
+	roomIds []string
+	mu      sync.Mutex
 }
 
-func printMessage(resp web.Response) {
-	fmt.Println("---------------------")
-	fmt.Println("Status: ", resp.GetAction())
+//getRoomIds returns the IDs of the last listed rooms, which are offered by the tab completion.
+func (c *console) getRoomIds() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.roomIds
+}
+
+func (c *console) setRoomIds(ids []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.roomIds = ids
+}
+
+func printMessage(w io.Writer, resp web.Response) {
+	fmt.Fprintln(w, "---------------------")
+	fmt.Fprintln(w, "Status: ", resp.GetAction())
 	if len(resp.GetMessage()) > 0 {
-		fmt.Println("Message: ", resp.GetMessage())
+		fmt.Fprintln(w, "Message: ", resp.GetMessage())
+	}
+	if len(resp.GetCode()) > 0 {
+		fmt.Fprintln(w, "Code: ", resp.GetCode())
+	}
+	if len(resp.GetRequestId()) > 0 {
+		fmt.Fprintln(w, "Request: ", resp.GetRequestId())
 	}
 	if len(resp.GetArgs()) != 0 {
-		fmt.Println("Additional info: ", resp.GetArgs())
+		fmt.Fprintln(w, "Additional info: ", resp.GetArgs())
 	}
 }
 
-func (c *Client) processResponse(resp web.Response) {
-	printMessage(resp)
-
-	switch resp.GetAction() {
-	case Register:
-		c.id = resp.Args["id"].(string)
-	case PlaceShip:
-		c.board.Print()
-	case Placed:
-		c.placeShip(resp)
-		c.board.Print()
-	case ShootOutcome:
-		c.processShootOutcome(resp)
-		c.board.Print()
-	case Shoot:
-		c.receiveAttack(resp)
-		c.board.Print()
+func (c *console) processEvent(event client.Event) {
+	if event.GetAction() != "" {
+		printMessage(c.out, event.Response)
+	}
+	if event.Err != nil {
+		fmt.Fprintln(c.out, event.Err)
 	}
-}
 
-func extractCoordinates(resp web.Response) (int, int) {
-	x := int(resp.Args["x"].(float64))
-	y := int(resp.Args["y"].(float64))
-	return x, y
+	switch event.GetAction() {
+	case Info:
+		c.printRooms(event.Response)
+	case PlaceShip, Placed:
+		c.client.FprintBoard(c.out)
+	case ShootOutcome, Shoot:
+		c.client.FprintBoard(c.out)
+		c.printFleet()
+	case State:
+		c.printState(event.Response)
+	case Win, Lose:
+		c.printSummary(event.Response)
+	}
 }
 
-func (c *Client) placeShip(resp web.Response) {
-	x, y := extractCoordinates(resp)
-	direction := resp.Args["direction"].(string)
-	length := int(resp.Args["length"].(float64))
+//printSummary prints the revealed boards of both players and their stats after the game.
+func (c *console) printSummary(resp web.Response) {
+	summary, ok := client.DecodeSummary(resp)
+	if !ok {
+		return
+	}
+	for _, p := range []struct {
+		result  string
+		summary *web.PlayerSummary
+	}{{"winner", summary.Winner}, {"loser", summary.Loser}} {
+		s := p.summary
+		fmt.Fprintf(c.out, "%s (%s): shots %d, hits %.0f%%, longest streak %d, turns %d, time %s\n", s.Name, p.result,
+			s.Shots, s.HitPercentage, s.LongestStreak, s.Turns, formatSeconds(s.TimeSpent))
+		game.FprintFields(c.out, s.Fields)
+	}
+}
 
-	ship := game.CreateShip(x, y, direction, length)
-	err := c.board.PlaceShip(ship)
-	if err != nil {
-		fmt.Println(err)
+//printState prints the snapshot of the game received from the server together with the resynced board.
+func (c *console) printState(resp web.Response) {
+	state, ok := client.DecodeState(resp)
+	if !ok {
+		return
+	}
+	fmt.Fprintf(c.out, "Phase: %s  your turn: %t", state.Phase, state.Turn)
+	if state.NextShip > 0 {
+		fmt.Fprintf(c.out, "  next ship: %d", state.NextShip)
 	}
+	fmt.Fprintln(c.out)
+	if state.Phase == Lobby {
+		return
+	}
+	fmt.Fprintf(c.out, "To place: %s  afloat: %s\n", formatFleet(state.ToPlace), formatFleet(state.Afloat))
+	c.client.FprintBoard(c.out)
 }
 
-func (c *Client) processShootOutcome(resp web.Response) {
-	success := resp.Args["hit"].(bool)
-	x, y := extractCoordinates(resp)
-	err := c.board.Attack(game.Position{
-		X: x,
-		Y: y,
-	}, success)
-	if err != nil {
-		fmt.Println(err)
+//printFleet prints the tally of the enemy ships by class.
+func (c *console) printFleet() {
+	var classes []string
+	for _, class := range c.client.EnemyFleet() {
+		classes = append(classes, formatTally(class))
 	}
+	fmt.Fprintf(c.out, "Enemy fleet: %s\n", strings.Join(classes, ", "))
 }
 
-func (c *Client) receiveAttack(resp web.Response) {
-	if len(resp.GetArgs()) != 0 {
-		x, y := extractCoordinates(resp)
-		c.board.ReceiveAttack(game.Position{
-			X: x,
-			Y: y,
-		})
+func (c *console) printRooms(resp web.Response) {
+	rooms, ok := client.DecodeRooms(resp)
+	if !ok {
+		return
+	}
+
+	ids := make([]string, 0, len(rooms))
+	fmt.Fprintf(c.out, "Page %v, %v rooms in total\n", resp.Args["page"], resp.Args["total"])
+	for _, r := range rooms {
+		ids = append(ids, r.Id)
+		fmt.Fprintf(c.out, "%s  creator: %s  players: %d/2  rules: %s  board: %dx%d  fleet: %s  phase: %s  created: %s\n",
+			r.Id, r.Creator, r.Players, r.Rules, r.BoardSize, r.BoardSize, formatFleet(r.Fleet), r.Phase,
+			r.CreatedAt.Format("15:04:05"))
 	}
+	c.setRoomIds(ids)
 }
 
-func readLoop(done chan<- struct{}, client *Client) {
-	defer func() {
-		done <- struct{}{}
-	}()
-	for {
-		fmt.Println("read")
-		_, bytes, err := client.conn.ReadMessage()
+func formatFleet(fleet map[int]int) string {
+	var lengths []int
+	for length := range fleet {
+		lengths = append(lengths, length)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(lengths)))
 
-		if err != nil {
-			return
-		}
-		var resp web.Response
-		json.Unmarshal(bytes, &resp)
-		client.processResponse(resp)
+	var parts []string
+	for _, length := range lengths {
+		parts = append(parts, fmt.Sprintf("%dx%d", fleet[length], length))
 	}
+	return strings.Join(parts, " ")
+}
+
+//formatTally describes how many ships of the class are still afloat and how many are sunk.
+func formatTally(class game.ClassTally) string {
+	return fmt.Sprintf("%s %d afloat %d sunk", class.Class, class.Afloat, class.Sunk)
 }
 
-func writeLoop(done chan<- struct{}, client *Client) {
+func readLoop(done chan<- struct{}, c *console) {
 	defer func() {
 		done <- struct{}{}
 	}()
-	for {
-		b, action, err := readAction()
-		if err != nil {
-			fmt.Println("An error has occurred. Please enter your command again")
-			continue
-		}
-
-		request := web.BuildRequest(client.id, action, nil)
-
-		switch action {
-		case Create:
-			sendRequest(request, client)
-		case List:
-			sendRequest(request, client)
-		case Join:
-			joinRoom(request, client)
-		case JoinRandom:
-			sendRequest(request, client)
-		case PlaceShip:
-			placeShipOnBoard(b, request, client)
-		case Shoot:
-			shootAtEnemy(b, request, client)
-		case Exit:
-			sendRequest(request, client)
-			return
-		}
+	for event := range c.client.Events() {
+		c.processEvent(event)
 	}
 }
 
-func readAction() ([]byte, string, error) {
-	fmt.Println("enter action")
-	buf := bufio.NewReader(os.Stdin)
-
-	b, err := buf.ReadBytes('\n')
+//loadLayout loads the layout from the file and places the whole fleet with it. The layout is checked
+//against the rules of the room before any ship is sent.
+func (c *console) loadLayout(path string) {
+	layout, err := client.LoadLayout(path)
+	if err == nil {
+		err = c.client.UseLayout(layout)
+	}
 	if err != nil {
-		return nil, "", err
+		fmt.Fprintf(c.out, "can't use layout %s: %s\n", path, err)
+		return
 	}
-
-	action := string(b)
-	action = strings.TrimSuffix(action, "\n")
-	return b, action, nil
+	fmt.Fprintf(c.out, "placing %d ships from %s\n", len(layout.Ships), path)
 }
 
-func joinRoom(request web.Request, client *Client) {
-	fmt.Println("enter room ID")
-	buf := bufio.NewReader(os.Stdin)
-
-	b, _ := buf.ReadBytes('\n')
-	id := string(b)
-
-	id = strings.TrimSuffix(id, "\n")
-	args := map[string]interface{}{"roomId": id}
-	request.Args = args
-
-	sendRequest(request, client)
+//saveLayout saves the ships placed in the current game to the file.
+func (c *console) saveLayout(path string) {
+	if err := c.client.Layout().Save(path); err != nil {
+		fmt.Fprintf(c.out, "can't save layout to %s: %s\n", path, err)
+		return
+	}
+	fmt.Fprintf(c.out, "layout saved to %s\n", path)
 }
 
-func placeShipOnBoard(b []byte, request web.Request, client *Client) {
-	buf := bufio.NewReader(os.Stdin)
-
-	fmt.Println("enter x coordinate")
-	rune, _, _ := buf.ReadRune()
-	x := strconv.Itoa(int(rune - 'A'))
-	buf.ReadBytes('\n')
-
-	fmt.Println("enter y coordinate")
-	b, _ = buf.ReadBytes('\n')
-	y := string(b)
-	y = strings.TrimSuffix(y, "\n")
-
-	fmt.Println("enter placement direction")
-	b, _ = buf.ReadBytes('\n')
-	direction := string(b)
-	direction = strings.TrimSuffix(direction, "\n")
-
-	args := map[string]interface{}{"x": x, "y": y, "direction": direction}
-	request.Args = args
-	sendRequest(request, client)
+//lineReader reads the commands typed into the console. It is implemented by term.Terminal, which
+//keeps the history and completes the commands, and by the plain reader used when the standard input
+//isn't a terminal.
+type lineReader interface {
+	ReadLine() (string, error)
 }
 
-func shootAtEnemy(b []byte, request web.Request, client *Client) {
-	buf := bufio.NewReader(os.Stdin)
+type plainReader struct {
+	scanner *bufio.Scanner
+}
 
-	fmt.Println("enter x coordinate")
-	rune, _, _ := buf.ReadRune()
-	x := strconv.Itoa(int(rune - 'A'))
-	buf.ReadBytes('\n')
+func (r plainReader) ReadLine() (string, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
 
-	fmt.Println("enter y coordinate")
-	b, _ = buf.ReadBytes('\n')
-	y := string(b)
-	y = strings.TrimSuffix(y, "\n")
+func writeLoop(done chan<- struct{}, c *console, input lineReader) {
+	defer func() {
+		done <- struct{}{}
+	}()
+	fmt.Fprintln(c.out, "enter command, e.g. ls, join <room ID>, place A1 right, shoot B7 (help lists all commands)")
+	for {
+		line, err := input.ReadLine()
+		if err != nil {
+			return
+		}
 
-	args := map[string]interface{}{"x": x, "y": y}
-	request.Args = args
-	sendRequest(request, client)
-}
+		cmd, args, err := parseCommand(line)
+		if err == errEmptyCommand {
+			continue
+		}
+		if err != nil {
+			fmt.Fprintln(c.out, err)
+			continue
+		}
 
-func sendRequest(request web.Request, client *Client) {
-	marshal, _ := json.Marshal(request)
-	err := client.conn.WriteMessage(websocket.BinaryMessage, marshal)
-	if err != nil {
-		fmt.Println(">>", err)
+		switch cmd.name {
+		case Help:
+			printHelp(c.out)
+		case Quit:
+			return
+		case LoadLayout:
+			c.loadLayout(args["file"].(string))
+		case SaveLayout:
+			c.saveLayout(args["file"].(string))
+		default:
+			if err := c.client.Send(cmd.action, args); err != nil {
+				fmt.Fprintln(c.out, ">>", err)
+			}
+		}
 	}
 }
 
 func main() {
-	u := url.URL{Scheme: "ws", Host: *addr, Path: "/ws"}
-	log.Printf("connecting to %s", u.String())
+	flag.Parse()
+	if *local != "" {
+		if err := runLocal(*local, *rules); err != nil {
+			log.Fatal("local game: ", err)
+		}
+		return
+	}
+
+	log.Printf("connecting to %s", *addr)
 
-	c, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	c, err := client.Connect(*addr, *encoding)
 	if err != nil {
-		log.Fatal("dial:", err)
+		log.Fatal("connect: ", err)
 	}
 	defer c.Close()
 
-	client := &Client{
-		id:    "",
-		conn:  c,
-		board: game.InitBoard(),
+	if !*plain {
+		if err := runTUI(c); err != nil {
+			log.Fatal("terminal UI: ", err)
+		}
+		return
 	}
-	done := make(chan struct{})
 
-	go readLoop(done, client)
-	go writeLoop(done, client)
+	con := &console{client: c, out: os.Stdout}
+	input, restore, err := openConsole(con)
+	if err != nil {
+		log.Fatal("console: ", err)
+	}
+	defer restore()
+
+	done := make(chan struct{})
+	go readLoop(done, con)
+	go writeLoop(done, con, input)
 	<-done
 }
+
+//openConsole returns the reader of the commands. If the standard input is terminal, it is switched
+//to raw mode and the commands are read by term.Terminal with history and tab completion. All output
+//of the client goes through the terminal, so the responses don't break the line being typed. The
+//returned function restores the previous mode of the terminal.
+func openConsole(c *console) (lineReader, func(), error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return plainReader{scanner: bufio.NewScanner(os.Stdin)}, func() {}, nil
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, nil, err
+	}
+	terminal := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, "> ")
+	terminal.AutoCompleteCallback = c.complete
+	c.out = terminal
+	return terminal, func() { term.Restore(fd, state) }, nil
+}
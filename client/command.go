@@ -0,0 +1,386 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	Help       = "help"
+	Quit       = "quit"
+	LoadLayout = "load-layout"
+	SaveLayout = "save-layout"
+)
+
+//command is single command of the line based console, e.g. "shoot B7". The command is sent to the
+//server as request with the action and the arguments returned by parse. Commands without action
+//(help, quit, load-layout, save-layout) are handled by the console itself.
+type command struct {
+	name    string
+	action  string
+	usage   string
+	about   string
+	parse   func(args []string, text string) (map[string]interface{}, error)
+	options func(c *console, arg int) []string
+}
+
+//commands lists the commands of the console in the order in which they are shown by help.
+var commands = []command{
+	{name: "ls", action: List, usage: "ls [open] [rules=<name>] [page=<n>] [pageSize=<n>]",
+		about: "list the rooms", parse: parseList, options: listOptions},
+	{name: "create", action: Create, usage: "create [name=<name>] [rules=<name>]",
+		about: "create new room", parse: parseCreate, options: createOptions},
+	{name: "join", action: Join, usage: "join <room ID> [name=<name>]",
+		about: "join the room", parse: parseJoin, options: joinOptions},
+	{name: "random", action: JoinRandom, usage: "random",
+		about: "join random room", parse: parseNoArgs},
+	{name: "watch", action: Spectate, usage: "watch <room ID> [full]",
+		about: "watch the room, full shows both fleets with delay", parse: parseSpectate, options: spectateOptions},
+	{name: "place", action: PlaceShip, usage: "place <position> <up|down|left|right>",
+		about: "place the ship, e.g. place A1 right", parse: parsePlace, options: placeOptions},
+	{name: "shoot", action: Shoot, usage: "shoot <position>",
+		about: "shoot at the enemy field, e.g. shoot B7", parse: parseShoot},
+	{name: LoadLayout, usage: "load-layout <file>",
+		about: "place the whole fleet from the layout file (.json or text grid)", parse: parseFile},
+	{name: SaveLayout, usage: "save-layout <file>",
+		about: "save the placed fleet to the layout file", parse: parseFile},
+	{name: "rematch", action: Rematch, usage: "rematch <yes|no>",
+		about: "answer whether to play again", parse: parseRematch, options: rematchOptions},
+	{name: State, action: State, usage: "state",
+		about: "resync the board and show the state of the game kept by the server", parse: parseNoArgs},
+	{name: "chat", action: Chat, usage: "chat <message>",
+		about: "send message to the room", parse: parseChat},
+	{name: "mute", action: Mute, usage: "mute <name>",
+		about: "mute or unmute the player", parse: parseMute},
+	{name: "exit", action: Exit, usage: "exit",
+		about: "leave the room", parse: parseNoArgs},
+	{name: Help, usage: "help",
+		about: "show this list", parse: parseNoArgs},
+	{name: Quit, usage: "quit",
+		about: "close the client", parse: parseNoArgs},
+}
+
+var errEmptyCommand = errors.New("empty command")
+
+//findCommand returns the command with the provided name. The action names used by the previous
+//version of the console (e.g. ls-rooms, join-room) are accepted as well.
+func findCommand(name string) (command, bool) {
+	name = strings.ToLower(name)
+	for _, cmd := range commands {
+		if cmd.name == name || (cmd.action != "" && cmd.action == name) {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+//parseCommand parses single line typed into the console into the command and the arguments of its
+//request. The arguments are validated, so typos are reported before anything is sent to the server.
+func parseCommand(line string) (command, map[string]interface{}, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return command{}, nil, errEmptyCommand
+	}
+
+	cmd, ok := findCommand(fields[0])
+	if !ok {
+		return command{}, nil, fmt.Errorf("unknown command %q, type help to list the commands", fields[0])
+	}
+
+	text := strings.TrimSpace(strings.TrimSpace(line)[len(fields[0]):])
+	args, err := cmd.parse(fields[1:], text)
+	if err != nil {
+		return command{}, nil, fmt.Errorf("%v, usage: %s", err, cmd.usage)
+	}
+	return cmd, args, nil
+}
+
+func printHelp(w io.Writer) {
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-52s %s\n", cmd.usage, cmd.about)
+	}
+	fmt.Fprintln(w, "Positions are row A-J followed by column 0-9. Tab completes the commands, up and down browse the history.")
+}
+
+func parseNoArgs(args []string, _ string) (map[string]interface{}, error) {
+	if len(args) != 0 {
+		return nil, errors.New("unexpected arguments")
+	}
+	return map[string]interface{}{}, nil
+}
+
+func parseList(args []string, _ string) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for _, arg := range args {
+		if arg == "open" {
+			result["open"] = true
+			continue
+		}
+		key, value, err := parseOption(arg, "rules", "page", "pageSize")
+		if err != nil {
+			return nil, err
+		}
+		if key == "rules" {
+			result[key] = value
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid %s %q", key, value)
+		}
+		result[key] = n
+	}
+	return result, nil
+}
+
+func parseCreate(args []string, _ string) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for _, arg := range args {
+		key, value, err := parseOption(arg, "name", "rules")
+		if err != nil {
+			return nil, err
+		}
+		if key == "rules" {
+			if _, err := game.GetRules(value); err != nil {
+				return nil, fmt.Errorf("unknown rule set %q, expected one of %s", value,
+					strings.Join(game.RuleSetNames(), ", "))
+			}
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+//parseOption splits key=value argument and checks that the key is one of the allowed keys.
+func parseOption(arg string, keys ...string) (string, string, error) {
+	kv := strings.SplitN(arg, "=", 2)
+	if len(kv) != 2 || kv[1] == "" {
+		return "", "", fmt.Errorf("invalid argument %q", arg)
+	}
+	for _, key := range keys {
+		if kv[0] == key {
+			return kv[0], kv[1], nil
+		}
+	}
+	return "", "", fmt.Errorf("unknown option %q", kv[0])
+}
+
+func parseJoin(args []string, _ string) (map[string]interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.New("expected room ID")
+	}
+	result := map[string]interface{}{"roomId": args[0]}
+	if len(args) == 2 {
+		key, value, err := parseOption(args[1], "name")
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+func parseSpectate(args []string, _ string) (map[string]interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.New("expected room ID")
+	}
+	full := len(args) == 2
+	if full && args[1] != "full" {
+		return nil, fmt.Errorf("invalid argument %q", args[1])
+	}
+	return map[string]interface{}{"roomId": args[0], "full": full}, nil
+}
+
+func parsePlace(args []string, _ string) (map[string]interface{}, error) {
+	if len(args) != 2 {
+		return nil, errors.New("expected position and direction")
+	}
+	position, err := parsePosition(args[0])
+	if err != nil {
+		return nil, err
+	}
+	direction, err := parseDirection(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"x": position.X, "y": position.Y, "direction": direction}, nil
+}
+
+func parseFile(args []string, _ string) (map[string]interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("expected file")
+	}
+	return map[string]interface{}{"file": args[0]}, nil
+}
+
+func parseShoot(args []string, _ string) (map[string]interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("expected position")
+	}
+	position, err := parsePosition(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"x": position.X, "y": position.Y}, nil
+}
+
+func parseRematch(args []string, _ string) (map[string]interface{}, error) {
+	if len(args) == 1 {
+		switch strings.ToLower(args[0]) {
+		case "yes", "y":
+			return map[string]interface{}{"accept": true}, nil
+		case "no", "n":
+			return map[string]interface{}{"accept": false}, nil
+		}
+	}
+	return nil, errors.New("expected yes or no")
+}
+
+func parseChat(_ []string, text string) (map[string]interface{}, error) {
+	if text == "" {
+		return nil, errors.New("expected message")
+	}
+	return map[string]interface{}{"text": text}, nil
+}
+
+func parseMute(args []string, _ string) (map[string]interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("expected name of the player")
+	}
+	return map[string]interface{}{"name": args[0]}, nil
+}
+
+//parsePosition parses field written as row letter followed by column number, e.g. B7, and checks
+//that the field is on the board.
+func parsePosition(s string) (game.Position, error) {
+	lastRow := 'A' + rune(game.BoardSize-1)
+	invalid := fmt.Errorf("invalid position %q, expected row A-%c and column 0-%d, e.g. B7",
+		s, lastRow, game.BoardSize-1)
+	if len(s) < 2 {
+		return game.Position{}, invalid
+	}
+
+	row := unicode.ToUpper(rune(s[0]))
+	column, err := strconv.Atoi(s[1:])
+	if err != nil || row < 'A' || row > lastRow || column < 0 || column >= game.BoardSize {
+		return game.Position{}, invalid
+	}
+	return game.Position{X: int(row - 'A'), Y: column}, nil
+}
+
+//parseDirection accepts the direction or its first letter.
+func parseDirection(s string) (string, error) {
+	s = strings.ToLower(s)
+	for _, direction := range []string{game.Up, game.Down, game.Left, game.Right} {
+		if s == direction || s == direction[:1] {
+			return direction, nil
+		}
+	}
+	return "", fmt.Errorf("invalid direction %q, expected up, down, left or right", s)
+}
+
+func listOptions(_ *console, _ int) []string {
+	options := []string{"open", "page=", "pageSize="}
+	for _, name := range game.RuleSetNames() {
+		options = append(options, "rules="+name)
+	}
+	return options
+}
+
+func createOptions(_ *console, _ int) []string {
+	options := []string{"name="}
+	for _, name := range game.RuleSetNames() {
+		options = append(options, "rules="+name)
+	}
+	return options
+}
+
+func roomOptions(c *console, arg int) []string {
+	if arg != 0 {
+		return nil
+	}
+	return c.getRoomIds()
+}
+
+func joinOptions(c *console, arg int) []string {
+	if arg == 1 {
+		return []string{"name="}
+	}
+	return roomOptions(c, arg)
+}
+
+func spectateOptions(c *console, arg int) []string {
+	if arg == 1 {
+		return []string{"full"}
+	}
+	return roomOptions(c, arg)
+}
+
+func placeOptions(_ *console, arg int) []string {
+	if arg != 1 {
+		return nil
+	}
+	return []string{game.Up, game.Down, game.Left, game.Right}
+}
+
+func rematchOptions(_ *console, arg int) []string {
+	if arg != 0 {
+		return nil
+	}
+	return []string{"yes", "no"}
+}
+
+//complete is called by the terminal for every key. On Tab it completes the word before the cursor
+//with the command names or the arguments of the command, e.g. the IDs of the last listed rooms. If
+//several words match, the common prefix is completed.
+func (c *console) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+
+	before := line[:pos]
+	start := strings.LastIndexAny(before, " ") + 1
+	word := before[start:]
+	fields := strings.Fields(before[:start])
+
+	var candidates []string
+	if len(fields) == 0 {
+		for _, cmd := range commands {
+			candidates = append(candidates, cmd.name)
+		}
+	} else if cmd, ok := findCommand(fields[0]); ok && cmd.options != nil {
+		candidates = cmd.options(c, len(fields)-1)
+	}
+
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) {
+			matches = append(matches, candidate)
+		}
+	}
+	if len(matches) == 0 {
+		return "", 0, false
+	}
+
+	completion := commonPrefix(matches)
+	after := line[pos:]
+	if len(matches) == 1 && !strings.HasSuffix(completion, "=") && !strings.HasPrefix(after, " ") {
+		completion += " "
+	}
+	return before[:start] + completion + after, start + len(completion), true
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
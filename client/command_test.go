@@ -0,0 +1,222 @@
+package main
+
+import (
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	// given
+	testCases := []struct {
+		Name   string
+		Line   string
+		Action string
+		Args   map[string]interface{}
+		Err    string
+	}{
+		{
+			Name:   "list open rooms",
+			Line:   "ls open rules=compact page=2",
+			Action: List,
+			Args:   map[string]interface{}{"open": true, "rules": "compact", "page": 2},
+		},
+		{
+			Name:   "create room with name and rules",
+			Line:   "create name=bob rules=compact",
+			Action: Create,
+			Args:   map[string]interface{}{"name": "bob", "rules": "compact"},
+		},
+		{
+			Name:   "join room with name",
+			Line:   "join room name=bob",
+			Action: Join,
+			Args:   map[string]interface{}{"roomId": "room", "name": "bob"},
+		},
+		{
+			Name:   "accept action name of the previous console",
+			Line:   "join-room room",
+			Action: Join,
+			Args:   map[string]interface{}{"roomId": "room"},
+		},
+		{
+			Name:   "watch room with full view",
+			Line:   "watch room full",
+			Action: Spectate,
+			Args:   map[string]interface{}{"roomId": "room", "full": true},
+		},
+		{
+			Name:   "place ship with short direction",
+			Line:   "place b7 r",
+			Action: PlaceShip,
+			Args:   map[string]interface{}{"x": 1, "y": 7, "direction": game.Right},
+		},
+		{
+			Name:   "shoot",
+			Line:   "SHOOT J0",
+			Action: Shoot,
+			Args:   map[string]interface{}{"x": 9, "y": 0},
+		},
+		{
+			Name:   "decline rematch",
+			Line:   "rematch n",
+			Action: Rematch,
+			Args:   map[string]interface{}{"accept": false},
+		},
+		{
+			Name:   "chat keeps the whole message",
+			Line:   "  chat  good   luck ",
+			Action: Chat,
+			Args:   map[string]interface{}{"text": "good   luck"},
+		},
+		{
+			Name:   "mute",
+			Line:   "mute bob",
+			Action: Mute,
+			Args:   map[string]interface{}{"name": "bob"},
+		},
+		{
+			Name: "fail on empty line",
+			Line: "   ",
+			Err:  "empty command",
+		},
+		{
+			Name: "fail on unknown command",
+			Line: "fire B7",
+			Err:  `unknown command "fire", type help to list the commands`,
+		},
+		{
+			Name: "fail on unknown option",
+			Line: "create color=red",
+			Err:  `unknown option "color", usage: create [name=<name>] [rules=<name>]`,
+		},
+		{
+			Name: "fail on unknown rule set",
+			Line: "create rules=huge",
+			Err:  `unknown rule set "huge", expected one of classic, compact, usage: create [name=<name>] [rules=<name>]`,
+		},
+		{
+			Name: "fail on invalid page",
+			Line: "ls page=0",
+			Err:  `invalid page "0", usage: ls [open] [rules=<name>] [page=<n>] [pageSize=<n>]`,
+		},
+		{
+			Name: "fail on invalid direction",
+			Line: "place A1 diagonal",
+			Err:  `invalid direction "diagonal", expected up, down, left or right, usage: place <position> <up|down|left|right>`,
+		},
+		{
+			Name: "fail on unexpected arguments",
+			Line: "exit now",
+			Err:  "unexpected arguments, usage: exit",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			// when
+			cmd, args, err := parseCommand(testCase.Line)
+
+			// then
+			if testCase.Err != "" {
+				assert.EqualError(t, err, testCase.Err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.Action, cmd.action)
+			assert.Equal(t, testCase.Args, args)
+		})
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	// given
+	testCases := []struct {
+		Name     string
+		Input    string
+		Position game.Position
+		Valid    bool
+	}{
+		{Name: "first field", Input: "A0", Position: game.Position{X: 0, Y: 0}, Valid: true},
+		{Name: "last field", Input: "J9", Position: game.Position{X: 9, Y: 9}, Valid: true},
+		{Name: "lower case row", Input: "c4", Position: game.Position{X: 2, Y: 4}, Valid: true},
+		{Name: "row out of the board", Input: "K1"},
+		{Name: "column out of the board", Input: "A10"},
+		{Name: "negative column", Input: "A-1"},
+		{Name: "missing column", Input: "B"},
+		{Name: "column is not number", Input: "Bx"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			// when
+			position, err := parsePosition(testCase.Input)
+
+			// then
+			if !testCase.Valid {
+				assert.EqualError(t, err, `invalid position "`+testCase.Input+
+					`", expected row A-J and column 0-9, e.g. B7`)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.Position, position)
+		})
+	}
+}
+
+func TestConsole_Complete(t *testing.T) {
+	// given
+	testCases := []struct {
+		Name   string
+		Line   string
+		Pos    int
+		Key    rune
+		Result string
+		Cursor int
+		Ok     bool
+	}{
+		{Name: "complete the only matching command", Line: "sh", Pos: 2, Key: '\t',
+			Result: "shoot ", Cursor: 6, Ok: true},
+		{Name: "complete common prefix of matching commands", Line: "s", Pos: 1, Key: '\t',
+			Result: "s", Cursor: 1, Ok: true},
+		{Name: "complete room ID", Line: "join ro", Pos: 7, Key: '\t',
+			Result: "join room-1 ", Cursor: 12, Ok: true},
+		{Name: "complete option without space after it", Line: "join room-1 n", Pos: 13, Key: '\t',
+			Result: "join room-1 name=", Cursor: 17, Ok: true},
+		{Name: "keep text after the cursor", Line: "wa room-1", Pos: 2, Key: '\t',
+			Result: "watch room-1", Cursor: 5, Ok: true},
+		{Name: "ignore other keys", Line: "sh", Pos: 2, Key: 'x'},
+		{Name: "ignore words without match", Line: "fire", Pos: 4, Key: '\t'},
+		{Name: "ignore commands without options", Line: "shoot B", Pos: 7, Key: '\t'},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			// when
+			c := &console{roomIds: []string{"room-1"}}
+			result, cursor, ok := c.complete(testCase.Line, testCase.Pos, testCase.Key)
+
+			// then
+			assert.Equal(t, testCase.Ok, ok)
+			assert.Equal(t, testCase.Result, result)
+			assert.Equal(t, testCase.Cursor, cursor)
+		})
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	// given
+	testCases := []struct {
+		Name   string
+		Words  []string
+		Prefix string
+	}{
+		{Name: "single word", Words: []string{"shoot"}, Prefix: "shoot"},
+		{Name: "shared prefix", Words: []string{"save-layout", "state", "shoot"}, Prefix: "s"},
+		{Name: "one word is prefix of the other", Words: []string{"rules=compact", "rules="}, Prefix: "rules="},
+		{Name: "nothing in common", Words: []string{"ls", "join"}, Prefix: ""},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			// then
+			assert.Equal(t, testCase.Prefix, commonPrefix(testCase.Words))
+		})
+	}
+}
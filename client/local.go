@@ -0,0 +1,327 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/gdamore/tcell/v2"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+//The kinds of the local game selected with the -local flag.
+const (
+	localHotSeat = "hotseat"
+	localAI      = "ai"
+)
+
+//localGame is the game played without the server. The moves are made on game.Match, so the same
+//rules apply as in the rooms of the server, and the boards are drawn by the terminal UI. In the
+//hot-seat game two players share the terminal and the boards are hidden between the turns until the
+//next player is at the keyboard. Against the AI the human is always the first player.
+type localGame struct {
+	*TUI
+	rules   game.Rules
+	match   *game.Match
+	names   [2]string
+	ai      *game.AI
+	fleet   []game.Ship
+	viewer  int
+	covered bool
+}
+
+//runLocal runs the local game of the kind by the rules until the player quits.
+func runLocal(kind, rulesName string) error {
+	rules, err := game.GetRules(rulesName)
+	if err != nil {
+		return fmt.Errorf("%v, expected one of %s", err, strings.Join(game.RuleSetNames(), ", "))
+	}
+	if kind != localHotSeat && kind != localAI {
+		return fmt.Errorf("unknown local game %q, expected %s or %s", kind, localHotSeat, localAI)
+	}
+
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return err
+	}
+	if err = screen.Init(); err != nil {
+		return err
+	}
+	defer screen.Fini()
+
+	l := &localGame{
+		TUI:   &TUI{screen: screen},
+		rules: rules,
+		names: [2]string{"Player 1", "Player 2"},
+	}
+	if kind == localAI {
+		l.names = [2]string{"You", "Computer"}
+	}
+	if err := l.start(kind == localAI); err != nil {
+		return err
+	}
+	return l.run()
+}
+
+//start starts new match. Against the AI the computer plans its whole fleet in advance.
+func (l *localGame) start(withAI bool) error {
+	match, err := game.NewMatch(l.rules)
+	if err != nil {
+		return err
+	}
+	l.match = match
+	l.cursor = game.Position{}
+	l.viewer = -1
+	if withAI {
+		l.ai = game.NewAI(rand.New(rand.NewSource(time.Now().UnixNano())))
+		if l.fleet, err = l.ai.PlanFleet(l.rules); err != nil {
+			return err
+		}
+	}
+	l.addLog(fmt.Sprintf("New %s game, fleet %s.", l.rules.Name, formatFleet(l.rules.Fleet)), styleDefault)
+	l.turn()
+	return nil
+}
+
+func (l *localGame) run() error {
+	for !l.quit {
+		l.draw()
+		switch ev := l.screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			l.screen.Sync()
+		case *tcell.EventKey:
+			l.handleKey(ev)
+		case nil:
+			return nil
+		}
+	}
+	return nil
+}
+
+//turn lets the AI make its moves and prepares the UI for the player on turn. In the hot-seat game the
+//boards are covered whenever the turn passes to the other player.
+func (l *localGame) turn() {
+	for l.ai != nil && l.match.Current() == 1 && l.match.Phase() != game.PhaseOver {
+		if err := l.playAI(); err != nil {
+			l.addLog(fmt.Sprintf("The computer failed to play: %s", err), styleError)
+			break
+		}
+	}
+
+	switch l.match.Phase() {
+	case game.PhasePlace:
+		l.mode = modePlacing
+		l.shipLength = l.match.NextShip()
+		l.setStatus("Place the ship with length %d.", l.shipLength)
+	case game.PhaseShoot:
+		l.mode = modeShooting
+		l.setStatus("Choose the field to attack.")
+	default:
+		l.mode = modeRematch
+		l.status = fmt.Sprintf("%s won! Press y to play again.", l.names[l.match.Winner()])
+		l.addLog(l.status, styleTitle)
+	}
+	l.covered = l.ai == nil && l.mode != modeRematch && l.match.Current() != l.viewer
+}
+
+func (l *localGame) setStatus(format string, args ...interface{}) {
+	l.status = fmt.Sprintf(format, args...)
+	if l.ai == nil {
+		l.status = l.names[l.match.Current()] + ": " + l.status
+	}
+}
+
+//playAI makes single move of the AI.
+func (l *localGame) playAI() error {
+	if l.match.Phase() == game.PhasePlace {
+		if len(l.fleet) == 0 {
+			return game.ErrFleetDoesNotFit
+		}
+		ship := l.fleet[0]
+		l.fleet = l.fleet[1:]
+		_, err := l.match.Place(ship.GetX(), ship.GetY(), ship.GetDirection())
+		return err
+	}
+
+	board := l.match.Board(l.match.Current())
+	p := l.ai.Target(board)
+	sunk, err := l.shoot(p)
+	if err != nil {
+		return err
+	}
+	l.ai.Record(board, p, sunk)
+	return nil
+}
+
+//shoot shoots at the field for the player on turn and logs the outcome.
+func (l *localGame) shoot(p game.Position) (bool, error) {
+	player := l.match.Current()
+	hit, sunk, err := l.match.Shoot(p)
+	if err != nil {
+		return false, err
+	}
+	outcome := web.ShotPayload{X: p.X, Y: p.Y, Hit: hit, Sunk: sunk}
+	if sunk {
+		outcome.Class = game.ShipClass(len(l.match.Board(1 - player).ShipFields(p)))
+	}
+	l.addLog(fmt.Sprintf("%s shot at %s: %s.", l.names[player], formatPosition(p.X, p.Y), formatOutcome(outcome)),
+		styleDefault)
+	return sunk, nil
+}
+
+func (l *localGame) handleKey(ev *tcell.EventKey) {
+	if ev.Key() == tcell.KeyCtrlC || ev.Rune() == 'q' {
+		l.quit = true
+		return
+	}
+
+	switch ev.Key() {
+	case tcell.KeyPgUp:
+		l.scroll++
+		return
+	case tcell.KeyPgDn:
+		if l.scroll > 0 {
+			l.scroll--
+		}
+		return
+	}
+
+	if l.covered {
+		if ev.Key() == tcell.KeyEnter {
+			l.covered = false
+			l.viewer = l.match.Current()
+		}
+		return
+	}
+
+	if l.mode == modeRematch {
+		if ev.Rune() == 'y' {
+			if err := l.start(l.ai != nil); err != nil {
+				l.addLog(fmt.Sprintf("Can't start new game: %s", err), styleError)
+			}
+		}
+		return
+	}
+
+	switch ev.Key() {
+	case tcell.KeyUp:
+		l.moveCursor(-1, 0)
+	case tcell.KeyDown:
+		l.moveCursor(1, 0)
+	case tcell.KeyLeft:
+		l.moveCursor(0, -1)
+	case tcell.KeyRight:
+		l.moveCursor(0, 1)
+	case tcell.KeyEnter:
+		l.act()
+	}
+
+	switch ev.Rune() {
+	case ' ':
+		l.act()
+	case 'r':
+		l.vertical = !l.vertical
+	}
+}
+
+//act places the ship or shoots at the field under the cursor for the player on turn.
+func (l *localGame) act() {
+	board := l.match.Board(l.match.Current())
+	switch l.mode {
+	case modePlacing:
+		if _, ok := l.preview(board.GetOwnFields()); !ok {
+			l.status = "The ship doesn't fit there."
+			return
+		}
+		if _, err := l.match.Place(l.cursor.X, l.cursor.Y, l.direction()); err != nil {
+			l.addLog(fmt.Sprintf("Can't place the ship: %s", err), styleError)
+			return
+		}
+	case modeShooting:
+		_, err := l.shoot(l.cursor)
+		if errors.Is(err, game.ErrAlreadyShot) {
+			l.status = "You have already shot at that field."
+			return
+		}
+		if err != nil {
+			l.addLog(fmt.Sprintf("Can't shoot: %s", err), styleError)
+			return
+		}
+	}
+	l.turn()
+}
+
+func (l *localGame) draw() {
+	l.screen.Clear()
+	width, height := l.screen.Size()
+
+	l.drawText(0, 0, styleTitle, "Battleships - local "+l.rules.Name+" game")
+	switch {
+	case l.covered:
+		l.drawText(ownLeft, boardTop, styleTitle, fmt.Sprintf("Pass the terminal to %s.", l.names[l.match.Current()]))
+		l.drawText(ownLeft, boardTop+2, styleDefault, "The boards are hidden. Press Enter when you are ready.")
+	case l.mode == modeRematch:
+		l.drawBoards(l.fleetView(0), l.fleetView(1))
+	default:
+		player := l.match.Current()
+		enemy := boardView{
+			title:  "Enemy waters",
+			fields: l.match.Board(player).GetEnemyFields(),
+			wrecks: wrecks(l.match.Board(1 - player)),
+		}
+		if l.mode == modeShooting {
+			enemy.fleet = l.match.Board(1 - player).Tally()
+		}
+		l.drawBoards(l.fleetView(player), enemy)
+	}
+	l.drawLog(boardTop+game.BoardSize+2, height-2)
+
+	l.fillLine(height-2, width, styleStatus)
+	l.drawText(0, height-2, styleStatus, " "+l.status)
+	l.drawText(0, height-1, styleDefault, l.help())
+	l.screen.HideCursor()
+	l.screen.Show()
+}
+
+//fleetView returns the own fields of the player with his sunk ships.
+func (l *localGame) fleetView(player int) boardView {
+	title := l.names[player] + "'s fleet"
+	if l.ai != nil && player == 0 {
+		title = "Your fleet"
+	}
+	board := l.match.Board(player)
+	return boardView{title: title, fields: board.GetOwnFields(), wrecks: wrecks(board)}
+}
+
+//wrecks returns the fields of the sunk ships on the own fields of the board.
+func wrecks(board *game.Board) map[game.Position]bool {
+	own := board.GetOwnFields()
+	sunk := make(map[game.Position]bool)
+	for x, row := range own {
+		for y, field := range row {
+			p := game.Position{X: x, Y: y}
+			if field != game.Hit || sunk[p] || !board.ShipIsSunk(p) {
+				continue
+			}
+			for _, f := range board.ShipFields(p) {
+				sunk[f] = true
+			}
+		}
+	}
+	return sunk
+}
+
+func (l *localGame) help() string {
+	switch {
+	case l.covered:
+		return "enter show the boards  PgUp/PgDn log  q quit"
+	case l.mode == modePlacing:
+		return "arrows move  r rotate  enter place  PgUp/PgDn log  q quit"
+	case l.mode == modeShooting:
+		return "arrows move  enter shoot  PgUp/PgDn log  q quit"
+	default:
+		return "y play again  PgUp/PgDn log  q quit"
+	}
+}
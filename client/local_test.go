@@ -0,0 +1,207 @@
+package main
+
+import (
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func pressEnter(l *localGame) {
+	l.handleKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+}
+
+func pressRune(l *localGame, r rune) {
+	l.handleKey(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+}
+
+//newLocalGame returns started local game by the compact rules. The terminal UI has no screen, the
+//tests drive the game only through the keys.
+func newLocalGame(t *testing.T, withAI bool) *localGame {
+	rules, err := game.GetRules(game.Compact)
+	assert.Nil(t, err)
+	l := &localGame{
+		TUI:   &TUI{},
+		rules: rules,
+		names: [2]string{"Player 1", "Player 2"},
+	}
+	if withAI {
+		l.names = [2]string{"You", "Computer"}
+	}
+	assert.Nil(t, l.start(withAI))
+	return l
+}
+
+//actAt moves the cursor to the field and places the ship or shoots there.
+func actAt(l *localGame, p game.Position) {
+	l.cursor = p
+	pressEnter(l)
+}
+
+//placeHotSeatFleets places the ships of both players in the hot-seat game on the even rows, every
+//player uncovers the boards before his turn.
+func placeHotSeatFleets(t *testing.T, l *localGame) {
+	var rows [2]int
+	for l.mode == modePlacing {
+		assert.True(t, l.covered)
+		pressEnter(l)
+		player := l.match.Current()
+		actAt(l, game.Position{X: rows[player]})
+		rows[player] += 2
+	}
+}
+
+func TestLocalGame_HotSeat(t *testing.T) {
+	t.Run("cover the boards when the turn passes", func(t *testing.T) {
+		// when
+		l := newLocalGame(t, false)
+
+		// then
+		assert.Equal(t, modePlacing, l.mode)
+		assert.True(t, l.covered)
+		assert.Equal(t, 5, l.shipLength)
+		assert.Equal(t, "Player 1: Place the ship with length 5.", l.status)
+
+		// when
+		pressEnter(l)
+
+		// then
+		assert.False(t, l.covered)
+		assert.Equal(t, 0, l.viewer)
+
+		// when
+		actAt(l, game.Position{})
+
+		// then
+		assert.True(t, l.covered)
+		assert.Equal(t, 1, l.match.Current())
+		assert.Equal(t, "Player 2: Place the ship with length 5.", l.status)
+
+		// when
+		pressEnter(l)
+
+		// then
+		assert.False(t, l.covered)
+		assert.Equal(t, 1, l.viewer)
+	})
+
+	t.Run("keep the turn when the ship doesn't fit", func(t *testing.T) {
+		// when
+		l := newLocalGame(t, false)
+		pressEnter(l)
+		actAt(l, game.Position{Y: 6})
+
+		// then
+		assert.Equal(t, "The ship doesn't fit there.", l.status)
+		assert.False(t, l.covered)
+		assert.Equal(t, 5, l.match.NextShip())
+	})
+
+	t.Run("play the match to the end", func(t *testing.T) {
+		// when
+		l := newLocalGame(t, false)
+		placeHotSeatFleets(t, l)
+
+		// then
+		assert.Equal(t, modeShooting, l.mode)
+		assert.True(t, l.covered)
+
+		// when
+		pressEnter(l)
+		shooter := l.match.Current()
+		actAt(l, game.Position{})
+
+		// then
+		assert.True(t, l.covered)
+		assert.Equal(t, 1-shooter, l.match.Current())
+		assert.Equal(t, l.names[shooter]+" shot at A0: hit.", lastLog(l.TUI))
+
+		// when
+		pressEnter(l)
+		actAt(l, game.Position{X: 1})
+		pressEnter(l)
+		actAt(l, game.Position{})
+
+		// then
+		assert.Equal(t, "You have already shot at that field.", l.status)
+		assert.False(t, l.covered)
+		assert.Equal(t, shooter, l.match.Current())
+
+		// when
+		misses := 1
+		for l.mode == modeShooting {
+			if l.covered {
+				pressEnter(l)
+			}
+			if l.match.Current() == shooter {
+				p := nextShipField(l.match.Board(1 - shooter))
+				actAt(l, p)
+			} else {
+				misses += 2
+				actAt(l, game.Position{X: misses % game.BoardSize, Y: misses / game.BoardSize})
+			}
+		}
+
+		// then
+		assert.Equal(t, modeRematch, l.mode)
+		assert.False(t, l.covered)
+		assert.Equal(t, l.names[shooter]+" won! Press y to play again.", l.status)
+
+		// when
+		pressRune(l, 'y')
+
+		// then
+		assert.Equal(t, modePlacing, l.mode)
+		assert.Equal(t, 0, l.match.Current())
+		assert.True(t, l.covered)
+	})
+}
+
+//nextShipField returns field of ship on the board which was not shot yet.
+func nextShipField(board *game.Board) game.Position {
+	for x, row := range board.GetOwnFields() {
+		for y, field := range row {
+			if field == game.Taken {
+				return game.Position{X: x, Y: y}
+			}
+		}
+	}
+	return game.Position{}
+}
+
+func TestLocalGame_AI(t *testing.T) {
+	t.Run("computer places his ship after each ship of the player", func(t *testing.T) {
+		// when
+		l := newLocalGame(t, true)
+
+		// then
+		assert.False(t, l.covered)
+		assert.Equal(t, "Place the ship with length 5.", l.status)
+
+		// when
+		for row := 0; l.mode == modePlacing; row += 2 {
+			actAt(l, game.Position{X: row})
+			assert.False(t, l.covered)
+			assert.Equal(t, 0, l.match.Current())
+		}
+
+		// then
+		assert.Equal(t, modeShooting, l.mode)
+		assert.Empty(t, l.fleet)
+	})
+
+	t.Run("computer shoots after the player", func(t *testing.T) {
+		// when
+		l := newLocalGame(t, true)
+		for row := 0; l.mode == modePlacing; row += 2 {
+			actAt(l, game.Position{X: row})
+		}
+		actAt(l, game.Position{X: 1})
+
+		// then
+		assert.Equal(t, modeShooting, l.mode)
+		assert.Equal(t, 0, l.match.Current())
+		assert.Contains(t, l.log[len(l.log)-2].text, "You shot at B0: ")
+		assert.Contains(t, lastLog(l.TUI), "Computer shot at ")
+	})
+}
@@ -0,0 +1,782 @@
+package main
+
+import (
+	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/client"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/gdamore/tcell/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//The modes of the terminal UI. The mode decides what the keys do and what is drawn in the main area.
+const (
+	modeLobby = iota
+	modeWaiting
+	modePlacing
+	modeShooting
+	modeRematch
+	modeSpectating
+)
+
+//maxLogLines is the count of the log lines kept by the terminal UI.
+const maxLogLines = 500
+
+const (
+	boardTop  = 2
+	ownLeft   = 2
+	enemyLeft = 30
+	fleetLeft = enemyLeft + 2*game.BoardSize + 4
+)
+
+var (
+	styleDefault  = tcell.StyleDefault
+	styleTitle    = tcell.StyleDefault.Bold(true)
+	styleWater    = tcell.StyleDefault.Foreground(tcell.ColorSteelBlue)
+	styleShip     = tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorGray)
+	styleHit      = tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorRed)
+	styleSunk     = tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorMaroon)
+	styleMiss     = tcell.StyleDefault.Foreground(tcell.ColorSilver)
+	stylePreview  = tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorGreen)
+	styleCollide  = tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorDarkRed)
+	styleCursor   = tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorYellow)
+	styleStatus   = tcell.StyleDefault.Reverse(true)
+	styleError    = tcell.StyleDefault.Foreground(tcell.ColorRed)
+	styleSelected = tcell.StyleDefault.Reverse(true)
+)
+
+type logLine struct {
+	text  string
+	style tcell.Style
+}
+
+//prompt is a line of text typed in by the player. When it is submitted the answer is passed to submit.
+type prompt struct {
+	question string
+	answer   []rune
+	submit   func(string)
+}
+
+//boardView is single board drawn by the terminal UI - its title, its fields, the fields of the sunk
+//ships on it and the tally of its fleet, which is drawn next to the enemy board.
+type boardView struct {
+	title  string
+	fields []string
+	wrecks map[game.Position]bool
+	fleet  []game.ClassTally
+}
+
+//TUI is the full-screen terminal UI of the client. The responses are read in separate goroutine and
+//posted to the event loop of the screen, so the state of the UI is changed only from the event loop.
+type TUI struct {
+	client     *client.Client
+	screen     tcell.Screen
+	mode       int
+	cursor     game.Position
+	vertical   bool
+	shipLength int
+	rooms      []web.RoomInfo
+	selected   int
+	log        []logLine
+	scroll     int
+	status     string
+	prompt     *prompt
+	quit       bool
+	exited     bool
+	summary    *web.SummaryPayload
+	won        bool
+}
+
+//connectionClosed is posted to the event loop when reading from the connection fails.
+type connectionClosed struct {
+	err error
+}
+
+//runTUI runs the terminal UI until the player quits or the connection is closed.
+func runTUI(c *client.Client) error {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return err
+	}
+	if err = screen.Init(); err != nil {
+		return err
+	}
+	defer screen.Fini()
+
+	t := &TUI{
+		client: c,
+		screen: screen,
+		mode:   modeLobby,
+		status: "Connecting...",
+	}
+	go t.readLoop()
+	return t.run()
+}
+
+func (t *TUI) readLoop() {
+	for event := range t.client.Events() {
+		_ = t.screen.PostEvent(tcell.NewEventInterrupt(event))
+	}
+	_ = t.screen.PostEvent(tcell.NewEventInterrupt(connectionClosed{err: t.client.Err()}))
+}
+
+func (t *TUI) run() error {
+	for !t.quit {
+		t.draw()
+		switch ev := t.screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			t.screen.Sync()
+		case *tcell.EventKey:
+			t.handleKey(ev)
+		case *tcell.EventInterrupt:
+			switch data := ev.Data().(type) {
+			case client.Event:
+				t.handleEvent(data)
+			case connectionClosed:
+				if t.exited {
+					return nil
+				}
+				return fmt.Errorf("connection closed: %v", data.err)
+			}
+		case nil:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (t *TUI) send(action string, args map[string]interface{}) {
+	if err := t.client.Send(action, args); err != nil {
+		t.addLog(fmt.Sprintf("Failed to send %s: %s", action, err), styleError)
+	}
+}
+
+func (t *TUI) addLog(text string, style tcell.Style) {
+	t.log = append(t.log, logLine{text: text, style: style})
+	if len(t.log) > maxLogLines {
+		t.log = t.log[len(t.log)-maxLogLines:]
+	}
+	t.scroll = 0
+}
+
+func (t *TUI) ask(question string, submit func(string)) {
+	t.prompt = &prompt{question: question, submit: submit}
+}
+
+//handleEvent updates the mode of the UI according to the response received from the server.
+func (t *TUI) handleEvent(event client.Event) {
+	if event.Err != nil {
+		t.addLog(event.Err.Error(), styleError)
+	}
+	resp := event.Response
+	args := resp.GetArgs()
+
+	switch resp.GetAction() {
+	case Register:
+		t.status = "Connected. Press l to list the rooms."
+		t.send(List, nil)
+		return
+	case web.Hello, Ack:
+		return
+	case Info:
+		if rooms, ok := client.DecodeRooms(resp); ok {
+			t.rooms = rooms
+			if t.selected >= len(rooms) {
+				t.selected = 0
+			}
+			t.status = fmt.Sprintf("%v rooms in total.", args["total"])
+			return
+		}
+	case Wait:
+		if t.mode == modeLobby {
+			t.cursor = game.Position{}
+		}
+		if resp.GetCode() == "" {
+			t.mode = modeWaiting
+		}
+	case PlaceShip:
+		t.mode = modePlacing
+		t.shipLength, _ = client.ShipLength(resp)
+	case Placed:
+		t.mode = modeWaiting
+	case Shoot:
+		t.mode = modeShooting
+		if len(args) > 0 {
+			if shot, ok := t.decodeShot(resp); ok {
+				t.addLog(fmt.Sprintf("Opponent shot at %s: %s.", formatPosition(shot.X, shot.Y), formatOutcome(shot)),
+					styleDefault)
+			}
+		}
+		t.status = "Your turn. Choose the field to attack."
+		return
+	case ShootOutcome:
+		t.mode = modeWaiting
+		if shot, ok := t.decodeShot(resp); ok {
+			t.addLog(fmt.Sprintf("You shot at %s: %s.", formatPosition(shot.X, shot.Y), formatOutcome(shot)),
+				styleDefault)
+		}
+		t.status = "Wait for your opponent."
+		return
+	case Win, Lose:
+		if summary, ok := client.DecodeSummary(resp); ok {
+			t.summary = &summary
+			t.won = resp.GetAction() == Win
+		}
+	case Rematch:
+		t.mode = modeRematch
+	case Lobby:
+		t.mode = modeLobby
+		t.summary = nil
+		t.send(List, nil)
+	case Spectate:
+		t.mode = modeSpectating
+	case Chat:
+		t.addLog(fmt.Sprintf("[%v] %v: %s", args["channel"], args["from"], resp.GetMessage()), styleDefault)
+		return
+	case State:
+		if state, ok := client.DecodeState(resp); ok {
+			t.applyState(state)
+		}
+		return
+	case Retry:
+		t.addLog(fmt.Sprintf("%s (%s)", resp.GetMessage(), resp.GetCode()), styleError)
+		t.status = resp.GetMessage()
+		return
+	}
+
+	if resp.GetMessage() != "" {
+		t.addLog(resp.GetMessage(), styleDefault)
+		t.status = resp.GetMessage()
+	}
+}
+
+//applyState switches the mode according to the snapshot of the game kept by the server, which the
+//client asks for when the board may be out of sync.
+func (t *TUI) applyState(state web.StatePayload) {
+	switch {
+	case state.Phase == Lobby:
+		t.mode = modeLobby
+	case state.Phase == pkg.Placing && state.Turn:
+		t.mode = modePlacing
+		t.shipLength = state.NextShip
+	case state.Phase == pkg.Shooting && state.Turn:
+		t.mode = modeShooting
+	case state.Phase == Rematch:
+		t.mode = modeRematch
+	default:
+		t.mode = modeWaiting
+	}
+	t.status = fmt.Sprintf("Synchronized with the server, phase %s.", state.Phase)
+	t.addLog(t.status, styleDefault)
+}
+
+//formatSeconds formats the time in seconds rounded to whole seconds, e.g. 1m5s.
+func formatSeconds(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second).String()
+}
+
+func formatPosition(x, y int) string {
+	return fmt.Sprintf("%c%d", 'A'+x, y)
+}
+
+//decodeShot decodes the shot described by shoot and shoot-outcome responses. If the response is malformed
+//it is logged as error and false is returned.
+func (t *TUI) decodeShot(resp web.Response) (web.ShotPayload, bool) {
+	var shot web.ShotPayload
+	if err := web.DecodePayload(resp.GetArgs(), &shot); err != nil {
+		t.addLog(fmt.Sprintf("Malformed %s response: %s", resp.GetAction(), err), styleError)
+		return web.ShotPayload{}, false
+	}
+	return shot, true
+}
+
+//formatOutcome describes the outcome of the shot, including the class of the sunk ship if it is known.
+func formatOutcome(shot web.ShotPayload) string {
+	switch {
+	case shot.Sunk:
+		if shot.Class != "" {
+			return "sunk the " + shot.Class
+		}
+		return "sunk"
+	case shot.Hit:
+		return "hit"
+	default:
+		return "miss"
+	}
+}
+
+//handleKey handles the key pressed by the player. While a prompt is open the keys edit the answer,
+//otherwise they are interpreted according to the mode.
+func (t *TUI) handleKey(ev *tcell.EventKey) {
+	if ev.Key() == tcell.KeyCtrlC {
+		t.quit = true
+		return
+	}
+	if t.prompt != nil {
+		t.editPrompt(ev)
+		return
+	}
+
+	switch ev.Key() {
+	case tcell.KeyPgUp:
+		t.scroll++
+		return
+	case tcell.KeyPgDn:
+		if t.scroll > 0 {
+			t.scroll--
+		}
+		return
+	}
+
+	switch ev.Rune() {
+	case 'q':
+		t.quit = true
+		return
+	case 't':
+		t.ask("Message: ", func(text string) {
+			t.send(Chat, map[string]interface{}{"text": text})
+		})
+		return
+	case 'm':
+		t.ask("Mute or unmute player: ", func(name string) {
+			t.send(Mute, map[string]interface{}{"name": name})
+		})
+		return
+	case 'x':
+		t.exited = t.mode == modeLobby
+		t.send(Exit, nil)
+		return
+	case 'u':
+		t.send(State, nil)
+		return
+	}
+
+	if t.mode == modeLobby {
+		t.handleLobbyKey(ev)
+	} else {
+		t.handleGameKey(ev)
+	}
+}
+
+func (t *TUI) handleLobbyKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyUp:
+		if t.selected > 0 {
+			t.selected--
+		}
+		return
+	case tcell.KeyDown:
+		if t.selected < len(t.rooms)-1 {
+			t.selected++
+		}
+		return
+	case tcell.KeyEnter:
+		if t.selected < len(t.rooms) {
+			t.send(Join, map[string]interface{}{"roomId": t.rooms[t.selected].Id})
+		}
+		return
+	}
+
+	switch ev.Rune() {
+	case 'l':
+		t.send(List, nil)
+	case 'c':
+		t.ask("Your name (empty for anonymous): ", func(name string) {
+			t.ask("Rule set (classic, compact): ", func(rules string) {
+				args := map[string]interface{}{}
+				if name != "" {
+					args["name"] = name
+				}
+				if rules != "" {
+					args["rules"] = rules
+				}
+				t.send(Create, args)
+			})
+		})
+	case 'n':
+		t.send(JoinRandom, nil)
+	case 'i':
+		t.ask("Room ID: ", func(id string) {
+			t.ask("Your name (empty to keep the current one): ", func(name string) {
+				args := map[string]interface{}{"roomId": id}
+				if name != "" {
+					args["name"] = name
+				}
+				t.send(Join, args)
+			})
+		})
+	case 'w', 'W':
+		if t.selected < len(t.rooms) {
+			t.send(Spectate, map[string]interface{}{"roomId": t.rooms[t.selected].Id, "full": ev.Rune() == 'W'})
+		}
+	}
+}
+
+func (t *TUI) handleGameKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyUp:
+		t.moveCursor(-1, 0)
+		return
+	case tcell.KeyDown:
+		t.moveCursor(1, 0)
+		return
+	case tcell.KeyLeft:
+		t.moveCursor(0, -1)
+		return
+	case tcell.KeyRight:
+		t.moveCursor(0, 1)
+		return
+	case tcell.KeyEnter:
+		t.act()
+		return
+	}
+
+	switch ev.Rune() {
+	case ' ':
+		t.act()
+	case 'r':
+		t.vertical = !t.vertical
+	case 'L':
+		if t.mode != modeSpectating {
+			t.ask("Load layout from file: ", t.loadLayout)
+		}
+	case 'S':
+		if t.mode != modeSpectating {
+			t.ask("Save layout to file: ", t.saveLayout)
+		}
+	case 'y', 'n':
+		if t.mode == modeRematch {
+			t.send(Rematch, map[string]interface{}{"accept": ev.Rune() == 'y'})
+			t.mode = modeWaiting
+		}
+	}
+}
+
+//loadLayout loads the layout from the file and places the whole fleet with it.
+func (t *TUI) loadLayout(path string) {
+	layout, err := client.LoadLayout(path)
+	if err == nil {
+		err = t.client.UseLayout(layout)
+	}
+	if err != nil {
+		t.addLog(fmt.Sprintf("Can't use layout %s: %s", path, err), styleError)
+		return
+	}
+	t.addLog(fmt.Sprintf("Placing the fleet from %s.", path), styleDefault)
+}
+
+//saveLayout saves the ships placed in the current game to the file.
+func (t *TUI) saveLayout(path string) {
+	if err := t.client.Layout().Save(path); err != nil {
+		t.addLog(fmt.Sprintf("Can't save layout to %s: %s", path, err), styleError)
+		return
+	}
+	t.addLog(fmt.Sprintf("Layout saved to %s.", path), styleDefault)
+}
+
+func (t *TUI) moveCursor(dx, dy int) {
+	x, y := t.cursor.X+dx, t.cursor.Y+dy
+	if x >= 0 && x < game.BoardSize && y >= 0 && y < game.BoardSize {
+		t.cursor = game.Position{X: x, Y: y}
+	}
+}
+
+func (t *TUI) direction() string {
+	if t.vertical {
+		return game.Down
+	}
+	return game.Right
+}
+
+//act places the ship or shoots at the field under the cursor, depending on the mode.
+func (t *TUI) act() {
+	switch t.mode {
+	case modePlacing:
+		if _, ok := t.preview(t.client.OwnFields()); !ok {
+			t.status = "The ship doesn't fit there."
+			return
+		}
+		t.send(PlaceShip, map[string]interface{}{"x": t.cursor.X, "y": t.cursor.Y, "direction": t.direction()})
+	case modeShooting:
+		if t.client.EnemyFields()[t.cursor.X][t.cursor.Y] != game.Empty {
+			t.status = "You have already shot at that field."
+			return
+		}
+		t.send(Shoot, map[string]interface{}{"x": t.cursor.X, "y": t.cursor.Y})
+	}
+}
+
+//preview returns the fields of the ship placed at the cursor and whether it can be placed there. The
+//ship can't go out of the board and can't touch any of the already placed ships on the own fields.
+func (t *TUI) preview(own []string) ([]game.Position, bool) {
+	ship := game.CreateShip(t.cursor.X, t.cursor.Y, t.direction(), t.shipLength)
+	positions, err := ship.GetPositions()
+	if err != nil {
+		positions = nil
+		for i := 0; i < t.shipLength; i++ {
+			p := game.Position{X: t.cursor.X, Y: t.cursor.Y + i}
+			if t.vertical {
+				p = game.Position{X: t.cursor.X + i, Y: t.cursor.Y}
+			}
+			if p.X < game.BoardSize && p.Y < game.BoardSize {
+				positions = append(positions, p)
+			}
+		}
+		return positions, false
+	}
+
+	for _, p := range positions {
+		if own[p.X][p.Y] != game.Empty {
+			return positions, false
+		}
+	}
+	return positions, true
+}
+
+func (t *TUI) editPrompt(ev *tcell.EventKey) {
+	p := t.prompt
+	switch ev.Key() {
+	case tcell.KeyEscape:
+		t.prompt = nil
+	case tcell.KeyEnter:
+		t.prompt = nil
+		p.submit(strings.TrimSpace(string(p.answer)))
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(p.answer) > 0 {
+			p.answer = p.answer[:len(p.answer)-1]
+		}
+	case tcell.KeyRune:
+		p.answer = append(p.answer, ev.Rune())
+	}
+}
+
+func (t *TUI) draw() {
+	t.screen.Clear()
+	width, height := t.screen.Size()
+
+	t.drawText(0, 0, styleTitle, "Battleships - "+t.modeName())
+	logTop := boardTop + game.BoardSize + 2
+	if t.mode == modeLobby {
+		logTop = t.drawRooms(height)
+	} else if t.mode == modeRematch && t.summary != nil {
+		logTop = t.drawSummary()
+	} else {
+		enemy := boardView{title: "Enemy waters", fields: t.client.EnemyFields(), wrecks: t.client.Wrecks(false)}
+		if t.mode != modeSpectating {
+			enemy.fleet = t.client.EnemyFleet()
+		}
+		t.drawBoards(boardView{title: "Your fleet", fields: t.client.OwnFields(), wrecks: t.client.Wrecks(true)}, enemy)
+	}
+	t.drawLog(logTop, height-2)
+
+	t.fillLine(height-2, width, styleStatus)
+	t.drawText(0, height-2, styleStatus, " "+t.status)
+	if t.prompt != nil {
+		t.drawText(0, height-1, styleDefault, t.prompt.question+string(t.prompt.answer))
+		t.screen.ShowCursor(len(t.prompt.question)+len(t.prompt.answer), height-1)
+	} else {
+		t.drawText(0, height-1, styleDefault, t.help())
+		t.screen.HideCursor()
+	}
+	t.screen.Show()
+}
+
+//drawSummary draws the summary of the game which has just ended - the revealed boards of both players
+//and the table of their stats below them. Returns the row below the table.
+func (t *TUI) drawSummary() int {
+	own, enemy := t.summary.Winner, t.summary.Loser
+	ownTitle, enemyTitle := "Your fleet - winner", enemy.Name+"'s fleet"
+	if !t.won {
+		own, enemy = enemy, own
+		ownTitle, enemyTitle = "Your fleet", enemy.Name+"'s fleet - winner"
+	}
+	t.drawBoards(boardView{title: ownTitle, fields: own.Fields}, boardView{title: enemyTitle, fields: enemy.Fields})
+
+	row := boardTop + game.BoardSize + 2
+	rows := []struct {
+		name  string
+		value func(s *web.PlayerSummary) string
+	}{
+		{"Shots fired", func(s *web.PlayerSummary) string { return strconv.Itoa(s.Shots) }},
+		{"Hit percentage", func(s *web.PlayerSummary) string { return fmt.Sprintf("%.0f%%", s.HitPercentage) }},
+		{"Longest streak", func(s *web.PlayerSummary) string { return strconv.Itoa(s.LongestStreak) }},
+		{"Turns taken", func(s *web.PlayerSummary) string { return strconv.Itoa(s.Turns) }},
+		{"Time spent", func(s *web.PlayerSummary) string { return formatSeconds(s.TimeSpent) }},
+	}
+	t.drawText(ownLeft, row, styleTitle, "Game summary")
+	t.drawText(ownLeft+18, row, styleTitle, "You")
+	t.drawText(ownLeft+30, row, styleTitle, enemy.Name)
+	for i, r := range rows {
+		t.drawText(ownLeft, row+1+i, styleDefault, r.name)
+		t.drawText(ownLeft+18, row+1+i, styleDefault, r.value(own))
+		t.drawText(ownLeft+30, row+1+i, styleDefault, r.value(enemy))
+	}
+	return row + len(rows) + 2
+}
+
+func (t *TUI) modeName() string {
+	switch t.mode {
+	case modeLobby:
+		return "lobby"
+	case modePlacing:
+		return fmt.Sprintf("place ship with length %d", t.shipLength)
+	case modeShooting:
+		return "your turn"
+	case modeRematch:
+		return "rematch?"
+	case modeSpectating:
+		return "spectating"
+	default:
+		return "waiting"
+	}
+}
+
+func (t *TUI) help() string {
+	switch t.mode {
+	case modeLobby:
+		return "up/down select  enter join  w/W watch  l list  c create  n random  i join by id  t chat  m mute  q quit"
+	case modePlacing:
+		return "arrows move  r rotate  enter place  L load layout  t chat  m mute  u resync  x leave  PgUp/PgDn log  q quit"
+	case modeShooting:
+		return "arrows move  enter shoot  S save layout  t chat  m mute  u resync  x leave  PgUp/PgDn log  q quit"
+	case modeRematch:
+		return "y play again  n back to lobby  t chat  x leave  q quit"
+	default:
+		return "t chat  m mute  u resync  x leave  PgUp/PgDn log  q quit"
+	}
+}
+
+func (t *TUI) drawRooms(height int) int {
+	t.drawText(ownLeft, boardTop, styleTitle, "Rooms")
+	row := boardTop + 1
+	if len(t.rooms) == 0 {
+		t.drawText(ownLeft, row, styleDefault, "There are no rooms. Press c to create one.")
+		return row + 2
+	}
+	maxRows := (height - boardTop) / 2
+	for i, r := range t.rooms {
+		if i >= maxRows {
+			break
+		}
+		style := styleDefault
+		if i == t.selected {
+			style = styleSelected
+		}
+		t.drawText(ownLeft, row, style, fmt.Sprintf("%-20s %d/2  %-8s %-8s spectators: %d  %s",
+			r.Creator, r.Players, r.Rules, r.Phase, r.Spectators, formatFleet(r.Fleet)))
+		row++
+	}
+	return row + 1
+}
+
+//drawBoards draws the own and the enemy board with the preview of the placed ship or the cursor of
+//the shot, depending on the mode.
+func (t *TUI) drawBoards(own, enemy boardView) {
+	t.drawText(ownLeft, boardTop-1, styleTitle, own.title)
+	t.drawText(enemyLeft, boardTop-1, styleTitle, enemy.title)
+	t.drawGrid(ownLeft, own)
+	t.drawGrid(enemyLeft, enemy)
+	t.drawFleet(enemy.fleet)
+
+	switch t.mode {
+	case modePlacing:
+		positions, ok := t.preview(own.fields)
+		style := stylePreview
+		if !ok {
+			style = styleCollide
+		}
+		for _, p := range positions {
+			t.drawCell(ownLeft, p, style, "[]")
+		}
+	case modeShooting:
+		t.drawCell(enemyLeft, t.cursor, styleCursor, "><")
+	}
+}
+
+//drawFleet draws the tally of the enemy fleet by class next to the enemy grid. The classes which are
+//sunk completely are drawn as wrecks.
+func (t *TUI) drawFleet(fleet []game.ClassTally) {
+	if len(fleet) == 0 {
+		return
+	}
+	t.drawText(fleetLeft, boardTop-1, styleTitle, "Enemy fleet")
+	for i, class := range fleet {
+		style := styleDefault
+		if class.Afloat == 0 {
+			style = styleMiss
+		}
+		t.drawText(fleetLeft, boardTop+1+i, style, formatTally(class))
+	}
+}
+
+func (t *TUI) drawGrid(left int, board boardView) {
+	for y := 0; y < game.BoardSize; y++ {
+		t.drawText(left+2+2*y, boardTop, styleDefault, strconv.Itoa(y))
+	}
+	for x, row := range board.fields {
+		t.drawText(left, boardTop+1+x, styleDefault, string(rune('A'+x)))
+		for y, field := range row {
+			p := game.Position{X: x, Y: y}
+			style, text := fieldStyle(field)
+			if board.wrecks[p] {
+				style, text = styleSunk, "##"
+			}
+			t.drawCell(left, p, style, text)
+		}
+	}
+}
+
+func fieldStyle(field rune) (tcell.Style, string) {
+	switch field {
+	case game.Taken:
+		return styleShip, "[]"
+	case game.Hit:
+		return styleHit, "><"
+	case game.Miss:
+		return styleMiss, " o"
+	default:
+		return styleWater, " ~"
+	}
+}
+
+func (t *TUI) drawCell(left int, p game.Position, style tcell.Style, text string) {
+	t.drawText(left+1+2*p.Y, boardTop+1+p.X, style, text)
+}
+
+//drawLog draws the newest lines of the log which fit between the rows top and bottom. The log can be
+//scrolled back with PgUp.
+func (t *TUI) drawLog(top, bottom int) {
+	if bottom <= top {
+		return
+	}
+	t.drawText(0, top, styleTitle, "Events")
+	rows := bottom - top - 1
+	end := len(t.log) - t.scroll
+	if end < 0 {
+		end = 0
+		t.scroll = len(t.log)
+	}
+	start := end - rows
+	if start < 0 {
+		start = 0
+	}
+	for i, line := range t.log[start:end] {
+		t.drawText(0, top+1+i, line.style, line.text)
+	}
+}
+
+func (t *TUI) fillLine(y, width int, style tcell.Style) {
+	for x := 0; x < width; x++ {
+		t.screen.SetContent(x, y, ' ', nil, style)
+	}
+}
+
+func (t *TUI) drawText(x, y int, style tcell.Style, text string) {
+	for _, r := range text {
+		t.screen.SetContent(x, y, r, nil, style)
+		x++
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/StanislavStefanov/Battleships/pkg/client"
+	"github.com/StanislavStefanov/Battleships/pkg/client/automock"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+)
+
+func encodeResponse(action string, message string, args map[string]interface{}) []byte {
+	data, _ := json.Marshal(web.BuildResponse(action, message, args))
+	return data
+}
+
+//newTestTUI returns terminal UI in the mode whose client has completed the handshake over fake
+//connection. The connection accepts every request and fails on reading after the handshake.
+func newTestTUI(t *testing.T, mode int) (*TUI, *automock.Connection) {
+	conn := &automock.Connection{}
+	conn.On("WriteMessage", mock.Anything, mock.Anything).Return(nil)
+	conn.On("ReadMessage").Return(websocket.BinaryMessage, encodeResponse(Register, "Connected to server.", map[string]interface{}{"id": "id"}), nil).Once()
+	conn.On("ReadMessage").Return(websocket.BinaryMessage, encodeResponse(web.Hello, "Protocol version negotiated.", map[string]interface{}{"version": 1, "encoding": web.JSON}), nil).Once()
+	conn.On("ReadMessage").Return(0, nil, errors.New("closed"))
+
+	c, err := client.New(conn, web.JSON)
+	assert.Nil(t, err)
+	return &TUI{client: c, mode: mode}, conn
+}
+
+//sentAction matches request with the action written to the connection.
+func sentAction(action string) interface{} {
+	return mock.MatchedBy(func(data []byte) bool {
+		return strings.Contains(string(data), `"action":"`+action+`"`)
+	})
+}
+
+func lastLog(t *TUI) string {
+	if len(t.log) == 0 {
+		return ""
+	}
+	return t.log[len(t.log)-1].text
+}
+
+func TestTUI_HandleEvent(t *testing.T) {
+	// given
+	summary := map[string]interface{}{
+		"winner": map[string]interface{}{"name": "alice", "shots": 20, "hits": 17},
+		"loser":  map[string]interface{}{"name": "bob", "shots": 30, "hits": 10},
+	}
+	testCases := []struct {
+		Name       string
+		Mode       int
+		Response   web.Response
+		Expected   int
+		ShipLength int
+		Log        string
+		Status     string
+	}{
+		{
+			Name:     "wait for opponent to join the room",
+			Mode:     modeLobby,
+			Response: web.BuildResponse(Wait, "You have created room room. Wait for an opponent to join the room.", nil),
+			Expected: modeWaiting,
+			Log:      "You have created room room. Wait for an opponent to join the room.",
+			Status:   "You have created room room. Wait for an opponent to join the room.",
+		},
+		{
+			Name:       "place ship with the length sent by the server",
+			Mode:       modeWaiting,
+			Response:   web.BuildResponse(PlaceShip, "Place ship with length 4.", map[string]interface{}{"length": 4}),
+			Expected:   modePlacing,
+			ShipLength: 4,
+			Log:        "Place ship with length 4.",
+			Status:     "Place ship with length 4.",
+		},
+		{
+			Name:     "wait after the ship is placed",
+			Mode:     modePlacing,
+			Response: web.BuildResponse(Placed, "Ship placed successfully. Wait for opponent to make his turn.", nil),
+			Expected: modeWaiting,
+			Log:      "Ship placed successfully. Wait for opponent to make his turn.",
+			Status:   "Ship placed successfully. Wait for opponent to make his turn.",
+		},
+		{
+			Name:     "start shooting without shot of opponent",
+			Mode:     modeWaiting,
+			Response: web.BuildResponse(Shoot, "Your turn.", nil),
+			Expected: modeShooting,
+			Status:   "Your turn. Choose the field to attack.",
+		},
+		{
+			Name:     "log the shot of opponent",
+			Mode:     modeWaiting,
+			Response: web.BuildResponse(Shoot, "Your turn.", map[string]interface{}{"x": 1, "y": 7, "hit": true, "sunk": false}),
+			Expected: modeShooting,
+			Log:      "Opponent shot at B7: hit.",
+			Status:   "Your turn. Choose the field to attack.",
+		},
+		{
+			Name:     "log malformed shot of opponent",
+			Mode:     modeWaiting,
+			Response: web.BuildResponse(Shoot, "Your turn.", map[string]interface{}{"x": "B", "y": 7}),
+			Expected: modeShooting,
+			Log:      "Malformed shoot response:",
+			Status:   "Your turn. Choose the field to attack.",
+		},
+		{
+			Name: "wait after own shot",
+			Mode: modeShooting,
+			Response: web.BuildResponse(ShootOutcome, "Ship sunk.", map[string]interface{}{
+				"x": 0, "y": 0, "hit": true, "sunk": true, "class": "destroyer"}),
+			Expected: modeWaiting,
+			Log:      "You shot at A0: sunk the destroyer.",
+			Status:   "Wait for your opponent.",
+		},
+		{
+			Name:     "show the summary of the won game",
+			Mode:     modeShooting,
+			Response: web.BuildResponse(Win, "Congratulations, you win!", summary),
+			Expected: modeShooting,
+			Log:      "Congratulations, you win!",
+			Status:   "Congratulations, you win!",
+		},
+		{
+			Name:     "answer rematch",
+			Mode:     modeWaiting,
+			Response: web.BuildResponse(Rematch, "Do you want a rematch?", nil),
+			Expected: modeRematch,
+			Log:      "Do you want a rematch?",
+			Status:   "Do you want a rematch?",
+		},
+		{
+			Name:     "watch the room",
+			Mode:     modeLobby,
+			Response: web.BuildResponse(Spectate, "You are watching room room.", nil),
+			Expected: modeSpectating,
+			Log:      "You are watching room room.",
+			Status:   "You are watching room room.",
+		},
+		{
+			Name:     "keep the mode on rejected request",
+			Mode:     modeShooting,
+			Response: web.BuildErrorResponse(web.NewError(web.AlreadyShot, "You have already shot at that field.")),
+			Expected: modeShooting,
+			Log:      "You have already shot at that field. (" + web.AlreadyShot + ")",
+			Status:   "You have already shot at that field.",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			// when
+			tui, _ := newTestTUI(t, testCase.Mode)
+			tui.handleEvent(client.Event{Response: testCase.Response})
+
+			// then
+			assert.Equal(t, testCase.Expected, tui.mode)
+			assert.Equal(t, testCase.ShipLength, tui.shipLength)
+			if testCase.Log == "" {
+				assert.Empty(t, tui.log)
+			} else {
+				assert.True(t, strings.HasPrefix(lastLog(tui), testCase.Log), lastLog(tui))
+			}
+			assert.Equal(t, testCase.Status, tui.status)
+		})
+	}
+
+	t.Run("keep the summary until returning to the lobby", func(t *testing.T) {
+		// when
+		tui, conn := newTestTUI(t, modeShooting)
+		tui.handleEvent(client.Event{Response: web.BuildResponse(Lose, "Defeat!", summary)})
+
+		// then
+		assert.NotNil(t, tui.summary)
+		assert.Equal(t, "alice", tui.summary.Winner.Name)
+		assert.False(t, tui.won)
+
+		// when
+		tui.handleEvent(client.Event{Response: web.BuildResponse(Lobby, "You are back in the lobby.", nil)})
+
+		// then
+		assert.Equal(t, modeLobby, tui.mode)
+		assert.Nil(t, tui.summary)
+		conn.AssertCalled(t, "WriteMessage", mock.Anything, sentAction(List))
+	})
+}
@@ -0,0 +1,631 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/gorilla/websocket"
+	"io"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+//go:generate mockery -name=Connection -output=automock -outpkg=automock -case=underscore
+//Connection is the connection to the server. It is implemented by *websocket.Conn.
+type Connection interface {
+	WriteMessage(int, []byte) error
+	Close() error
+	ReadMessage() (int, []byte, error)
+}
+
+//EventBuffer is the count of events buffered until they are read from Events. When the buffer is
+//full the client stops reading from the connection.
+const EventBuffer = 64
+
+//Indexes of the wrecks of the own and of the enemy ships.
+const (
+	ownWrecks = iota
+	enemyWrecks
+)
+
+//ErrPlacementStarted is returned by UseLayout when some of the ships have already been placed.
+var ErrPlacementStarted = errors.New("the layout can be used only before the first ship is placed")
+
+//ErrNotRegistered is returned by New when the connection is closed before the server has sent the
+//id of the player.
+var ErrNotRegistered = errors.New("connection closed before the player was registered")
+
+//Event is single response received from the server. Err is set if the response couldn't be decoded
+//or the board of the client couldn't be updated according to it.
+type Event struct {
+	web.Response
+	Err error
+}
+
+//Client is the client of the Battleships server. It negotiates the protocol with the server, sends
+//the requests of the player and keeps his board up to date with the responses. All responses are
+//delivered through Events, so the user interfaces and the bots only decide what to do next.
+type Client struct {
+	conn      Connection
+	id        string
+	version   int
+	codec     web.Codec
+	requestId int
+	board     *game.Board
+	rules     game.Rules
+	length    int
+	placed    []web.PlacedPayload
+	pending   []web.PlacedPayload
+	wrecks    [2]map[game.Position]bool
+	fleet     []game.ClassTally
+	events    chan Event
+	err       error
+	mu        sync.Mutex
+	writeMu   sync.Mutex
+}
+
+//Connect dials the server listening on addr (host:port) and returns the client connected to it. The
+//messages are encoded in the latest protocol version and in the provided encoding.
+func Connect(addr string, encoding string) (*Client, error) {
+	u := url.URL{Scheme: "ws", Host: addr, Path: "/ws"}
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := New(conn, encoding)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+//New returns client communicating over already established connection. It waits until the server
+//registers the player and negotiates the protocol version and the encoding, the responses received
+//meanwhile are the first events of the client. If the server doesn't support the encoding the error
+//is returned as *web.Error with code UnsupportedEncoding.
+func New(conn Connection, encoding string) (*Client, error) {
+	c := &Client{
+		conn:    conn,
+		version: web.ProtocolV1,
+		codec:   web.DefaultCodec(),
+		board:   game.InitBoard(),
+		rules:   game.DefaultRules(),
+		wrecks:  newWrecks(),
+		events:  make(chan Event, EventBuffer),
+	}
+
+	args := map[string]interface{}{"versions": web.SupportedVersions}
+	if encoding != "" {
+		args["encodings"] = []string{encoding}
+	}
+	if err := c.Send(web.Hello, args); err != nil {
+		return nil, err
+	}
+	if err := c.handshake(); err != nil {
+		return nil, err
+	}
+
+	go c.readLoop()
+	return c, nil
+}
+
+//handshake reads the responses until both the id of the player and the negotiated protocol are known.
+func (c *Client) handshake() error {
+	negotiated := false
+	for c.Id() == "" || !negotiated {
+		resp, closed, err := c.read()
+		if closed {
+			return ErrNotRegistered
+		}
+		if err != nil {
+			return err
+		}
+		if resp.GetAction() == pkg.Retry && (resp.GetCode() == web.UnsupportedVersion ||
+			resp.GetCode() == web.UnsupportedEncoding) {
+			return web.NewError(resp.GetCode(), resp.GetMessage())
+		}
+		negotiated = negotiated || resp.GetAction() == web.Hello
+		c.events <- Event{Response: resp, Err: c.update(resp)}
+	}
+	return nil
+}
+
+func (c *Client) readLoop() {
+	defer close(c.events)
+	for {
+		resp, closed, err := c.read()
+		if closed {
+			c.mu.Lock()
+			c.err = err
+			c.mu.Unlock()
+			return
+		}
+		if err != nil {
+			c.events <- Event{Err: err}
+			continue
+		}
+		c.events <- Event{Response: resp, Err: c.update(resp)}
+		if err := c.placePending(); err != nil {
+			c.events <- Event{Err: err}
+		}
+		if needsResync(resp) {
+			if err := c.State(); err != nil {
+				c.events <- Event{Err: err}
+			}
+		}
+	}
+}
+
+//needsResync returns true if the server has rejected the request because the phase of the game or
+//the turn doesn't match, which means the client has missed some of the responses.
+func needsResync(resp web.Response) bool {
+	return (resp.GetAction() == pkg.Retry && resp.GetCode() == web.WrongPhase) ||
+		(resp.GetAction() == pkg.Wait && resp.GetCode() == web.NotYourTurn)
+}
+
+//read reads the next response from the connection and decodes it from the negotiated encoding. An
+//error reading from the connection is reported with closed set to true.
+func (c *Client) read() (resp web.Response, closed bool, err error) {
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		return web.Response{}, true, err
+	}
+	data, err = web.Transcode(data, c.getCodec(), web.DefaultCodec())
+	if err != nil {
+		return web.Response{}, false, err
+	}
+	resp, _, err = web.DecodeResponse(data)
+	return resp, false, err
+}
+
+//update updates the id, the protocol and the board of the client according to the response.
+func (c *Client) update(resp web.Response) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch resp.GetAction() {
+	case pkg.Register:
+		var register web.RegisterPayload
+		if err := web.DecodePayload(resp.GetArgs(), &register); err != nil {
+			return err
+		}
+		c.id = register.Id
+	case web.Hello:
+		var hello web.HelloPayload
+		if err := web.DecodePayload(resp.GetArgs(), &hello); err != nil {
+			return err
+		}
+		c.version = hello.Version
+		if codec, err := web.GetCodec(hello.Encoding); err == nil {
+			c.codec = codec
+		}
+	case pkg.Wait:
+		var wait web.WaitPayload
+		if err := web.DecodePayload(resp.GetArgs(), &wait); err != nil {
+			return err
+		}
+		if rules, err := game.GetRules(wait.Rules); err == nil {
+			c.rules = rules
+		}
+	case pkg.PlaceShip:
+		var place web.PlaceShipPayload
+		if err := web.DecodePayload(resp.GetArgs(), &place); err != nil {
+			return err
+		}
+		c.length = place.Length
+	case pkg.Placed:
+		var placed web.PlacedPayload
+		if err := web.DecodePayload(resp.GetArgs(), &placed); err != nil {
+			return err
+		}
+		c.length = 0
+		c.placed = append(c.placed, placed)
+		return c.board.PlaceShip(game.CreateShip(placed.X, placed.Y, placed.Direction, placed.Length))
+	case pkg.ShootOutcome:
+		var shot web.ShotPayload
+		if err := web.DecodePayload(resp.GetArgs(), &shot); err != nil {
+			return err
+		}
+		if err := c.board.Attack(game.Position{X: shot.X, Y: shot.Y}, shot.Hit); err != nil {
+			return err
+		}
+		for _, water := range shot.Water {
+			if err := c.board.Attack(game.Position{X: water.X, Y: water.Y}, false); err != nil {
+				return err
+			}
+		}
+		c.addWreck(enemyWrecks, shot.Cells)
+		c.updateFleet(shot.Fleet)
+	case pkg.Shoot:
+		if len(resp.GetArgs()) == 0 {
+			return nil
+		}
+		var shot web.ShotPayload
+		if err := web.DecodePayload(resp.GetArgs(), &shot); err != nil {
+			return err
+		}
+		c.board.ReceiveAttack(game.Position{X: shot.X, Y: shot.Y})
+		c.addWreck(ownWrecks, shot.Cells)
+		c.updateFleet(shot.Fleet)
+	case pkg.Rematch, pkg.Lobby:
+		c.board = game.InitBoard()
+		c.length = 0
+		c.placed = nil
+		c.pending = nil
+		c.wrecks = newWrecks()
+		c.fleet = nil
+	case pkg.State:
+		var state web.StatePayload
+		if err := web.DecodePayload(resp.GetArgs(), &state); err != nil {
+			return err
+		}
+		return c.resync(state)
+	}
+	return nil
+}
+
+//resync replaces the board of the player, the placed ships and the length of the ship to place with
+//the snapshot of the game kept by the server. In the lobby the board is cleared.
+func (c *Client) resync(state web.StatePayload) error {
+	board := game.InitBoard()
+	var layout Layout
+	if state.Phase != pkg.Lobby {
+		var err error
+		if board, err = game.LoadBoard(state.OwnFields, state.EnemyFields); err != nil {
+			return err
+		}
+		if layout, err = layoutFromFields(state.OwnFields); err != nil {
+			return err
+		}
+	} else {
+		c.pending = nil
+		c.wrecks = newWrecks()
+	}
+
+	if rules, err := game.GetRules(state.Rules); err == nil {
+		c.rules = rules
+	}
+	c.board = board
+	c.placed = layout.Ships
+	c.length = state.NextShip
+	c.fleet = nil
+	c.updateFleet(state.Fleet)
+	c.wrecks[ownWrecks] = make(map[game.Position]bool)
+	for _, ship := range layout.Ships {
+		c.addWreck(ownWrecks, sunkCells(board, ship))
+	}
+	return nil
+}
+
+//sunkCells returns the cells of the placed ship if all of them are hit, otherwise nil.
+func sunkCells(board *game.Board, ship web.PlacedPayload) []web.Cell {
+	own := board.GetOwnFields()
+	fields := board.ShipFields(game.Position{X: ship.X, Y: ship.Y})
+	cells := make([]web.Cell, 0, len(fields))
+	for _, p := range fields {
+		if own[p.X][p.Y] != game.Hit {
+			return nil
+		}
+		cells = append(cells, web.Cell{X: p.X, Y: p.Y})
+	}
+	return cells
+}
+
+func newWrecks() [2]map[game.Position]bool {
+	return [2]map[game.Position]bool{make(map[game.Position]bool), make(map[game.Position]bool)}
+}
+
+func (c *Client) addWreck(side int, cells []web.Cell) {
+	for _, cell := range cells {
+		c.wrecks[side][game.Position{X: cell.X, Y: cell.Y}] = true
+	}
+}
+
+//Wrecks returns the fields of the sunk ships - of the own ships if own is true, otherwise of the enemy
+//ships. The fields are revealed by the server when the ship is sunk.
+func (c *Client) Wrecks(own bool) map[game.Position]bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	side := enemyWrecks
+	if own {
+		side = ownWrecks
+	}
+	wrecks := make(map[game.Position]bool, len(c.wrecks[side]))
+	for p := range c.wrecks[side] {
+		wrecks[p] = true
+	}
+	return wrecks
+}
+
+//updateFleet replaces the tally of the enemy fleet with the one sent by the server, if there is any.
+func (c *Client) updateFleet(fleet []web.FleetClass) {
+	if fleet == nil {
+		return
+	}
+	c.fleet = make([]game.ClassTally, 0, len(fleet))
+	for _, class := range fleet {
+		c.fleet = append(c.fleet, game.ClassTally{Class: class.Class, Length: class.Length, Afloat: class.Afloat, Sunk: class.Sunk})
+	}
+}
+
+//EnemyFleet returns the tally of the enemy ships by class from the longest one, as sent by the server
+//with the last shot. Until the first shot the whole fleet of the rules is afloat.
+func (c *Client) EnemyFleet() []game.ClassTally {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fleet == nil {
+		return c.rules.Tally()
+	}
+	return append([]game.ClassTally(nil), c.fleet...)
+}
+
+//ShipLength returns the length of the ship the server asks for in place response. False is returned
+//if the response is of other kind or doesn't carry the length.
+func ShipLength(resp web.Response) (int, bool) {
+	var place web.PlaceShipPayload
+	if resp.GetAction() != pkg.PlaceShip || web.DecodePayload(resp.GetArgs(), &place) != nil {
+		return 0, false
+	}
+	return place.Length, place.Length > 0
+}
+
+//Rules returns the rules of the room the player has entered last, the classic ones until then.
+func (c *Client) Rules() game.Rules {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rules
+}
+
+//Layout returns the ships the player has placed in the current game, so they can be saved and used
+//in later games.
+func (c *Client) Layout() Layout {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	layout := Layout{Ships: append([]web.PlacedPayload(nil), c.placed...)}
+	layout.sort()
+	return layout
+}
+
+//UseLayout places the ships of the layout in the current game. The layout is validated against the
+//rules of the room first, then every time the server asks for a ship the ship with that length is
+//sent, so the whole fleet is placed without any further requests of the player. The layout can be
+//used only before the first ship is placed.
+func (c *Client) UseLayout(layout Layout) error {
+	if err := layout.Validate(c.Rules()); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if len(c.placed) > 0 {
+		c.mu.Unlock()
+		return ErrPlacementStarted
+	}
+	c.pending = append([]web.PlacedPayload(nil), layout.Ships...)
+	c.mu.Unlock()
+
+	return c.placePending()
+}
+
+//placePending sends the ship of the pending layout with the length the server asks for, if there is
+//such ship.
+func (c *Client) placePending() error {
+	c.mu.Lock()
+	var ship *web.PlacedPayload
+	for i, pending := range c.pending {
+		if c.length > 0 && pending.Length == c.length {
+			ship = &pending
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			c.length = 0
+			break
+		}
+	}
+	c.mu.Unlock()
+
+	if ship == nil {
+		return nil
+	}
+	return c.Place(game.Position{X: ship.X, Y: ship.Y}, ship.Direction)
+}
+
+//Events returns the channel of the responses received from the server. The channel is closed when
+//the connection is closed, Err returns the reason. The channel has to be drained, otherwise the
+//client stops reading from the connection.
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+//Err returns the error which has closed the connection or nil if it is still open.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+//Close closes the connection to the server.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+//Id returns the id of the player assigned by the server.
+func (c *Client) Id() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.id
+}
+
+//Version returns the negotiated protocol version.
+func (c *Client) Version() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version
+}
+
+func (c *Client) getCodec() web.Codec {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.codec
+}
+
+//OwnFields returns the own fields of the player, one string for each row.
+func (c *Client) OwnFields() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.board.GetOwnFields()
+}
+
+//FprintBoard writes the enemy and the own fields of the player to w.
+func (c *Client) FprintBoard(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.board.Fprint(w)
+}
+
+//EnemyFields returns the enemy fields as known to the player, one string for each row.
+func (c *Client) EnemyFields() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.board.GetEnemyFields()
+}
+
+//Send sends request with the provided action and args. The request is numbered and encoded in the
+//negotiated protocol version and encoding.
+func (c *Client) Send(action string, args map[string]interface{}) error {
+	c.mu.Lock()
+	c.requestId++
+	request := web.BuildRequest(c.id, action, args)
+	request.RequestId = strconv.Itoa(c.requestId)
+	version, codec := c.version, c.codec
+	c.mu.Unlock()
+
+	data, err := web.EncodeRequest(request, version)
+	if err != nil {
+		return err
+	}
+	data, err = web.Transcode(data, web.DefaultCodec(), codec)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+//sendPayload sends request with args taken from the typed payload.
+func (c *Client) sendPayload(action string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	var args map[string]interface{}
+	if err = json.Unmarshal(data, &args); err != nil {
+		return err
+	}
+	return c.Send(action, args)
+}
+
+//ListRooms asks for the rooms matching the filter. The rooms are delivered as info event, which can
+//be decoded with DecodeRooms.
+func (c *Client) ListRooms(filter web.ListRoomsPayload) error {
+	return c.sendPayload(pkg.ListRooms, filter)
+}
+
+//CreateRoom creates new room with the provided rules, the player joins it under the provided name.
+//Empty name and rules are left to the server defaults.
+func (c *Client) CreateRoom(name string, rules string) error {
+	return c.sendPayload(pkg.CreateRoom, web.CreateRoomPayload{Name: name, Rules: rules})
+}
+
+//Join joins the room with the provided id under the provided name. Empty name is left to the server
+//default.
+func (c *Client) Join(roomId string, name string) error {
+	return c.sendPayload(pkg.JoinRoom, web.JoinRoomPayload{RoomId: roomId, Name: name})
+}
+
+//JoinRandom joins random room waiting for a player. The server doesn't create new room, if there is no
+//room with free place the request is rejected with code NO_FREE_ROOMS.
+func (c *Client) JoinRandom() error {
+	return c.Send(pkg.JoinRandom, nil)
+}
+
+//Spectate watches the room with the provided id, with full view both fleets are shown with delay.
+func (c *Client) Spectate(roomId string, full bool) error {
+	return c.sendPayload(pkg.Spectate, web.SpectatePayload{RoomId: roomId, Full: full})
+}
+
+//Place places the ship starting at the position in the provided direction (up, down, left, right).
+func (c *Client) Place(position game.Position, direction string) error {
+	return c.sendPayload(pkg.PlaceShip, web.PlacePayload{X: position.X, Y: position.Y, Direction: direction})
+}
+
+//Shoot shoots at the enemy field at the position.
+func (c *Client) Shoot(position game.Position) error {
+	return c.sendPayload(pkg.Shoot, web.ShootPayload{X: position.X, Y: position.Y})
+}
+
+//Rematch answers whether the player wants to play again.
+func (c *Client) Rematch(accept bool) error {
+	return c.sendPayload(pkg.Rematch, web.RematchPayload{Accept: &accept})
+}
+
+//Chat sends the message to the room.
+func (c *Client) Chat(text string) error {
+	return c.sendPayload(pkg.Chat, web.ChatPayload{Text: text})
+}
+
+//Mute mutes the player with the provided name or unmutes him if he is already muted.
+func (c *Client) Mute(name string) error {
+	return c.sendPayload(pkg.Mute, web.MutePayload{Name: name})
+}
+
+//State asks for the snapshot of the game kept by the server. The board of the client is replaced with
+//it when the state event arrives. The client asks for it by itself whenever the server rejects the
+//request because of the wrong phase or turn.
+func (c *Client) State() error {
+	return c.Send(pkg.State, nil)
+}
+
+//DecodeState returns the snapshot of the game carried by state event.
+func DecodeState(resp web.Response) (web.StatePayload, bool) {
+	var state web.StatePayload
+	if resp.GetAction() != pkg.State || web.DecodePayload(resp.GetArgs(), &state) != nil {
+		return web.StatePayload{}, false
+	}
+	return state, true
+}
+
+//DecodeSummary returns the boards and the stats of both players carried by win or lose event. The event
+//has no summary if the game has ended because the opponent left it.
+func DecodeSummary(resp web.Response) (web.SummaryPayload, bool) {
+	var summary web.SummaryPayload
+	if resp.GetAction() != pkg.Win && resp.GetAction() != pkg.Lose {
+		return web.SummaryPayload{}, false
+	}
+	if web.DecodePayload(resp.GetArgs(), &summary) != nil || summary.Winner == nil || summary.Loser == nil {
+		return web.SummaryPayload{}, false
+	}
+	return summary, true
+}
+
+//Exit leaves the room, in the lobby it disconnects the player.
+func (c *Client) Exit() error {
+	return c.Send(pkg.Exit, nil)
+}
+
+//DecodeRooms returns the rooms carried by info event. False is returned if the event doesn't list
+//any rooms.
+func DecodeRooms(resp web.Response) ([]web.RoomInfo, bool) {
+	if _, ok := resp.GetArgs()["rooms"]; !ok {
+		return nil, false
+	}
+	var info web.InfoPayload
+	if err := web.DecodePayload(resp.GetArgs(), &info); err != nil {
+		return nil, false
+	}
+	return info.Rooms, true
+}
@@ -0,0 +1,475 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/client/automock"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func encodeResponse(action string, message string, args map[string]interface{}) []byte {
+	data, _ := json.Marshal(web.BuildResponse(action, message, args))
+	return data
+}
+
+func encodeRequest(playerId string, requestId string, action string, args map[string]interface{}, version int) []byte {
+	req := web.BuildRequest(playerId, action, args)
+	req.RequestId = requestId
+	data, _ := web.EncodeRequest(req, version)
+	return data
+}
+
+//connect returns client which has completed the handshake in ProtocolV1 and whose connection returns
+//the provided responses and then fails.
+func connect(t *testing.T, responses ...[]byte) (*Client, *automock.Connection) {
+	conn := &automock.Connection{}
+	conn.On("WriteMessage", websocket.BinaryMessage, mock.Anything).Return(nil)
+	conn.On("ReadMessage").Return(websocket.BinaryMessage, encodeResponse(pkg.Register, "Connected to server.", map[string]interface{}{"id": "id"}), nil).Once()
+	conn.On("ReadMessage").Return(websocket.BinaryMessage, encodeResponse(web.Hello, "Protocol version negotiated.", map[string]interface{}{"version": 1, "encoding": web.JSON}), nil).Once()
+	for _, resp := range responses {
+		conn.On("ReadMessage").Return(websocket.BinaryMessage, resp, nil).Once()
+	}
+	conn.On("ReadMessage").Return(0, nil, errors.New("closed")).Once()
+
+	c, err := New(conn, web.JSON)
+	assert.Nil(t, err)
+	return c, conn
+}
+
+func drain(c *Client) []Event {
+	var events []Event
+	for event := range c.Events() {
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestNew(t *testing.T) {
+	t.Run("register and negotiate the protocol", func(t *testing.T) {
+		// when
+		helloReq := encodeRequest("", "1", web.Hello, map[string]interface{}{
+			"versions":  web.SupportedVersions,
+			"encodings": []string{web.JSON},
+		}, web.ProtocolV1)
+		register := encodeResponse(pkg.Register, "Connected to server.", map[string]interface{}{"id": "id"})
+		hello, _ := web.EncodeResponse(web.BuildResponse(web.Hello, "Protocol version negotiated.",
+			map[string]interface{}{"version": 2, "encoding": web.JSON}), web.ProtocolV2)
+
+		conn := &automock.Connection{}
+		conn.On("WriteMessage", websocket.BinaryMessage, helloReq).Return(nil).Once()
+		conn.On("ReadMessage").Return(websocket.BinaryMessage, register, nil).Once()
+		conn.On("ReadMessage").Return(websocket.BinaryMessage, hello, nil).Once()
+		conn.On("ReadMessage").Return(0, nil, errors.New("closed")).Once()
+
+		c, err := New(conn, web.JSON)
+
+		// then
+		assert.Nil(t, err)
+		assert.Equal(t, "id", c.Id())
+		assert.Equal(t, web.ProtocolV2, c.Version())
+
+		events := drain(c)
+		assert.Len(t, events, 2)
+		assert.Equal(t, pkg.Register, events[0].GetAction())
+		assert.Equal(t, web.Hello, events[1].GetAction())
+		assert.EqualError(t, c.Err(), "closed")
+		conn.AssertExpectations(t)
+	})
+	t.Run("fail when the encoding is not supported", func(t *testing.T) {
+		// when
+		retry, _ := json.Marshal(web.BuildErrorResponse(web.NewError(web.UnsupportedEncoding, "unsupported encoding xml")))
+
+		conn := &automock.Connection{}
+		conn.On("WriteMessage", websocket.BinaryMessage, mock.Anything).Return(nil).Once()
+		conn.On("ReadMessage").Return(websocket.BinaryMessage, encodeResponse(pkg.Register, "Connected to server.", map[string]interface{}{"id": "id"}), nil).Once()
+		conn.On("ReadMessage").Return(websocket.BinaryMessage, retry, nil).Once()
+
+		c, err := New(conn, "xml")
+
+		// then
+		assert.Nil(t, c)
+		assert.Equal(t, web.NewError(web.UnsupportedEncoding, "unsupported encoding xml"), err)
+		conn.AssertExpectations(t)
+	})
+	t.Run("fail when the connection is closed before registration", func(t *testing.T) {
+		// when
+		conn := &automock.Connection{}
+		conn.On("WriteMessage", websocket.BinaryMessage, mock.Anything).Return(nil).Once()
+		conn.On("ReadMessage").Return(0, nil, errors.New("closed")).Once()
+
+		c, err := New(conn, web.JSON)
+
+		// then
+		assert.Nil(t, c)
+		assert.Equal(t, ErrNotRegistered, err)
+		conn.AssertExpectations(t)
+	})
+	t.Run("fail when the hello request can't be sent", func(t *testing.T) {
+		// when
+		conn := &automock.Connection{}
+		conn.On("WriteMessage", websocket.BinaryMessage, mock.Anything).Return(errors.New("write failure")).Once()
+
+		c, err := New(conn, web.JSON)
+
+		// then
+		assert.Nil(t, c)
+		assert.EqualError(t, err, "write failure")
+		conn.AssertExpectations(t)
+	})
+}
+
+func TestClient_Send(t *testing.T) {
+	tests := []struct {
+		name   string
+		send   func(c *Client) error
+		action string
+		args   map[string]interface{}
+	}{
+		{"list rooms", func(c *Client) error { return c.ListRooms(web.ListRoomsPayload{Open: true, Page: 2}) },
+			pkg.ListRooms, map[string]interface{}{"open": true, "page": 2}},
+		{"create room", func(c *Client) error { return c.CreateRoom("bob", game.Compact) },
+			pkg.CreateRoom, map[string]interface{}{"name": "bob", "rules": game.Compact}},
+		{"create room with defaults", func(c *Client) error { return c.CreateRoom("", "") },
+			pkg.CreateRoom, map[string]interface{}{}},
+		{"join room", func(c *Client) error { return c.Join("room", "") },
+			pkg.JoinRoom, map[string]interface{}{"roomId": "room"}},
+		{"join room with name", func(c *Client) error { return c.Join("room", "bob") },
+			pkg.JoinRoom, map[string]interface{}{"roomId": "room", "name": "bob"}},
+		{"join random room", func(c *Client) error { return c.JoinRandom() },
+			pkg.JoinRandom, nil},
+		{"spectate", func(c *Client) error { return c.Spectate("room", true) },
+			pkg.Spectate, map[string]interface{}{"roomId": "room", "full": true}},
+		{"place ship", func(c *Client) error { return c.Place(game.Position{X: 1, Y: 2}, game.Down) },
+			pkg.PlaceShip, map[string]interface{}{"x": 1, "y": 2, "direction": game.Down}},
+		{"shoot", func(c *Client) error { return c.Shoot(game.Position{X: 3, Y: 4}) },
+			pkg.Shoot, map[string]interface{}{"x": 3, "y": 4}},
+		{"decline rematch", func(c *Client) error { return c.Rematch(false) },
+			pkg.Rematch, map[string]interface{}{"accept": false}},
+		{"chat", func(c *Client) error { return c.Chat("hi") },
+			pkg.Chat, map[string]interface{}{"text": "hi"}},
+		{"mute", func(c *Client) error { return c.Mute("bob") },
+			pkg.Mute, map[string]interface{}{"name": "bob"}},
+		{"exit", func(c *Client) error { return c.Exit() },
+			pkg.Exit, nil},
+		{"state", func(c *Client) error { return c.State() },
+			pkg.State, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// when
+			c, conn := connect(t)
+			drain(c)
+			expected := encodeRequest("id", "2", test.action, test.args, web.ProtocolV1)
+
+			// then
+			assert.Nil(t, test.send(c))
+			conn.AssertCalled(t, "WriteMessage", websocket.BinaryMessage, expected)
+		})
+	}
+}
+
+func TestClient_Events(t *testing.T) {
+	t.Run("update the board with the responses", func(t *testing.T) {
+		// when
+		c, _ := connect(t,
+			encodeResponse(pkg.Placed, "", map[string]interface{}{"x": 0, "y": 0, "direction": game.Right, "length": 2}),
+			encodeResponse(pkg.ShootOutcome, "", map[string]interface{}{"x": 5, "y": 5, "hit": true}),
+			encodeResponse(pkg.Shoot, "", map[string]interface{}{"x": 0, "y": 1, "hit": true}),
+		)
+		events := drain(c)
+
+		// then
+		board := game.InitBoard()
+		assert.Nil(t, board.PlaceShip(game.CreateShip(0, 0, game.Right, 2)))
+		assert.Nil(t, board.Attack(game.Position{X: 5, Y: 5}, true))
+		board.ReceiveAttack(game.Position{X: 0, Y: 1})
+
+		assert.Len(t, events, 5)
+		for _, event := range events {
+			assert.Nil(t, event.Err)
+		}
+		assert.Equal(t, board.GetOwnFields(), c.OwnFields())
+		assert.Equal(t, board.GetEnemyFields(), c.EnemyFields())
+	})
+	t.Run("mark the sunk ships and the water around them", func(t *testing.T) {
+		// when
+		c, _ := connect(t,
+			encodeResponse(pkg.Placed, "", map[string]interface{}{"x": 5, "y": 0, "direction": game.Right, "length": 2}),
+			encodeResponse(pkg.ShootOutcome, "", map[string]interface{}{"x": 0, "y": 0, "hit": true}),
+			encodeResponse(pkg.ShootOutcome, "", map[string]interface{}{"x": 0, "y": 1, "hit": true, "sunk": true,
+				"class": "boat", "cells": []web.Cell{{X: 0, Y: 0}, {X: 0, Y: 1}},
+				"water": []web.Cell{{X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 2}}}),
+			encodeResponse(pkg.Shoot, "", map[string]interface{}{"x": 5, "y": 0, "hit": true}),
+			encodeResponse(pkg.Shoot, "", map[string]interface{}{"x": 5, "y": 1, "hit": true, "sunk": true,
+				"class": "boat", "cells": []web.Cell{{X: 5, Y: 0}, {X: 5, Y: 1}}}),
+		)
+		events := drain(c)
+
+		// then
+		for _, event := range events {
+			assert.Nil(t, event.Err)
+		}
+		assert.Equal(t, "xxo-------", c.EnemyFields()[0])
+		assert.Equal(t, "oo--------", c.EnemyFields()[1])
+		assert.Equal(t, map[game.Position]bool{{X: 0, Y: 0}: true, {X: 0, Y: 1}: true}, c.Wrecks(false))
+		assert.Equal(t, map[game.Position]bool{{X: 5, Y: 0}: true, {X: 5, Y: 1}: true}, c.Wrecks(true))
+	})
+	t.Run("keep the tally of the enemy fleet", func(t *testing.T) {
+		// when
+		compact, _ := game.GetRules(game.Compact)
+		c, _ := connect(t,
+			encodeResponse(pkg.Wait, "Wait for your opponent.", map[string]interface{}{"rules": game.Compact}),
+		)
+		drain(c)
+		initial := c.EnemyFleet()
+		c, _ = connect(t,
+			encodeResponse(pkg.ShootOutcome, "", map[string]interface{}{"x": 0, "y": 1, "hit": true, "sunk": true,
+				"fleet": []web.FleetClass{{Class: "destroyer", Length: 5, Afloat: 1}, {Class: "boat", Length: 2, Sunk: 1}}}),
+		)
+		drain(c)
+
+		// then
+		assert.Equal(t, compact.Tally(), initial)
+		assert.Equal(t, []game.ClassTally{
+			{Class: "destroyer", Length: 5, Afloat: 1},
+			{Class: "boat", Length: 2, Sunk: 1},
+		}, c.EnemyFleet())
+	})
+	t.Run("reset the board when going back to the lobby", func(t *testing.T) {
+		// when
+		c, _ := connect(t,
+			encodeResponse(pkg.Placed, "", map[string]interface{}{"x": 0, "y": 0, "direction": game.Right, "length": 2}),
+			encodeResponse(pkg.Lobby, "Back in the lobby.", nil),
+		)
+		drain(c)
+
+		// then
+		assert.Equal(t, game.InitBoard().GetOwnFields(), c.OwnFields())
+	})
+	t.Run("report response which doesn't fit the board", func(t *testing.T) {
+		// when
+		c, _ := connect(t,
+			encodeResponse(pkg.Placed, "", map[string]interface{}{"x": 9, "y": 9, "direction": game.Right, "length": 2}),
+		)
+		events := drain(c)
+
+		// then
+		assert.Len(t, events, 3)
+		assert.Equal(t, pkg.Placed, events[2].GetAction())
+		assert.Equal(t, game.ErrShipOutOfBounds, events[2].Err)
+	})
+	t.Run("report invalid response and keep reading", func(t *testing.T) {
+		// when
+		c, _ := connect(t, []byte("not json"), encodeResponse(pkg.Wait, "Wait for your opponent.", nil))
+		events := drain(c)
+
+		// then
+		assert.Len(t, events, 4)
+		assert.NotNil(t, events[2].Err)
+		assert.Equal(t, pkg.Wait, events[3].GetAction())
+	})
+	t.Run("resync the board with the state", func(t *testing.T) {
+		// when
+		board := game.InitBoard()
+		assert.Nil(t, board.PlaceShip(game.CreateShip(0, 0, game.Down, 3)))
+		assert.Nil(t, board.Attack(game.Position{X: 5, Y: 5}, false))
+		board.ReceiveAttack(game.Position{X: 1, Y: 0})
+		c, _ := connect(t,
+			encodeResponse(pkg.Placed, "", map[string]interface{}{"x": 9, "y": 0, "direction": game.Right, "length": 2}),
+			encodeResponse(pkg.State, "", map[string]interface{}{
+				"phase":       pkg.Placing,
+				"rules":       game.Compact,
+				"turn":        true,
+				"nextShip":    2,
+				"ownFields":   board.GetOwnFields(),
+				"enemyFields": board.GetEnemyFields(),
+			}),
+		)
+		events := drain(c)
+
+		// then
+		assert.Nil(t, events[3].Err)
+		assert.Equal(t, board.GetOwnFields(), c.OwnFields())
+		assert.Equal(t, board.GetEnemyFields(), c.EnemyFields())
+		assert.Equal(t, game.Compact, c.Rules().Name)
+		assert.Equal(t, Layout{Ships: []web.PlacedPayload{{X: 0, Y: 0, Direction: game.Down, Length: 3}}}, c.Layout())
+		assert.Empty(t, c.Wrecks(true))
+	})
+	t.Run("clear the board with the lobby state", func(t *testing.T) {
+		// when
+		c, _ := connect(t,
+			encodeResponse(pkg.Placed, "", map[string]interface{}{"x": 0, "y": 0, "direction": game.Right, "length": 2}),
+			encodeResponse(pkg.State, "", map[string]interface{}{"phase": pkg.Lobby, "turn": false}),
+		)
+		drain(c)
+
+		// then
+		assert.Equal(t, game.InitBoard().GetOwnFields(), c.OwnFields())
+		assert.Equal(t, Layout{}, c.Layout())
+	})
+	t.Run("report invalid state", func(t *testing.T) {
+		// when
+		c, _ := connect(t,
+			encodeResponse(pkg.State, "", map[string]interface{}{"phase": pkg.Shooting, "ownFields": []string{"---"}}),
+		)
+		events := drain(c)
+
+		// then
+		assert.Equal(t, game.ErrInvalidFields, events[2].Err)
+	})
+	t.Run("ask for the state when the phase doesn't match", func(t *testing.T) {
+		// when
+		wrongPhase := web.BuildErrorResponse(web.NewError(web.WrongPhase, "Invalid action during Phase: shoot."))
+		data, _ := json.Marshal(wrongPhase)
+		c, conn := connect(t, data, encodeResponse(pkg.Wait, "Wait for your opponent.", nil))
+		drain(c)
+
+		// then
+		conn.AssertCalled(t, "WriteMessage", websocket.BinaryMessage, encodeRequest("id", "2", pkg.State, nil, web.ProtocolV1))
+		conn.AssertNumberOfCalls(t, "WriteMessage", 2)
+	})
+}
+
+func TestDecodeState(t *testing.T) {
+	t.Run("decode the state", func(t *testing.T) {
+		// when
+		state, ok := DecodeState(web.BuildResponse(pkg.State, "", map[string]interface{}{
+			"phase": pkg.Shooting, "turn": true, "afloat": map[string]interface{}{"5": float64(1)},
+		}))
+
+		// then
+		assert.True(t, ok)
+		assert.Equal(t, web.StatePayload{Phase: pkg.Shooting, Turn: true, Afloat: map[int]int{5: 1}}, state)
+	})
+	t.Run("ignore other responses", func(t *testing.T) {
+		// when
+		_, ok := DecodeState(web.BuildResponse(pkg.Wait, "", nil))
+
+		// then
+		assert.False(t, ok)
+	})
+}
+
+func TestDecodeSummary(t *testing.T) {
+	t.Run("decode the summary of the game", func(t *testing.T) {
+		// when
+		summary, ok := DecodeSummary(web.BuildResponse(pkg.Lose, "Defeat!", map[string]interface{}{
+			"winner": map[string]interface{}{"name": "first", "shots": float64(20), "hits": float64(17),
+				"hitPercentage": 85.0, "longestStreak": float64(6), "turns": float64(30), "timeSpent": 42.5},
+			"loser": map[string]interface{}{"name": "second", "fields": []interface{}{"sx--------"}},
+		}))
+
+		// then
+		assert.True(t, ok)
+		assert.Equal(t, web.SummaryPayload{
+			Winner: &web.PlayerSummary{Name: "first", Shots: 20, Hits: 17, HitPercentage: 85, LongestStreak: 6,
+				Turns: 30, TimeSpent: 42.5},
+			Loser: &web.PlayerSummary{Name: "second", Fields: []string{"sx--------"}},
+		}, summary)
+	})
+	t.Run("ignore the win after the opponent has left", func(t *testing.T) {
+		// when
+		_, ok := DecodeSummary(web.BuildResponse(pkg.Win, "Your opponent exited the game. Congratulations, you win!", nil))
+
+		// then
+		assert.False(t, ok)
+	})
+}
+
+func TestDecodeRooms(t *testing.T) {
+	t.Run("decode listed rooms", func(t *testing.T) {
+		// when
+		resp := web.BuildResponse(pkg.Info, "", map[string]interface{}{
+			"rooms": []interface{}{map[string]interface{}{"id": "room", "creator": "bob", "players": float64(1)}},
+			"total": float64(1),
+		})
+		rooms, ok := DecodeRooms(resp)
+
+		// then
+		assert.True(t, ok)
+		assert.Equal(t, []web.RoomInfo{{Id: "room", Creator: "bob", Players: 1}}, rooms)
+	})
+	t.Run("ignore info without rooms", func(t *testing.T) {
+		// when
+		rooms, ok := DecodeRooms(web.BuildResponse(pkg.Info, "bob is muted", map[string]interface{}{"name": "bob"}))
+
+		// then
+		assert.False(t, ok)
+		assert.Nil(t, rooms)
+	})
+}
+
+func TestClient_UseLayout(t *testing.T) {
+	t.Run("place the ship the server asks for", func(t *testing.T) {
+		// when
+		c, conn := connect(t,
+			encodeResponse(pkg.Wait, "You have joined room room.", map[string]interface{}{"rules": game.Classic}),
+			encodeResponse(pkg.PlaceShip, "Select where to place ship with length 4", map[string]interface{}{"length": 4}),
+		)
+		err := c.UseLayout(classicLayout)
+		drain(c)
+
+		// then
+		assert.Nil(t, err)
+		conn.AssertCalled(t, "WriteMessage", websocket.BinaryMessage,
+			encodeRequest("id", "2", pkg.PlaceShip, map[string]interface{}{"x": 2, "y": 0, "direction": game.Right}, web.ProtocolV1))
+		conn.AssertNumberOfCalls(t, "WriteMessage", 2)
+	})
+	t.Run("fail when the layout doesn't match the rules of the room", func(t *testing.T) {
+		// when
+		c, conn := connect(t,
+			encodeResponse(pkg.Wait, "You have joined room room.", map[string]interface{}{"rules": game.Compact}),
+		)
+		drain(c)
+		err := c.UseLayout(classicLayout)
+
+		// then
+		assert.EqualError(t, err, "the layout has 2 ships with length 4, the compact fleet has 1")
+		assert.Equal(t, game.Compact, c.Rules().Name)
+		conn.AssertNumberOfCalls(t, "WriteMessage", 1)
+	})
+	t.Run("fail when the placement has started", func(t *testing.T) {
+		// when
+		c, _ := connect(t,
+			encodeResponse(pkg.Placed, "", map[string]interface{}{"x": 0, "y": 0, "direction": game.Down, "length": 5}),
+		)
+		drain(c)
+		err := c.UseLayout(classicLayout)
+
+		// then
+		assert.Equal(t, ErrPlacementStarted, err)
+		assert.Equal(t, Layout{Ships: []web.PlacedPayload{{X: 0, Y: 0, Direction: game.Down, Length: 5}}}, c.Layout())
+	})
+}
+
+func TestShipLength(t *testing.T) {
+	t.Run("return the length of the requested ship", func(t *testing.T) {
+		// when
+		length, ok := ShipLength(web.BuildResponse(pkg.PlaceShip, "Select where to place ship",
+			map[string]interface{}{"length": 3}))
+
+		// then
+		assert.True(t, ok)
+		assert.Equal(t, 3, length)
+	})
+	t.Run("ignore place response without length", func(t *testing.T) {
+		// when
+		_, ok := ShipLength(web.BuildResponse(pkg.PlaceShip, "Select where to place ship with length 3", nil))
+
+		// then
+		assert.False(t, ok)
+	})
+	t.Run("ignore other responses", func(t *testing.T) {
+		// when
+		_, ok := ShipLength(web.BuildResponse(pkg.Wait, "Wait for the ship with length 3", nil))
+
+		// then
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,240 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//Characters of the text grid format of the layout. Water may be written also as the ship area(b) of
+//the printed board, so the own fields printed by the client can be saved as they are.
+const (
+	layoutShip  = game.Taken
+	layoutWater = game.Empty
+)
+
+//ErrLayoutShape is returned when the ships of the text grid are not straight lines.
+var ErrLayoutShape = errors.New("every ship has to be straight horizontal or vertical line")
+
+//Layout is the placement of the whole fleet, which can be saved to a file and placed again in later
+//games. The ships are placed in the order in which the server asks for them, from the longest one.
+type Layout struct {
+	Ships []web.PlacedPayload `json:"ships"`
+}
+
+//LoadLayout reads the layout from the file. Files with .json extension contain the layout as JSON,
+//all other files the text grid - one line for each row of the board with s for the fields of the
+//ships and - for the water, optionally with the row and the column labels as printed by the client.
+func LoadLayout(path string) (Layout, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Layout{}, err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var layout Layout
+		if err := json.Unmarshal(data, &layout); err != nil {
+			return Layout{}, err
+		}
+		return layout, nil
+	}
+	return ParseGrid(data)
+}
+
+//Save writes the layout to the file as JSON or as the text grid, according to the extension.
+func (l Layout) Save(path string) error {
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		encoded, err := json.MarshalIndent(l, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(encoded, '\n')
+	} else {
+		grid, err := l.Grid()
+		if err != nil {
+			return err
+		}
+		data = grid
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+//Grid returns the layout in the text grid format with the row and the column labels.
+func (l Layout) Grid() ([]byte, error) {
+	board, err := l.place()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(" ")
+	for y := 0; y < game.BoardSize; y++ {
+		fmt.Fprintf(&buf, " %d", y)
+	}
+	buf.WriteString("\n")
+	for x, row := range board.GetOwnFields() {
+		fmt.Fprintf(&buf, "%c", 'A'+x)
+		for _, field := range row {
+			if field != layoutShip {
+				field = layoutWater
+			}
+			fmt.Fprintf(&buf, " %c", field)
+		}
+		buf.WriteString("\n")
+	}
+	return buf.Bytes(), nil
+}
+
+//ParseGrid parses the layout from the text grid format.
+func ParseGrid(data []byte) (Layout, error) {
+	var rows [][]bool
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || isColumnLabels(fields) {
+			continue
+		}
+		if len(fields) == 1 {
+			fields = strings.Split(fields[0], "")
+		}
+		if len(fields) == game.BoardSize+1 {
+			fields = fields[1:]
+		}
+		if len(fields) != game.BoardSize {
+			return Layout{}, fmt.Errorf("row %d has %d fields, expected %d", len(rows)+1, len(fields), game.BoardSize)
+		}
+
+		row := make([]bool, game.BoardSize)
+		for y, field := range fields {
+			switch field {
+			case string(layoutShip), "S", "#":
+				row[y] = true
+			case string(layoutWater), string(game.ShipArea), ".":
+			default:
+				return Layout{}, fmt.Errorf("unknown field %q in row %d", field, len(rows)+1)
+			}
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return Layout{}, err
+	}
+	if len(rows) != game.BoardSize {
+		return Layout{}, fmt.Errorf("the grid has %d rows, expected %d", len(rows), game.BoardSize)
+	}
+	return layoutFromGrid(rows)
+}
+
+func isColumnLabels(fields []string) bool {
+	for i, field := range fields {
+		if field != fmt.Sprint(i) {
+			return false
+		}
+	}
+	return true
+}
+
+//layoutFromFields returns the layout of the ships on the own fields of the board, including the hit ones.
+func layoutFromFields(own []string) (Layout, error) {
+	rows := make([][]bool, len(own))
+	for x, row := range own {
+		for _, field := range row {
+			rows[x] = append(rows[x], field == game.Taken || field == game.Hit)
+		}
+	}
+	return layoutFromGrid(rows)
+}
+
+//layoutFromGrid finds the ships in the grid. Every ship starts at the field which has no ship field on
+//the left or above it and continues to the right or down.
+func layoutFromGrid(rows [][]bool) (Layout, error) {
+	isShip := func(x, y int) bool {
+		return x >= 0 && x < len(rows) && y >= 0 && y < len(rows[x]) && rows[x][y]
+	}
+
+	var layout Layout
+	fields, covered := 0, 0
+	for x := range rows {
+		for y := range rows[x] {
+			if !rows[x][y] {
+				continue
+			}
+			fields++
+			if isShip(x-1, y) || isShip(x, y-1) {
+				continue
+			}
+
+			ship := web.PlacedPayload{X: x, Y: y, Direction: game.Right, Length: 1}
+			if isShip(x+1, y) {
+				ship.Direction = game.Down
+				for isShip(x+ship.Length, y) {
+					ship.Length++
+				}
+			} else {
+				for isShip(x, y+ship.Length) {
+					ship.Length++
+				}
+			}
+			covered += ship.Length
+			layout.Ships = append(layout.Ships, ship)
+		}
+	}
+	if covered != fields {
+		return Layout{}, ErrLayoutShape
+	}
+	layout.sort()
+	return layout, nil
+}
+
+//sort orders the ships from the longest one, as they are placed.
+func (l Layout) sort() {
+	sort.SliceStable(l.Ships, func(i, j int) bool {
+		return l.Ships[i].Length > l.Ships[j].Length
+	})
+}
+
+//place places the ships of the layout on empty board, so the same rules apply as on the server.
+func (l Layout) place() (*game.Board, error) {
+	board := game.InitBoard()
+	for _, ship := range l.Ships {
+		err := board.PlaceShip(game.CreateShip(ship.X, ship.Y, ship.Direction, ship.Length))
+		if err != nil {
+			return nil, fmt.Errorf("ship with length %d at %c%d: %w", ship.Length, 'A'+ship.X, ship.Y, err)
+		}
+	}
+	return board, nil
+}
+
+//Validate checks that the layout contains exactly the fleet required by the rules and that all ships
+//fit on the board without touching each other.
+func (l Layout) Validate(rules game.Rules) error {
+	if rules.BoardSize != game.BoardSize {
+		return fmt.Errorf("board with size %d is not supported", rules.BoardSize)
+	}
+
+	counts := map[int]int{}
+	for _, ship := range l.Ships {
+		if _, ok := rules.Fleet[ship.Length]; !ok {
+			return fmt.Errorf("the %s fleet has no ships with length %d", rules.Name, ship.Length)
+		}
+		counts[ship.Length]++
+	}
+	for length := rules.LongestShip(); length > 0; length-- {
+		if count := rules.Fleet[length]; counts[length] != count {
+			return fmt.Errorf("the layout has %d ships with length %d, the %s fleet has %d",
+				counts[length], length, rules.Name, count)
+		}
+	}
+
+	_, err := l.place()
+	return err
+}
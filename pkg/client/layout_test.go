@@ -0,0 +1,156 @@
+package client
+
+import (
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+const classicGrid = `  0 1 2 3 4 5 6 7 8 9
+A s s s s s - s s s -
+B - - - - - - - - - -
+C s s s s - s s s s -
+D - - - - - - - - - -
+E s s s - s s s - - -
+F - - - - - - - - - -
+G s s - s s - s s - -
+H - - - - - - - - - -
+I s s - - - - - - - -
+J - - - - - - - - - -
+`
+
+var classicLayout = Layout{Ships: []web.PlacedPayload{
+	{X: 0, Y: 0, Direction: game.Right, Length: 5},
+	{X: 2, Y: 0, Direction: game.Right, Length: 4},
+	{X: 2, Y: 5, Direction: game.Right, Length: 4},
+	{X: 0, Y: 6, Direction: game.Right, Length: 3},
+	{X: 4, Y: 0, Direction: game.Right, Length: 3},
+	{X: 4, Y: 4, Direction: game.Right, Length: 3},
+	{X: 6, Y: 0, Direction: game.Right, Length: 2},
+	{X: 6, Y: 3, Direction: game.Right, Length: 2},
+	{X: 6, Y: 6, Direction: game.Right, Length: 2},
+	{X: 8, Y: 0, Direction: game.Right, Length: 2},
+}}
+
+func TestParseGrid(t *testing.T) {
+	t.Run("parse grid with labels", func(t *testing.T) {
+		// when
+		layout, err := ParseGrid([]byte(classicGrid))
+
+		// then
+		assert.Nil(t, err)
+		assert.Equal(t, classicLayout, layout)
+	})
+	t.Run("parse compact grid with vertical ships", func(t *testing.T) {
+		// when
+		grid := "s---------\ns---------\n----------\n---sss----\n----------\n\n----------\n----------\n---------s\n---------s\n----------\n"
+		layout, err := ParseGrid([]byte(grid))
+
+		// then
+		assert.Nil(t, err)
+		assert.Equal(t, Layout{Ships: []web.PlacedPayload{
+			{X: 3, Y: 3, Direction: game.Right, Length: 3},
+			{X: 0, Y: 0, Direction: game.Down, Length: 2},
+			{X: 7, Y: 9, Direction: game.Down, Length: 2},
+		}}, layout)
+	})
+	tests := []struct {
+		name string
+		grid string
+		err  string
+	}{
+		{"fail when the ship is not straight", "ss--------\n-s--------\n" + repeatRows(8), ErrLayoutShape.Error()},
+		{"fail on unknown field", "sx--------\n" + repeatRows(9), `unknown field "x" in row 1`},
+		{"fail when the row is too short", "s-\n" + repeatRows(9), "row 1 has 2 fields, expected 10"},
+		{"fail when rows are missing", repeatRows(9), "the grid has 9 rows, expected 10"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// when
+			_, err := ParseGrid([]byte(test.grid))
+
+			// then
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func repeatRows(count int) string {
+	rows := ""
+	for i := 0; i < count; i++ {
+		rows += "----------\n"
+	}
+	return rows
+}
+
+func TestLayout_Save(t *testing.T) {
+	for _, name := range []string{"fleet.json", "fleet.txt"} {
+		t.Run("save and load "+name, func(t *testing.T) {
+			// when
+			path := filepath.Join(t.TempDir(), name)
+			err := classicLayout.Save(path)
+			loaded, loadErr := LoadLayout(path)
+
+			// then
+			assert.Nil(t, err)
+			assert.Nil(t, loadErr)
+			assert.Equal(t, classicLayout, loaded)
+		})
+	}
+	t.Run("save the grid with labels", func(t *testing.T) {
+		// when
+		path := filepath.Join(t.TempDir(), "fleet")
+		err := classicLayout.Save(path)
+		data, _ := ioutil.ReadFile(path)
+
+		// then
+		assert.Nil(t, err)
+		assert.Equal(t, classicGrid, string(data))
+	})
+	t.Run("fail to load missing file", func(t *testing.T) {
+		// when
+		_, err := LoadLayout(filepath.Join(t.TempDir(), "missing.json"))
+
+		// then
+		assert.NotNil(t, err)
+	})
+}
+
+func TestLayout_Validate(t *testing.T) {
+	compact, _ := game.GetRules(game.Compact)
+	tests := []struct {
+		name   string
+		layout Layout
+		rules  game.Rules
+		err    string
+	}{
+		{"accept the classic fleet", classicLayout, game.DefaultRules(), ""},
+		{"fail when the fleet doesn't match the rules", classicLayout, compact,
+			"the layout has 2 ships with length 4, the compact fleet has 1"},
+		{"fail on ship length missing in the fleet", Layout{Ships: append([]web.PlacedPayload{
+			{X: 9, Y: 9, Direction: game.Right, Length: 1}}, classicLayout.Ships...)}, game.DefaultRules(),
+			"the classic fleet has no ships with length 1"},
+		{"fail when the ships touch", Layout{Ships: append([]web.PlacedPayload{
+			{X: 1, Y: 0, Direction: game.Right, Length: 5}}, classicLayout.Ships[1:]...)}, game.DefaultRules(),
+			"ship with length 4 at C0: " + game.ErrFieldsTaken.Error()},
+		{"fail when the ship is out of the board", Layout{Ships: append([]web.PlacedPayload{
+			{X: 9, Y: 8, Direction: game.Right, Length: 5}}, classicLayout.Ships[1:]...)}, game.DefaultRules(),
+			"ship with length 5 at J8: " + game.ErrShipOutOfBounds.Error()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// when
+			err := test.layout.Validate(test.rules)
+
+			// then
+			if test.err == "" {
+				assert.Nil(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
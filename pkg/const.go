@@ -1,6 +1,7 @@
 package pkg
 
 const (
+	Register     = "register"
 	Exit         = "exit"
 	Shoot        = "shoot"
 	ShootOutcome = "shoot-outcome"
@@ -11,6 +12,15 @@ const (
 	Win          = "win"
 	Lose         = "lose"
 	Info         = "info"
+	Event        = "event"
+	GameOver     = "game-over"
+	Chat         = "chat"
+	Mute         = "mute"
+	Rematch      = "rematch"
+	Lobby        = "lobby"
+	Disconnect   = "disconnect"
+	Ack          = "ack"
+	State        = "state"
 )
 
 const (
@@ -18,4 +28,11 @@ const (
 	CreateRoom = "create-room"
 	JoinRoom   = "join-room"
 	JoinRandom = "join-random"
+	Spectate   = "spectate"
+)
+
+const (
+	Waiting  = "waiting"
+	Placing  = "placing"
+	Shooting = "shooting"
 )
@@ -0,0 +1,170 @@
+package game
+
+import (
+	"errors"
+	"math/rand"
+)
+
+//maxPlanAttempts is the count of attempts to plan the fleet before the AI gives up.
+const maxPlanAttempts = 100
+
+//ErrFleetDoesNotFit is returned when the AI fails to place the whole fleet on the board.
+var ErrFleetDoesNotFit = errors.New("the fleet doesn't fit on the board")
+
+//AI is the built-in opponent of the local game. It places its fleet randomly and hunts the enemy
+//ships by shooting at every second field. When a ship is hit it targets the fields around the hit,
+//following the line of the hits, until the ship is sunk. The fields next to the sunk ships are
+//never shot, because the ships can't touch each other.
+type AI struct {
+	rnd  *rand.Rand
+	sunk map[Position]bool
+}
+
+//NewAI creates AI which makes its random choices with rnd.
+func NewAI(rnd *rand.Rand) *AI {
+	return &AI{
+		rnd:  rnd,
+		sunk: make(map[Position]bool),
+	}
+}
+
+//PlanFleet returns random placement of the fleet described by the rules. The ships are in the order
+//in which they are placed. If there is no place left for some ship the planning starts again.
+func (a *AI) PlanFleet(rules Rules) ([]Ship, error) {
+	order := rules.PlacementOrder()
+	for attempt := 0; attempt < maxPlanAttempts; attempt++ {
+		board := InitBoard()
+		ships := make([]Ship, 0, len(order))
+		for _, length := range order {
+			candidates := placements(board, length)
+			if len(candidates) == 0 {
+				break
+			}
+			ship := candidates[a.rnd.Intn(len(candidates))]
+			if err := board.PlaceShip(ship); err != nil {
+				return nil, err
+			}
+			ships = append(ships, ship)
+		}
+		if len(ships) == len(order) {
+			return ships, nil
+		}
+	}
+	return nil, ErrFleetDoesNotFit
+}
+
+//placements returns all ships with the length which can be placed on the board.
+func placements(board *Board, length int) []Ship {
+	var ships []Ship
+	for x := 0; x < BoardSize; x++ {
+		for y := 0; y < BoardSize; y++ {
+			for _, direction := range []string{Right, Down} {
+				ship := CreateShip(x, y, direction, length)
+				if board.fits(ship) {
+					ships = append(ships, ship)
+				}
+			}
+		}
+	}
+	return ships
+}
+
+//fits returns true if the ship can be placed on the board.
+func (b *Board) fits(ship Ship) bool {
+	positions, err := ship.GetPositions()
+	if err != nil {
+		return false
+	}
+	for _, p := range positions {
+		if b.ownFields[p.X][p.Y] != Empty {
+			return false
+		}
+	}
+	return true
+}
+
+//Target chooses the enemy field to shoot at from the enemy fields of the board.
+func (a *AI) Target(board *Board) Position {
+	fields := board.enemyFields
+	if targets := a.targets(fields); len(targets) > 0 {
+		return targets[a.rnd.Intn(len(targets))]
+	}
+
+	var even, all []Position
+	for x := range fields {
+		for y := range fields[x] {
+			p := Position{X: x, Y: y}
+			if fields[x][y] != Empty || a.isWater(p) {
+				continue
+			}
+			all = append(all, p)
+			if (x+y)%2 == 0 {
+				even = append(even, p)
+			}
+		}
+	}
+	if len(even) > 0 {
+		return even[a.rnd.Intn(len(even))]
+	}
+	if len(all) > 0 {
+		return all[a.rnd.Intn(len(all))]
+	}
+	return Position{}
+}
+
+//targets returns the fields next to the hits of the ships which are not sunk yet. If the hits of a
+//ship already form a line, only the fields which continue the line are returned.
+func (a *AI) targets(fields [][]rune) []Position {
+	var next, line []Position
+	for x := range fields {
+		for y := range fields[x] {
+			hit := Position{X: x, Y: y}
+			if fields[x][y] != Hit || a.sunk[hit] {
+				continue
+			}
+			for _, p := range getNeighbours(hit) {
+				if isOutOfBounds(p) || fields[p.X][p.Y] != Empty || a.isWater(p) {
+					continue
+				}
+				next = append(next, p)
+				behind := Position{X: 2*hit.X - p.X, Y: 2*hit.Y - p.Y}
+				if !isOutOfBounds(behind) && fields[behind.X][behind.Y] == Hit && !a.sunk[behind] {
+					line = append(line, p)
+				}
+			}
+		}
+	}
+	if len(line) > 0 {
+		return line
+	}
+	return next
+}
+
+//isWater returns true if the field is next to a sunk ship, so there can't be any ship on it.
+func (a *AI) isWater(p Position) bool {
+	for _, n := range getNeighbours(p) {
+		if a.sunk[n] {
+			return true
+		}
+	}
+	return false
+}
+
+//Record remembers the outcome of the shot at p. When a ship is sunk all its hit fields are marked as
+//sunk, so the AI stops targeting them.
+func (a *AI) Record(board *Board, p Position, sunk bool) {
+	if !sunk {
+		return
+	}
+	fields := board.enemyFields
+	queue := []Position{p}
+	for len(queue) > 0 {
+		hit := queue[0]
+		queue = queue[1:]
+		if isOutOfBounds(hit) || fields[hit.X][hit.Y] != Hit || a.sunk[hit] {
+			continue
+		}
+		a.sunk[hit] = true
+		queue = append(queue, getNeighbours(hit)...)
+	}
+}
@@ -0,0 +1,113 @@
+package game
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"testing"
+)
+
+func TestAI_PlanFleet(t *testing.T) {
+	for _, name := range RuleSetNames() {
+		t.Run("plan the "+name+" fleet", func(t *testing.T) {
+			// when
+			rules, _ := GetRules(name)
+			ships, err := NewAI(rand.New(rand.NewSource(1))).PlanFleet(rules)
+
+			// then
+			require.Nil(t, err)
+			order := rules.PlacementOrder()
+			require.Len(t, ships, len(order))
+			board := InitBoard()
+			for i, ship := range ships {
+				assert.Equal(t, order[i], ship.GetLength())
+				assert.Nil(t, board.PlaceShip(ship))
+			}
+		})
+	}
+	t.Run("fail when the fleet doesn't fit", func(t *testing.T) {
+		// when
+		rules := Rules{Name: "huge", BoardSize: BoardSize, Fleet: map[int]int{9: 12}}
+		_, err := NewAI(rand.New(rand.NewSource(1))).PlanFleet(rules)
+
+		// then
+		assert.Equal(t, ErrFleetDoesNotFit, err)
+	})
+}
+
+func TestAI_Target(t *testing.T) {
+	t.Run("target the fields around the hit", func(t *testing.T) {
+		// when
+		board := InitBoard()
+		_ = board.Attack(Position{X: 4, Y: 4}, true)
+		target := NewAI(rand.New(rand.NewSource(1))).Target(board)
+
+		// then
+		assert.Contains(t, getNeighbours(Position{X: 4, Y: 4}), target)
+	})
+	t.Run("follow the line of the hits", func(t *testing.T) {
+		// when
+		board := InitBoard()
+		_ = board.Attack(Position{X: 4, Y: 4}, true)
+		_ = board.Attack(Position{X: 4, Y: 5}, true)
+		target := NewAI(rand.New(rand.NewSource(1))).Target(board)
+
+		// then
+		assert.Contains(t, []Position{{4, 3}, {4, 6}}, target)
+	})
+	t.Run("skip the fields next to the sunk ship", func(t *testing.T) {
+		// when
+		board := InitBoard()
+		for x := 0; x < BoardSize; x++ {
+			for y := 0; y < BoardSize; y++ {
+				if x > 1 || y > 2 {
+					_ = board.Attack(Position{X: x, Y: y}, false)
+				}
+			}
+		}
+		_ = board.Attack(Position{X: 0, Y: 0}, true)
+		_ = board.Attack(Position{X: 0, Y: 1}, true)
+		ai := NewAI(rand.New(rand.NewSource(1)))
+		ai.Record(board, Position{X: 0, Y: 1}, true)
+		target := ai.Target(board)
+
+		// then
+		assert.Equal(t, Position{X: 1, Y: 2}, target)
+	})
+}
+
+func TestAI_PlayMatch(t *testing.T) {
+	// given
+	rnd := rand.New(rand.NewSource(7))
+	players := []*AI{NewAI(rnd), NewAI(rnd)}
+	m, _ := NewMatch(DefaultRules())
+	var fleets [2][]Ship
+	for i, ai := range players {
+		ships, err := ai.PlanFleet(m.Rules)
+		require.Nil(t, err)
+		fleets[i] = ships
+	}
+
+	// when
+	for m.Phase() == PhasePlace {
+		player := m.Current()
+		ship := fleets[player][0]
+		fleets[player] = fleets[player][1:]
+		_, err := m.Place(ship.GetX(), ship.GetY(), ship.GetDirection())
+		require.Nil(t, err)
+	}
+	shots := 0
+	for m.Phase() == PhaseShoot && shots < 2*BoardSize*BoardSize {
+		ai := players[m.Current()]
+		board := m.Board(m.Current())
+		p := ai.Target(board)
+		_, sunk, err := m.Shoot(p)
+		require.Nil(t, err)
+		ai.Record(board, p, sunk)
+		shots++
+	}
+
+	// then
+	assert.Equal(t, PhaseOver, m.Phase())
+	assert.True(t, m.Board(1-m.Winner()).IsBeaten())
+}
@@ -72,6 +72,28 @@ func TestShip_GetPositions(t *testing.T) {
 			ExpectedPositions:  nil,
 			ExpectedErrMessage: errorMsg,
 		},
+		{
+			Name: "Fail start out of bounds for direction up",
+			Ship: Ship{
+				x:         6,
+				y:         50,
+				direction: "up",
+				length:    4,
+			},
+			ExpectedPositions:  nil,
+			ExpectedErrMessage: errorMsg,
+		},
+		{
+			Name: "Fail start out of bounds for direction right",
+			Ship: Ship{
+				x:         -1,
+				y:         2,
+				direction: "right",
+				length:    4,
+			},
+			ExpectedPositions:  nil,
+			ExpectedErrMessage: errorMsg,
+		},
 		{
 			Name: "Fail out of bounds for direction down",
 			Ship: Ship{
@@ -301,6 +323,30 @@ func TestBoard_ReceiveAttack(t *testing.T) {
 		assert.Equal(t, Miss, board.ownFields[5][5])
 	})
 
+	t.Run("fail on the field which has already been attacked", func(t *testing.T) {
+		// when
+		ship := Ship{
+			x:         6,
+			y:         3,
+			direction: "up",
+			length:    2,
+		}
+
+		board := InitBoard()
+		err := board.PlaceShip(ship)
+		assert.NoError(t, err)
+		_, _, _ = board.ReceiveAttack(Position{X: 5, Y: 3})
+		_, _, _ = board.ReceiveAttack(Position{X: 5, Y: 5})
+
+		// then
+		_, _, hitErr := board.ReceiveAttack(Position{X: 5, Y: 3})
+		_, _, missErr := board.ReceiveAttack(Position{X: 5, Y: 5})
+		assert.Equal(t, ErrAlreadyShot, hitErr)
+		assert.Equal(t, ErrAlreadyShot, missErr)
+		assert.Equal(t, Hit, board.ownFields[5][3])
+		assert.Equal(t, Miss, board.ownFields[5][5])
+	})
+
 	t.Run("fail index out of bounds", func(t *testing.T) {
 		// when
 		board := InitBoard()
@@ -469,3 +515,127 @@ func TestBoard_IsSunk(t *testing.T) {
 		assert.False(t, beaten)
 	})
 }
+
+func TestBoard_GetFields(t *testing.T) {
+	t.Run("own and enemy fields", func(t *testing.T) {
+		// when
+		b := InitBoard()
+		err := b.PlaceShip(CreateShip(0, 0, Right, 2))
+		require.NoError(t, err)
+		err = b.Attack(Position{X: 9, Y: 9}, true)
+		require.NoError(t, err)
+
+		// then
+		own := b.GetOwnFields()
+		enemy := b.GetEnemyFields()
+		assert.Equal(t, BoardSize, len(own))
+		assert.Equal(t, "ssb-------", own[0])
+		assert.Equal(t, "bb--------", own[1])
+		assert.Equal(t, "---------x", enemy[9])
+	})
+}
+
+func TestLoadBoard(t *testing.T) {
+	t.Run("load the fields of the board", func(t *testing.T) {
+		// when
+		b := InitBoard()
+		require.NoError(t, b.PlaceShip(CreateShip(0, 0, Right, 2)))
+		require.NoError(t, b.Attack(Position{X: 9, Y: 9}, true))
+		_, _, err := b.ReceiveAttack(Position{X: 0, Y: 1})
+		require.NoError(t, err)
+		loaded, loadErr := LoadBoard(b.GetOwnFields(), b.GetEnemyFields())
+
+		// then
+		assert.Nil(t, loadErr)
+		assert.Equal(t, b, loaded)
+	})
+	tests := []struct {
+		name string
+		own  []string
+	}{
+		{"fail when rows are missing", InitBoard().GetOwnFields()[1:]},
+		{"fail when the row is too short", append([]string{"---"}, InitBoard().GetOwnFields()[1:]...)},
+		{"fail on unknown field", append([]string{"-----z----"}, InitBoard().GetOwnFields()[1:]...)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// when
+			_, err := LoadBoard(test.own, InitBoard().GetEnemyFields())
+
+			// then
+			assert.Equal(t, ErrInvalidFields, err)
+		})
+	}
+}
+
+func TestBoard_CountShips(t *testing.T) {
+	// given
+	b := InitBoard()
+	require.NoError(t, b.PlaceShip(CreateShip(0, 0, Right, 3)))
+	require.NoError(t, b.PlaceShip(CreateShip(2, 0, Down, 2)))
+	require.NoError(t, b.PlaceShip(CreateShip(5, 5, Right, 2)))
+	for _, p := range []Position{{2, 0}, {3, 0}, {0, 1}} {
+		_, _, err := b.ReceiveAttack(p)
+		require.NoError(t, err)
+	}
+
+	// when
+	placed, afloat := b.CountShips()
+
+	// then
+	assert.Equal(t, map[int]int{3: 1, 2: 2}, placed)
+	assert.Equal(t, map[int]int{3: 1, 2: 1}, afloat)
+}
+
+func TestBoard_Tally(t *testing.T) {
+	// given
+	b := InitBoard()
+	require.NoError(t, b.PlaceShip(CreateShip(0, 0, Right, 3)))
+	require.NoError(t, b.PlaceShip(CreateShip(2, 0, Down, 2)))
+	require.NoError(t, b.PlaceShip(CreateShip(5, 5, Right, 2)))
+	for _, p := range []Position{{2, 0}, {3, 0}} {
+		_, _, err := b.ReceiveAttack(p)
+		require.NoError(t, err)
+	}
+
+	// when
+	tally := b.Tally()
+
+	// then
+	assert.Equal(t, []ClassTally{
+		{Class: "ship", Length: 3, Afloat: 1, Sunk: 0},
+		{Class: "boat", Length: 2, Afloat: 1, Sunk: 1},
+	}, tally)
+}
+
+func TestBoard_ShipFields(t *testing.T) {
+	// given
+	b := InitBoard()
+	require.NoError(t, b.PlaceShip(CreateShip(4, 6, Up, 3)))
+	_, _, err := b.ReceiveAttack(Position{X: 3, Y: 6})
+	require.NoError(t, err)
+
+	// when
+	fields := b.ShipFields(Position{X: 3, Y: 6})
+	empty := b.ShipFields(Position{X: 5, Y: 6})
+
+	// then
+	assert.Equal(t, []Position{{2, 6}, {3, 6}, {4, 6}}, fields)
+	assert.Nil(t, empty)
+}
+
+func TestBoard_MarkWater(t *testing.T) {
+	// given
+	b := InitBoard()
+	require.NoError(t, b.Attack(Position{X: 0, Y: 0}, true))
+	require.NoError(t, b.Attack(Position{X: 0, Y: 1}, true))
+	require.NoError(t, b.Attack(Position{X: 1, Y: 1}, false))
+
+	// when
+	water := b.MarkWater([]Position{{0, 0}, {0, 1}})
+
+	// then
+	assert.Equal(t, []Position{{1, 0}, {0, 2}}, water)
+	assert.Equal(t, "xxo-------", b.GetEnemyFields()[0])
+	assert.Equal(t, "oo--------", b.GetEnemyFields()[1])
+}
@@ -3,6 +3,9 @@ package game
 import (
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"sort"
 )
 
 const (
@@ -12,6 +15,9 @@ const (
 	Right = "right"
 )
 
+//BoardSize is the count of rows and columns of the playing field.
+const BoardSize = 10
+
 const (
 	Hit      = 'x'
 	Miss     = 'o'
@@ -20,6 +26,16 @@ const (
 	Empty    = '-'
 )
 
+//Errors returned by the board, so the callers can recognise the reason of the failure.
+var (
+	ErrUnknownDirection    = errors.New("unknown positioning direction")
+	ErrShipOutOfBounds     = errors.New("ship goes out of bounds")
+	ErrFieldsTaken         = errors.New("some of the fields are already taken")
+	ErrPositionOutOfBounds = errors.New("position out of bounds")
+	ErrInvalidFields       = errors.New("invalid fields of the board")
+	ErrAlreadyShot         = errors.New("the field has already been shot")
+)
+
 type Position struct {
 	X int
 	Y int
@@ -85,7 +101,7 @@ func (s *Ship) GetPositions() ([]Position, error) {
 	case Down:
 		return fill(start,
 			s.length,
-			func(p Position, offset int) bool { return p.X+offset >= 10 },
+			func(p Position, offset int) bool { return p.X+offset >= BoardSize },
 			func(p Position, i int) Position {
 				return Position{
 					X: p.X + i,
@@ -104,7 +120,7 @@ func (s *Ship) GetPositions() ([]Position, error) {
 	case Right:
 		return fill(start,
 			s.length,
-			func(p Position, offset int) bool { return p.Y+offset >= 10 },
+			func(p Position, offset int) bool { return p.Y+offset >= BoardSize },
 			func(p Position, i int) Position {
 				return Position{
 					X: p.X,
@@ -112,17 +128,21 @@ func (s *Ship) GetPositions() ([]Position, error) {
 				}
 			})
 	default:
-		return nil, errors.New("unknown positioning direction")
+		return nil, ErrUnknownDirection
 	}
 }
 
 func fill(start Position, size int, outOfBounds func(Position, int) bool, next func(Position, int) Position) ([]Position, error) {
 	if outOfBounds(start, size) {
-		return nil, errors.New("ship goes out of bounds")
+		return nil, ErrShipOutOfBounds
 	}
 	var positions []Position
 	for i := 0; i < size; i++ {
-		positions = append(positions, next(start, i))
+		p := next(start, i)
+		if isOutOfBounds(p) {
+			return nil, ErrShipOutOfBounds
+		}
+		positions = append(positions, p)
 	}
 	return positions, nil
 }
@@ -133,10 +153,10 @@ type Board struct {
 }
 
 func initFields() [][]rune {
-	fields := make([][]rune, 10)
-	for i := 0; i < 10; i++ {
-		fields[i] = make([]rune, 10)
-		for j := 0; j < 10; j++ {
+	fields := make([][]rune, BoardSize)
+	for i := 0; i < BoardSize; i++ {
+		fields[i] = make([]rune, BoardSize)
+		for j := 0; j < BoardSize; j++ {
 			fields[i][j] = Empty
 		}
 	}
@@ -151,28 +171,101 @@ func InitBoard() *Board {
 	}
 }
 
-func printBoard(b [][]rune) {
-	fmt.Print("  ")
-	for i := 0; i < 10; i++ {
-		fmt.Print(i, " ")
+//LoadBoard returns board with the provided own and enemy fields, one string for each row, as returned
+//by GetOwnFields and GetEnemyFields. If there isn't a field for every position of the board or some of
+//the fields is unknown ErrInvalidFields is returned.
+func LoadBoard(own, enemy []string) (*Board, error) {
+	ownFields, err := rowsToFields(own)
+	if err != nil {
+		return nil, err
+	}
+	enemyFields, err := rowsToFields(enemy)
+	if err != nil {
+		return nil, err
+	}
+	return &Board{
+		ownFields:   ownFields,
+		enemyFields: enemyFields,
+	}, nil
+}
+
+func rowsToFields(rows []string) ([][]rune, error) {
+	if len(rows) != BoardSize {
+		return nil, ErrInvalidFields
+	}
+	fields := make([][]rune, BoardSize)
+	for i, row := range rows {
+		fields[i] = []rune(row)
+		if len(fields[i]) != BoardSize {
+			return nil, ErrInvalidFields
+		}
+		for _, field := range fields[i] {
+			switch field {
+			case Hit, Miss, Taken, ShipArea, Empty:
+			default:
+				return nil, ErrInvalidFields
+			}
+		}
+	}
+	return fields, nil
+}
+
+func printBoard(w io.Writer, b [][]rune) {
+	fmt.Fprint(w, "  ")
+	for i := 0; i < BoardSize; i++ {
+		fmt.Fprint(w, i, " ")
 	}
-	fmt.Println("")
+	fmt.Fprintln(w, "")
 	for i, r := range b {
 		//a := fmt.Sprint()
-		fmt.Printf("%c ",'A' + i)
+		fmt.Fprintf(w, "%c ",'A' + i)
 		for _, c := range r {
-			fmt.Print(string(c), " ")
+			fmt.Fprint(w, string(c), " ")
 		}
-		fmt.Println("")
+		fmt.Fprintln(w, "")
 	}
 }
 
 func (b *Board) Print() {
-	fmt.Println("Enemy fields:")
-	printBoard(b.enemyFields)
+	b.Fprint(os.Stdout)
+}
+
+//Fprint writes the enemy and the own fields to w, so the board can be printed by clients
+//which don't write directly to the standard output.
+func (b *Board) Fprint(w io.Writer) {
+	fmt.Fprintln(w, "Enemy fields:")
+	printBoard(w, b.enemyFields)
 
-	fmt.Println("Own fields:")
-	printBoard(b.ownFields)
+	fmt.Fprintln(w, "Own fields:")
+	printBoard(w, b.ownFields)
+}
+
+//FprintFields writes the rows of the fields to w in the same format as Fprint, e.g. the fields of a board
+//received from the server.
+func FprintFields(w io.Writer, rows []string) {
+	fields := make([][]rune, len(rows))
+	for i, row := range rows {
+		fields[i] = []rune(row)
+	}
+	printBoard(w, fields)
+}
+
+//GetOwnFields returns the own fields as strings, one string for each row.
+func (b *Board) GetOwnFields() []string {
+	return fieldsToRows(b.ownFields)
+}
+
+//GetEnemyFields returns the enemy fields as strings, one string for each row.
+func (b *Board) GetEnemyFields() []string {
+	return fieldsToRows(b.enemyFields)
+}
+
+func fieldsToRows(fields [][]rune) []string {
+	rows := make([]string, len(fields))
+	for i, r := range fields {
+		rows[i] = string(r)
+	}
+	return rows
 }
 
 //PlaceShip marks the fields of the ship on own fields as taken(s) and the neighboring fields
@@ -186,7 +279,7 @@ func (b *Board) PlaceShip(ship Ship) error {
 
 	for _, position := range positions {
 		if b.ownFields[position.X][position.Y] != Empty {
-			return errors.New("some of the fields are already taken")
+			return ErrFieldsTaken
 		}
 	}
 
@@ -205,7 +298,7 @@ func (b *Board) PlaceShip(ship Ship) error {
 //field enemy field as miss(o) if success is false. Returns error if p is out of bounds.
 func (b *Board) Attack(p Position, success bool) error {
 	if isOutOfBounds(p) {
-		return errors.New("position out of bounds")
+		return ErrPositionOutOfBounds
 	}
 
 	if success == true {
@@ -220,10 +313,14 @@ func (b *Board) Attack(p Position, success bool) error {
 //is taken(s) the hit status is true, otherwise false. If the targeted field is taken(s) and
 //every other field which is part of the ship that is hit has already been hit the sunk status
 //is true, otherwise false. If the targeted field is out of bounds the method returns false,
-//false and non nil error.
+//false and non nil error. The own fields remember every attack as hit(x) or miss(o), so if the
+//field has already been attacked ErrAlreadyShot is returned and the board is left unchanged.
 func (b *Board) ReceiveAttack(p Position) (bool, bool, error) {
 	if isOutOfBounds(p) {
-		return false, false, errors.New("position out of bounds")
+		return false, false, ErrPositionOutOfBounds
+	}
+	if b.IsShot(p) {
+		return false, false, ErrAlreadyShot
 	}
 
 	if b.ownFields[p.X][p.Y] == Taken {
@@ -235,78 +332,131 @@ func (b *Board) ReceiveAttack(p Position) (bool, bool, error) {
 	}
 }
 
+//IsShot returns true if the own field has already been attacked by the enemy.
+func (b *Board) IsShot(p Position) bool {
+	field := b.ownFields[p.X][p.Y]
+	return field == Hit || field == Miss
+}
+
 //ShipIsSunk returns true if all fields taken by the hit ship are already hit, false otherwise.
 func (b *Board) ShipIsSunk(p Position) bool {
-	pos := Position{
-		X: p.X - 1,
-		Y: p.Y,
-	}
-	for !isOutOfBounds(pos) {
-		if b.ownFields[pos.X][pos.Y] == Taken {
+	for _, field := range b.ShipFields(p) {
+		if b.ownFields[field.X][field.Y] == Taken {
 			return false
-		} else if b.ownFields[pos.X][pos.Y] == ShipArea {
-			break
 		}
-		pos.X--
 	}
+	return true
+}
 
-	pos = Position{
-		X: p.X + 1,
-		Y: p.Y,
-	}
-	for !isOutOfBounds(pos) {
-		if b.ownFields[pos.X][pos.Y] == Taken {
-			return false
-		} else if b.ownFields[pos.X][pos.Y] == ShipArea {
-			break
+//IsBeaten returns true if all ships are sunk, false otherwise.
+func (b *Board) IsBeaten() bool {
+	for _, r := range b.ownFields {
+		for _, v := range r {
+			if v == Taken {
+				return false
+			}
 		}
-		pos.X++
 	}
 
-	pos = Position{
-		X: p.X,
-		Y: p.Y - 1,
-	}
-	for !isOutOfBounds(pos) {
-		if b.ownFields[pos.X][pos.Y] == Taken {
-			return false
-		} else if b.ownFields[pos.X][pos.Y] == ShipArea {
-			break
+	return true
+}
+
+//CountShips returns the counts of the ships on the own fields by their length - all placed ships and
+//the ships which are not sunk yet.
+func (b *Board) CountShips() (map[int]int, map[int]int) {
+	placed, afloat := map[int]int{}, map[int]int{}
+	counted := make(map[Position]bool)
+	for x, row := range b.ownFields {
+		for y := range row {
+			if counted[Position{X: x, Y: y}] {
+				continue
+			}
+			fields := b.ShipFields(Position{X: x, Y: y})
+			if len(fields) == 0 {
+				continue
+			}
+
+			sunk := true
+			for _, p := range fields {
+				counted[p] = true
+				if b.ownFields[p.X][p.Y] == Taken {
+					sunk = false
+				}
+			}
+			placed[len(fields)]++
+			if !sunk {
+				afloat[len(fields)]++
+			}
 		}
-		pos.Y--
 	}
+	return placed, afloat
+}
+
+//Tally returns the tally of the ships placed on the own fields by their class from the longest one.
+func (b *Board) Tally() []ClassTally {
+	return tally(b.CountShips())
+}
 
-	pos = Position{
-		X: p.X,
-		Y: p.Y + 1,
+//ShipFields returns the own fields of the ship which covers p, both taken and hit, ordered by the rows
+//and the columns. If there is no ship at p nil is returned. The ships never touch each other, so every
+//line of taken and hit fields is one ship.
+func (b *Board) ShipFields(p Position) []Position {
+	if !b.isShipField(p) {
+		return nil
 	}
-	for !isOutOfBounds(pos) {
-		if b.ownFields[pos.X][pos.Y] == Taken {
-			return false
-		} else if b.ownFields[pos.X][pos.Y] == ShipArea {
-			break
+
+	fields := []Position{p}
+	visited := map[Position]bool{p: true}
+	for i := 0; i < len(fields); i++ {
+		for _, n := range getNeighbours(fields[i]) {
+			if !visited[n] && b.isShipField(n) {
+				visited[n] = true
+				fields = append(fields, n)
+			}
 		}
-		pos.Y++
 	}
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i].X != fields[j].X {
+			return fields[i].X < fields[j].X
+		}
+		return fields[i].Y < fields[j].Y
+	})
+	return fields
+}
 
-	return true
+//MarkWater marks the enemy fields next to the sunk ship as miss(o), as no other ship can be placed
+//there. Only the fields which haven't been shot at are marked and they are returned.
+func (b *Board) MarkWater(ship []Position) []Position {
+	var water []Position
+	for _, field := range ship {
+		for _, p := range getNeighbours(field) {
+			if !isOutOfBounds(p) && b.enemyFields[p.X][p.Y] == Empty {
+				b.enemyFields[p.X][p.Y] = Miss
+				water = append(water, p)
+			}
+		}
+	}
+	return water
 }
 
-//IsBeaten returns true if all ships are sunk, false otherwise.
-func (b *Board) IsBeaten() bool {
-	for _, r := range b.ownFields {
-		for _, v := range r {
-			if v == Taken {
-				return false
+//markOwnWater marks the own fields next to the sunk ship as miss(o), as the attacker already knows
+//there is no other ship, so they can't be shot at anymore.
+func (b *Board) markOwnWater(ship []Position) {
+	for _, field := range ship {
+		for _, p := range getNeighbours(field) {
+			if !isOutOfBounds(p) && !b.isShipField(p) {
+				b.ownFields[p.X][p.Y] = Miss
 			}
 		}
 	}
+}
 
-	return true
+func (b *Board) isShipField(p Position) bool {
+	return !isOutOfBounds(p) && (b.ownFields[p.X][p.Y] == Taken || b.ownFields[p.X][p.Y] == Hit)
 }
 
 func isOutOfBounds(p Position) bool {
-	return p.X < 0 || p.X >= 10 || p.Y < 0 || p.Y >= 10
+	return p.X < 0 || p.X >= BoardSize || p.Y < 0 || p.Y >= BoardSize
 }
 
 func getNeighbours(p Position) []Position {
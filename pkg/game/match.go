@@ -0,0 +1,196 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+)
+
+//The phases of the match.
+const (
+	PhasePlace = "place"
+	PhaseShoot = "shoot"
+	PhaseOver  = "over"
+)
+
+//ErrWrongPhase is returned by the match when the move is not allowed in its current phase.
+var ErrWrongPhase = errors.New("the move is not allowed in this phase of the match")
+
+//Match is a game between two players which is played without the server, e.g. two players on one
+//terminal or a player against the AI. The players are identified by their index 0 and 1. The turns
+//follow the same order as in the rooms of the server - the players take turns placing the ships
+//from the longest one, the first player starts shooting and the turn passes after every shot.
+type Match struct {
+	Rules     Rules
+	boards    [2]*Board
+	placement *Placement
+	current   int
+	phase     string
+	winner    int
+}
+
+//Placement is the order in which two players place their fleets. The players take turns and both of
+//them place the ships from the longest to the shortest one. It is shared by the matches and the rooms
+//of the server, so the ships are placed in the same order everywhere. The players are identified by
+//their index 0 and 1, the first player places the first ship.
+type Placement struct {
+	order   []int
+	placed  [2]int
+	current int
+}
+
+//Shot is the outcome of single shot. If the shot has sunk a ship Ship contains all its fields and Water
+//the fields around it, which are marked as misses on the board of the shooter. Won is true if the last
+//ship of the target is sunk.
+type Shot struct {
+	Hit   bool
+	Sunk  bool
+	Ship  []Position
+	Water []Position
+	Won   bool
+}
+
+//NewPlacement creates the placement of the fleets described by the rules.
+func NewPlacement(rules Rules) *Placement {
+	return &Placement{order: rules.PlacementOrder()}
+}
+
+//Current returns the index of the player who places the next ship.
+func (p *Placement) Current() int {
+	return p.current
+}
+
+//NextShip returns the length of the ship which has to be placed by the current player or 0 if he has
+//placed all of his ships.
+func (p *Placement) NextShip() int {
+	if p.placed[p.current] >= len(p.order) {
+		return 0
+	}
+	return p.order[p.placed[p.current]]
+}
+
+//Done returns true if both players have placed all of their ships.
+func (p *Placement) Done() bool {
+	return p.placed[0] == len(p.order) && p.placed[1] == len(p.order)
+}
+
+//ShipPlaced records that the current player has placed his next ship. The turn passes to the opponent
+//if he still has ships to place. When all ships are placed the turn passes to the player who didn't
+//place the last ship, as he is the one to shoot first.
+func (p *Placement) ShipPlaced() {
+	p.placed[p.current]++
+	if p.placed[1-p.current] < len(p.order) || p.placed[p.current] == len(p.order) {
+		p.current = 1 - p.current
+	}
+}
+
+//Fire shoots at the field on the board of the target and records the outcome on the board of the
+//shooter. The same field can't be shot twice - ErrAlreadyShot is returned. When a ship is sunk the
+//fields around it are marked as misses on both boards, as there can't be any ship, so they can't be
+//shot at later either.
+func Fire(shooter, target *Board, p Position) (Shot, error) {
+	hit, sunk, err := target.ReceiveAttack(p)
+	if err != nil {
+		return Shot{}, err
+	}
+	if err := shooter.Attack(p, hit); err != nil {
+		return Shot{}, err
+	}
+
+	shot := Shot{Hit: hit, Sunk: sunk, Won: hit && target.IsBeaten()}
+	if sunk {
+		shot.Ship = target.ShipFields(p)
+		shot.Water = shooter.MarkWater(shot.Ship)
+		target.markOwnWater(shot.Ship)
+	}
+	return shot, nil
+}
+
+//NewMatch creates match played by the rules. The first player is the one to place the first ship.
+func NewMatch(rules Rules) (*Match, error) {
+	if rules.BoardSize != BoardSize {
+		return nil, fmt.Errorf("board with size %d is not supported", rules.BoardSize)
+	}
+	return &Match{
+		Rules:     rules,
+		boards:    [2]*Board{InitBoard(), InitBoard()},
+		placement: NewPlacement(rules),
+		phase:     PhasePlace,
+		winner:    -1,
+	}, nil
+}
+
+//Phase returns the current phase of the match.
+func (m *Match) Phase() string {
+	return m.phase
+}
+
+//Current returns the index of the player on turn. When the match is over it is the winner.
+func (m *Match) Current() int {
+	return m.current
+}
+
+//Opponent returns the index of the player who waits for his turn.
+func (m *Match) Opponent() int {
+	return 1 - m.current
+}
+
+//Board returns the board of the player.
+func (m *Match) Board(player int) *Board {
+	return m.boards[player]
+}
+
+//Winner returns the index of the player who sunk all enemy ships or -1 if the match is not over.
+func (m *Match) Winner() int {
+	return m.winner
+}
+
+//NextShip returns the length of the ship which has to be placed by the current player or 0 if the
+//placement is over.
+func (m *Match) NextShip() int {
+	if m.phase != PhasePlace {
+		return 0
+	}
+	return m.placement.NextShip()
+}
+
+//Place places the next ship of the current player. The turns follow the Placement of the fleets, so
+//when all ships are placed the shooting starts with the player who didn't place the last ship.
+func (m *Match) Place(x, y int, direction string) (Ship, error) {
+	if m.phase != PhasePlace {
+		return Ship{}, ErrWrongPhase
+	}
+
+	ship := CreateShip(x, y, direction, m.NextShip())
+	if err := m.boards[m.current].PlaceShip(ship); err != nil {
+		return Ship{}, err
+	}
+
+	m.placement.ShipPlaced()
+	m.current = m.placement.Current()
+	if m.placement.Done() {
+		m.phase = PhaseShoot
+	}
+	return ship, nil
+}
+
+//Shoot attacks the field of the opponent and returns whether a ship was hit and whether it was sunk.
+//The shot is made by Fire, as on the server, so the same field can't be shot twice - ErrAlreadyShot is
+//returned and the player keeps the turn. If the last ship of the opponent is sunk the match is over,
+//otherwise the turn passes to the opponent.
+func (m *Match) Shoot(p Position) (bool, bool, error) {
+	if m.phase != PhaseShoot {
+		return false, false, ErrWrongPhase
+	}
+	shot, err := Fire(m.boards[m.current], m.boards[m.Opponent()], p)
+	if err != nil {
+		return false, false, err
+	}
+
+	if shot.Won {
+		m.phase = PhaseOver
+		m.winner = m.current
+	} else {
+		m.current = m.Opponent()
+	}
+	return shot.Hit, shot.Sunk, nil
+}
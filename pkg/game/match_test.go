@@ -0,0 +1,308 @@
+package game
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRules_PlacementOrder(t *testing.T) {
+	// when
+	classic := DefaultRules().PlacementOrder()
+	compact, _ := GetRules(Compact)
+
+	// then
+	assert.Equal(t, []int{5, 4, 4, 3, 3, 3, 2, 2, 2, 2}, classic)
+	assert.Equal(t, []int{5, 4, 3, 3, 2}, compact.PlacementOrder())
+}
+
+func TestShipClass(t *testing.T) {
+	// when
+	classes := []string{ShipClass(5), ShipClass(4), ShipClass(3), ShipClass(2), ShipClass(7)}
+
+	// then
+	assert.Equal(t, []string{"destroyer", "battleship", "ship", "boat", "7-field ship"}, classes)
+}
+
+func TestRules_Tally(t *testing.T) {
+	// when
+	compact, _ := GetRules(Compact)
+	tally := compact.Tally()
+
+	// then
+	assert.Equal(t, []ClassTally{
+		{Class: "destroyer", Length: 5, Afloat: 1},
+		{Class: "battleship", Length: 4, Afloat: 1},
+		{Class: "ship", Length: 3, Afloat: 2},
+		{Class: "boat", Length: 2, Afloat: 1},
+	}, tally)
+}
+
+//smallRules has fleet of two ships, so the whole match can be played in few moves.
+func smallRules() Rules {
+	return Rules{Name: "small", BoardSize: BoardSize, Fleet: map[int]int{3: 1, 2: 1}}
+}
+
+//placeSmallFleets places the ships of both players to the same fields: the ship with length 3 to
+//A0-A2 and the ship with length 2 to C0-C1.
+func placeSmallFleets(t *testing.T, m *Match) {
+	for _, x := range []int{0, 0, 2, 2} {
+		_, err := m.Place(x, 0, Right)
+		require.Nil(t, err)
+	}
+}
+
+func TestPlacement(t *testing.T) {
+	t.Run("players take turns from the longest ship", func(t *testing.T) {
+		// when
+		placement := NewPlacement(DefaultRules())
+		var players, ships []int
+		for !placement.Done() {
+			players = append(players, placement.Current())
+			ships = append(ships, placement.NextShip())
+			placement.ShipPlaced()
+		}
+
+		// then
+		assert.Equal(t, []int{0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1}, players)
+		assert.Equal(t, []int{5, 5, 4, 4, 4, 4, 3, 3, 3, 3, 3, 3, 2, 2, 2, 2, 2, 2, 2, 2}, ships)
+	})
+	t.Run("pass the turn to the player who shoots first after the last ship", func(t *testing.T) {
+		// when
+		placement := NewPlacement(smallRules())
+		for i := 0; i < 4; i++ {
+			placement.ShipPlaced()
+		}
+
+		// then
+		assert.True(t, placement.Done())
+		assert.Equal(t, 0, placement.Current())
+		assert.Equal(t, 0, placement.NextShip())
+	})
+}
+
+func TestFire(t *testing.T) {
+	t.Run("record the shot on both boards", func(t *testing.T) {
+		// when
+		shooter, target := InitBoard(), InitBoard()
+		require.Nil(t, target.PlaceShip(CreateShip(0, 0, Right, 2)))
+		shot, err := Fire(shooter, target, Position{X: 0, Y: 0})
+
+		// then
+		assert.Nil(t, err)
+		assert.Equal(t, Shot{Hit: true}, shot)
+		assert.Equal(t, string(Hit), shooter.GetEnemyFields()[0][:1])
+		assert.Equal(t, string(Hit), target.GetOwnFields()[0][:1])
+	})
+	t.Run("describe the sunk ship and win when it is the last one", func(t *testing.T) {
+		// when
+		shooter, target := InitBoard(), InitBoard()
+		require.Nil(t, target.PlaceShip(CreateShip(0, 0, Right, 2)))
+		_, _ = Fire(shooter, target, Position{X: 0, Y: 0})
+		shot, err := Fire(shooter, target, Position{X: 0, Y: 1})
+
+		// then
+		assert.Nil(t, err)
+		assert.True(t, shot.Sunk)
+		assert.True(t, shot.Won)
+		assert.ElementsMatch(t, []Position{{0, 0}, {0, 1}}, shot.Ship)
+		assert.ElementsMatch(t, []Position{{0, 2}, {1, 0}, {1, 1}}, shot.Water)
+	})
+	t.Run("fail on the water around the sunk ship", func(t *testing.T) {
+		// when
+		shooter, target := InitBoard(), InitBoard()
+		require.Nil(t, target.PlaceShip(CreateShip(0, 0, Right, 2)))
+		_, _ = Fire(shooter, target, Position{X: 0, Y: 0})
+		_, _ = Fire(shooter, target, Position{X: 0, Y: 1})
+		_, err := Fire(shooter, target, Position{X: 1, Y: 1})
+
+		// then
+		assert.Equal(t, ErrAlreadyShot, err)
+		assert.Equal(t, "xxo-------", target.GetOwnFields()[0])
+		assert.Equal(t, "oo--------", target.GetOwnFields()[1])
+	})
+	t.Run("sink ship next to the water of other sunk ship", func(t *testing.T) {
+		// when
+		shooter, target := InitBoard(), InitBoard()
+		require.Nil(t, target.PlaceShip(CreateShip(0, 0, Right, 2)))
+		require.Nil(t, target.PlaceShip(CreateShip(0, 3, Right, 2)))
+		_, _ = Fire(shooter, target, Position{X: 0, Y: 3})
+		_, _ = Fire(shooter, target, Position{X: 0, Y: 4})
+		_, _ = Fire(shooter, target, Position{X: 0, Y: 0})
+		shot, err := Fire(shooter, target, Position{X: 0, Y: 1})
+
+		// then
+		assert.Nil(t, err)
+		assert.True(t, shot.Sunk)
+	})
+	t.Run("fail on the field which has already been shot", func(t *testing.T) {
+		// when
+		shooter, target := InitBoard(), InitBoard()
+		_, _ = Fire(shooter, target, Position{X: 5, Y: 5})
+		_, err := Fire(shooter, target, Position{X: 5, Y: 5})
+
+		// then
+		assert.Equal(t, ErrAlreadyShot, err)
+	})
+}
+
+func TestNewMatch(t *testing.T) {
+	t.Run("fail on unsupported board size", func(t *testing.T) {
+		// when
+		rules := DefaultRules()
+		rules.BoardSize = 12
+		_, err := NewMatch(rules)
+
+		// then
+		assert.EqualError(t, err, "board with size 12 is not supported")
+	})
+	t.Run("start with placement of the longest ship", func(t *testing.T) {
+		// when
+		m, err := NewMatch(DefaultRules())
+
+		// then
+		assert.Nil(t, err)
+		assert.Equal(t, PhasePlace, m.Phase())
+		assert.Equal(t, 0, m.Current())
+		assert.Equal(t, 5, m.NextShip())
+		assert.Equal(t, -1, m.Winner())
+	})
+}
+
+func TestMatch_Place(t *testing.T) {
+	t.Run("players take turns", func(t *testing.T) {
+		// when
+		m, _ := NewMatch(smallRules())
+		first, err := m.Place(0, 0, Right)
+
+		// then
+		assert.Nil(t, err)
+		assert.Equal(t, CreateShip(0, 0, Right, 3), first)
+		assert.Equal(t, 1, m.Current())
+		assert.Equal(t, 3, m.NextShip())
+		assert.Equal(t, "sssb------", m.Board(0).GetOwnFields()[0])
+		assert.Equal(t, "----------", m.Board(1).GetOwnFields()[0])
+	})
+	t.Run("first player starts shooting after the placement", func(t *testing.T) {
+		// when
+		m, _ := NewMatch(smallRules())
+		placeSmallFleets(t, m)
+
+		// then
+		assert.Equal(t, PhaseShoot, m.Phase())
+		assert.Equal(t, 0, m.Current())
+		assert.Equal(t, 0, m.NextShip())
+	})
+	t.Run("keep the turn when the ship can't be placed", func(t *testing.T) {
+		// when
+		m, _ := NewMatch(smallRules())
+		_, _ = m.Place(0, 0, Right)
+		_, _ = m.Place(0, 0, Right)
+		_, err := m.Place(1, 0, Right)
+
+		// then
+		assert.Equal(t, ErrFieldsTaken, err)
+		assert.Equal(t, 0, m.Current())
+		assert.Equal(t, 2, m.NextShip())
+	})
+	t.Run("fail after the placement", func(t *testing.T) {
+		// when
+		m, _ := NewMatch(smallRules())
+		placeSmallFleets(t, m)
+		_, err := m.Place(5, 5, Right)
+
+		// then
+		assert.Equal(t, ErrWrongPhase, err)
+	})
+}
+
+func TestMatch_Shoot(t *testing.T) {
+	t.Run("fail during the placement", func(t *testing.T) {
+		// when
+		m, _ := NewMatch(smallRules())
+		_, _, err := m.Shoot(Position{X: 0, Y: 0})
+
+		// then
+		assert.Equal(t, ErrWrongPhase, err)
+	})
+	t.Run("turn passes after every shot", func(t *testing.T) {
+		// when
+		m, _ := NewMatch(smallRules())
+		placeSmallFleets(t, m)
+		hit, sunk, err := m.Shoot(Position{X: 0, Y: 0})
+		missHit, _, missErr := m.Shoot(Position{X: 5, Y: 5})
+
+		// then
+		assert.Nil(t, err)
+		assert.True(t, hit)
+		assert.False(t, sunk)
+		assert.Nil(t, missErr)
+		assert.False(t, missHit)
+		assert.Equal(t, 0, m.Current())
+		assert.Equal(t, string(Hit), m.Board(0).GetEnemyFields()[0][:1])
+		assert.Equal(t, string(Hit), m.Board(1).GetOwnFields()[0][:1])
+		assert.Equal(t, string(Miss), m.Board(0).GetOwnFields()[5][5:6])
+	})
+	t.Run("mark the water around the sunk ship", func(t *testing.T) {
+		// when
+		m, _ := NewMatch(smallRules())
+		placeSmallFleets(t, m)
+		_, _, _ = m.Shoot(Position{X: 2, Y: 0})
+		_, _, _ = m.Shoot(Position{X: 9, Y: 9})
+		hit, sunk, err := m.Shoot(Position{X: 2, Y: 1})
+
+		// then
+		assert.Nil(t, err)
+		assert.True(t, hit)
+		assert.True(t, sunk)
+		enemy := m.Board(0).GetEnemyFields()
+		assert.Equal(t, "oo--------", enemy[1])
+		assert.Equal(t, "xxo-------", enemy[2])
+		assert.Equal(t, "oo--------", enemy[3])
+	})
+	t.Run("fail on the field which has already been shot", func(t *testing.T) {
+		// when
+		m, _ := NewMatch(smallRules())
+		placeSmallFleets(t, m)
+		_, _, _ = m.Shoot(Position{X: 0, Y: 0})
+		_, _, _ = m.Shoot(Position{X: 5, Y: 5})
+		_, _, err := m.Shoot(Position{X: 0, Y: 0})
+
+		// then
+		assert.Equal(t, ErrAlreadyShot, err)
+		assert.Equal(t, 0, m.Current())
+	})
+	t.Run("fail out of the board", func(t *testing.T) {
+		// when
+		m, _ := NewMatch(smallRules())
+		placeSmallFleets(t, m)
+		_, _, err := m.Shoot(Position{X: 0, Y: BoardSize})
+
+		// then
+		assert.Equal(t, ErrPositionOutOfBounds, err)
+	})
+	t.Run("win when the last ship is sunk", func(t *testing.T) {
+		// when
+		m, _ := NewMatch(smallRules())
+		placeSmallFleets(t, m)
+		targets := []Position{{0, 0}, {0, 1}, {2, 0}, {2, 1}, {0, 2}}
+		var sunk bool
+		for i, p := range targets {
+			var err error
+			_, sunk, err = m.Shoot(p)
+			require.Nil(t, err)
+			if i < len(targets)-1 {
+				_, _, err = m.Shoot(Position{X: 9, Y: i})
+				require.Nil(t, err)
+			}
+		}
+		_, _, err := m.Shoot(Position{X: 9, Y: 9})
+
+		// then
+		assert.True(t, sunk)
+		assert.Equal(t, PhaseOver, m.Phase())
+		assert.Equal(t, 0, m.Winner())
+		assert.Equal(t, 0, m.Current())
+		assert.Equal(t, ErrWrongPhase, err)
+	})
+}
@@ -0,0 +1,145 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	Classic = "classic"
+	Compact = "compact"
+)
+
+//Rules describes the variant of the game played in a room. Fleet maps ship length to the
+//count of ships with that length which every player has to place on his board.
+type Rules struct {
+	Name      string
+	BoardSize int
+	Fleet     map[int]int
+}
+
+var ruleSets = map[string]Rules{
+	Classic: {
+		Name:      Classic,
+		BoardSize: BoardSize,
+		Fleet:     map[int]int{5: 1, 4: 2, 3: 3, 2: 4},
+	},
+	Compact: {
+		Name:      Compact,
+		BoardSize: BoardSize,
+		Fleet:     map[int]int{5: 1, 4: 1, 3: 2, 2: 1},
+	},
+}
+
+//shipClasses maps the length of the ship to the name of its class.
+var shipClasses = map[int]string{
+	5: "destroyer",
+	4: "battleship",
+	3: "ship",
+	2: "boat",
+}
+
+//ErrUnknownRules is returned when there is no rule set registered under the requested name.
+var ErrUnknownRules = errors.New("unknown rule set")
+
+//DefaultRules returns the classic rule set.
+func DefaultRules() Rules {
+	rules, _ := GetRules(Classic)
+	return rules
+}
+
+//GetRules returns a copy of the rule set registered under the provided name. If there is no
+//such rule set an error is returned.
+func GetRules(name string) (Rules, error) {
+	rules, ok := ruleSets[name]
+	if !ok {
+		return Rules{}, fmt.Errorf("%w %s", ErrUnknownRules, name)
+	}
+
+	fleet := make(map[int]int, len(rules.Fleet))
+	for length, count := range rules.Fleet {
+		fleet[length] = count
+	}
+	rules.Fleet = fleet
+	return rules, nil
+}
+
+//RuleSetNames returns the names of all registered rule sets in alphabetical order.
+func RuleSetNames() []string {
+	names := make([]string, 0, len(ruleSets))
+	for name := range ruleSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//LongestShip returns the length of the longest ship in the fleet.
+func (r Rules) LongestShip() int {
+	longest := 0
+	for length := range r.Fleet {
+		if length > longest {
+			longest = length
+		}
+	}
+	return longest
+}
+
+//PlacementOrder returns the lengths of all ships of the fleet in the order in which every player
+//places them, from the longest to the shortest one.
+func (r Rules) PlacementOrder() []int {
+	var order []int
+	for length := r.LongestShip(); length > 0; length-- {
+		for i := 0; i < r.Fleet[length]; i++ {
+			order = append(order, length)
+		}
+	}
+	return order
+}
+
+//ShipClass returns the name of the class of the ships with the length. Ships with length which has no
+//class are named by their length.
+func ShipClass(length int) string {
+	if class, ok := shipClasses[length]; ok {
+		return class
+	}
+	return fmt.Sprintf("%d-field ship", length)
+}
+
+//ClassTally is the count of the ships of one class in the fleet - the ships which are still afloat and
+//the sunk ones.
+type ClassTally struct {
+	Class  string
+	Length int
+	Afloat int
+	Sunk   int
+}
+
+//Tally returns the tally of the whole fleet of the rules, all ships afloat, from the longest class.
+func (r Rules) Tally() []ClassTally {
+	return tally(r.Fleet, r.Fleet)
+}
+
+//tally returns the tally of the placed ships by their class from the longest one. Afloat maps the
+//length of the ships to the count of the ships which are not sunk yet.
+func tally(placed, afloat map[int]int) []ClassTally {
+	lengths := make([]int, 0, len(placed))
+	for length, count := range placed {
+		if count > 0 {
+			lengths = append(lengths, length)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(lengths)))
+
+	classes := make([]ClassTally, 0, len(lengths))
+	for _, length := range lengths {
+		classes = append(classes, ClassTally{
+			Class:  ShipClass(length),
+			Length: length,
+			Afloat: afloat[length],
+			Sunk:   placed[length] - afloat[length],
+		})
+	}
+	return classes
+}
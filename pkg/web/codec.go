@@ -0,0 +1,126 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+//Encodings of the messages which can be negotiated through hello request. JSON is used by default.
+const (
+	JSON    = "json"
+	Msgpack = "msgpack"
+)
+
+//SupportedEncodings lists the encodings supported by this package, the default one first.
+var SupportedEncodings = []string{JSON, Msgpack}
+
+//Codec encodes and decodes the messages exchanged with the client. The messages are encoded with
+//the same field names regardless of the codec, so every codec can carry both protocol versions.
+type Codec interface {
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return JSON
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+//msgpackCodec encodes the messages as MessagePack. The values are converted through their JSON form,
+//so the json tags of the messages and payloads are respected. The keys of the maps are sorted, so equal
+//messages are always encoded the same way.
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string {
+	return Msgpack
+}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err = json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetSortMapKeys(true)
+	if err = enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	var value interface{}
+	if err := msgpack.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, v)
+}
+
+var codecs = map[string]Codec{
+	JSON:    jsonCodec{},
+	Msgpack: msgpackCodec{},
+}
+
+//DefaultCodec returns the JSON codec, which is used until other encoding is negotiated.
+func DefaultCodec() Codec {
+	return codecs[JSON]
+}
+
+//GetCodec returns the codec of the encoding with the provided name. An error is returned if the
+//encoding is not supported.
+func GetCodec(name string) (Codec, error) {
+	codec, ok := codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding %s", name)
+	}
+	return codec, nil
+}
+
+//NegotiateEncoding returns the codec of the first encoding from the list of the other side, which is
+//supported by this package. If the list is empty the default codec is returned. An error is returned
+//if none of the encodings is supported.
+func NegotiateEncoding(encodings []string) (Codec, error) {
+	if len(encodings) == 0 {
+		return DefaultCodec(), nil
+	}
+	for _, name := range encodings {
+		if codec, err := GetCodec(name); err == nil {
+			return codec, nil
+		}
+	}
+	return nil, fmt.Errorf("none of the encodings %v is supported", encodings)
+}
+
+//Transcode decodes the message with the from codec and encodes it with the to codec.
+func Transcode(data []byte, from Codec, to Codec) ([]byte, error) {
+	if from.Name() == to.Name() {
+		return data, nil
+	}
+	var value interface{}
+	if err := from.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return to.Marshal(value)
+}
@@ -0,0 +1,66 @@
+package web
+
+import (
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	t.Run("choose the first supported encoding", func(t *testing.T) {
+		// then
+		codec, err := NegotiateEncoding([]string{"protobuf", Msgpack, JSON})
+		assert.Nil(t, err)
+		assert.Equal(t, Msgpack, codec.Name())
+	})
+	t.Run("use json when no encoding is listed", func(t *testing.T) {
+		// then
+		codec, err := NegotiateEncoding(nil)
+		assert.Nil(t, err)
+		assert.Equal(t, JSON, codec.Name())
+	})
+	t.Run("fail when there is no supported encoding", func(t *testing.T) {
+		// then
+		_, err := NegotiateEncoding([]string{"protobuf"})
+		assert.EqualError(t, err, "none of the encodings [protobuf] is supported")
+	})
+}
+
+func TestCodec(t *testing.T) {
+	t.Run("encode and decode request with msgpack", func(t *testing.T) {
+		// when
+		codec, _ := GetCodec(Msgpack)
+		req := BuildRequest("id", pkg.Shoot, map[string]interface{}{"x": float64(1), "y": float64(2)})
+		req.RequestId = "1"
+
+		// then
+		data, err := codec.Marshal(req)
+		assert.Nil(t, err)
+
+		var decoded Request
+		assert.Nil(t, codec.Unmarshal(data, &decoded))
+		assert.Equal(t, req, decoded)
+	})
+	t.Run("transcode version 2 message between json and msgpack", func(t *testing.T) {
+		// when
+		codec, _ := GetCodec(Msgpack)
+		data, _ := EncodeResponse(BuildResponse(pkg.ShootOutcome, "", map[string]interface{}{"x": 1, "y": 2, "hit": true}), ProtocolV2)
+
+		// then
+		packed, err := Transcode(data, DefaultCodec(), codec)
+		assert.Nil(t, err)
+		assert.NotEqual(t, data, packed)
+
+		unpacked, err := Transcode(packed, codec, DefaultCodec())
+		assert.Nil(t, err)
+		assert.JSONEq(t, string(data), string(unpacked))
+	})
+	t.Run("fail when message is not valid msgpack", func(t *testing.T) {
+		// when
+		codec, _ := GetCodec(Msgpack)
+
+		// then
+		_, err := Transcode([]byte{0xc1}, codec, DefaultCodec())
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,59 @@
+package web
+
+import "github.com/StanislavStefanov/Battleships/pkg"
+
+//Error codes sent in the Code field of the responses, so clients can react to failures without
+//matching on the message.
+const (
+	InvalidRequest      = "INVALID_REQUEST"
+	UnknownAction       = "UNKNOWN_ACTION"
+	MissingArgument     = "MISSING_ARGUMENT"
+	InvalidArgument     = "INVALID_ARGUMENT"
+	UnsupportedVersion  = "UNSUPPORTED_VERSION"
+	UnsupportedEncoding = "UNSUPPORTED_ENCODING"
+	RoomNotFound        = "ROOM_NOT_FOUND"
+	RoomFull            = "ROOM_FULL"
+	GameNotFound        = "GAME_NOT_FOUND"
+	PlayerNotFound      = "PLAYER_NOT_FOUND"
+	RoomClosed          = "ROOM_CLOSED"
+	NoFreeRooms         = "NO_FREE_ROOMS"
+	TooManyRooms        = "TOO_MANY_ROOMS"
+	UnknownRules        = "UNKNOWN_RULES"
+	NameTooLong         = "NAME_TOO_LONG"
+	NameTaken           = "NAME_TAKEN"
+	NotYourTurn         = "NOT_YOUR_TURN"
+	WrongPhase          = "WRONG_PHASE"
+	ActionNotAllowed    = "ACTION_NOT_ALLOWED"
+	OutOfBounds         = "OUT_OF_BOUNDS"
+	Overlap             = "OVERLAP"
+	AlreadyShot         = "ALREADY_SHOT"
+	InvalidDirection    = "INVALID_DIRECTION"
+	EmptyMessage        = "EMPTY_MESSAGE"
+	MessageTooLong      = "MESSAGE_TOO_LONG"
+	RateLimited         = "RATE_LIMITED"
+	InternalError       = "INTERNAL_ERROR"
+)
+
+//Error is a failure which is reported to the client together with its code.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+//NewError returns Error with the provided code and message.
+func NewError(code string, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+//BuildErrorResponse returns Response with status Retry carrying the message and the code of the error.
+func BuildErrorResponse(err *Error) Response {
+	return Response{
+		Action:  pkg.Retry,
+		Message: err.Message,
+		Code:    err.Code,
+	}
+}
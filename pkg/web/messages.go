@@ -0,0 +1,255 @@
+package web
+
+import "github.com/StanislavStefanov/Battleships/pkg"
+
+//EmptyPayload is the payload of the messages which don't carry any arguments.
+type EmptyPayload struct{}
+
+//HelloPayload is exchanged while negotiating the protocol version and the encoding. The client lists
+//the versions it supports in Versions and the encodings it prefers in Encodings and the server answers
+//with the chosen ones in Version and Encoding.
+type HelloPayload struct {
+	Versions  []int    `json:"versions,omitempty"`
+	Version   int      `json:"version,omitempty"`
+	Encodings []string `json:"encodings,omitempty"`
+	Encoding  string   `json:"encoding,omitempty"`
+}
+
+//ListRoomsPayload is the payload of ls-rooms request. All fields are optional.
+type ListRoomsPayload struct {
+	Open     bool   `json:"open,omitempty"`
+	Rules    string `json:"rules,omitempty"`
+	Page     int    `json:"page,omitempty"`
+	PageSize int    `json:"pageSize,omitempty"`
+}
+
+//CreateRoomPayload is the payload of create-room request. All fields are optional.
+type CreateRoomPayload struct {
+	Name  string `json:"name,omitempty"`
+	Rules string `json:"rules,omitempty"`
+}
+
+//JoinRoomPayload is the payload of join-room request. The name is optional.
+type JoinRoomPayload struct {
+	RoomId string `json:"roomId"`
+	Name   string `json:"name,omitempty"`
+}
+
+//SpectatePayload is the payload of spectate request.
+type SpectatePayload struct {
+	RoomId string `json:"roomId"`
+	Full   bool   `json:"full,omitempty"`
+}
+
+//ChatPayload is the payload of chat request.
+type ChatPayload struct {
+	Text string `json:"text"`
+}
+
+//MutePayload is the payload of mute request.
+type MutePayload struct {
+	Name string `json:"name"`
+}
+
+//PlacePayload is the payload of place request. X and Y are the coordinates of the starting field
+//of the ship and Direction is one of up, down, left, right.
+type PlacePayload struct {
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	Direction string `json:"direction"`
+}
+
+//ShootPayload is the payload of shoot request.
+type ShootPayload struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+//RematchPayload is the payload of rematch request. If Accept is missing the rematch is accepted.
+type RematchPayload struct {
+	Accept *bool `json:"accept,omitempty"`
+}
+
+//RegisterPayload is the payload of register response.
+type RegisterPayload struct {
+	Id string `json:"id"`
+}
+
+//WaitPayload is the payload of wait response. Id is set only when the player has created a room and
+//Rules, the name of the rule set of the room, when the player has entered the room.
+type WaitPayload struct {
+	Id    string `json:"id,omitempty"`
+	Rules string `json:"rules,omitempty"`
+}
+
+//PlaceShipPayload is the payload of place response and tells the length of the ship the player has to place.
+type PlaceShipPayload struct {
+	Length int `json:"length"`
+}
+
+//PlacedPayload is the payload of placed response and describes the placed ship.
+type PlacedPayload struct {
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	Direction string `json:"direction"`
+	Length    int    `json:"length"`
+}
+
+//Cell is single field of the board.
+type Cell struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+//FleetClass is the count of the ships of one class which are still afloat and of the sunk ones.
+type FleetClass struct {
+	Class  string `json:"class"`
+	Length int    `json:"length"`
+	Afloat int    `json:"afloat"`
+	Sunk   int    `json:"sunk"`
+}
+
+//ShotPayload is the payload of shoot-outcome and shoot responses and describes the last shot. When
+//the shot has sunk a ship, Class is the class of the ship, Cells are all its fields and Water are the
+//fields around it, which the shooter hasn't shot at yet and which are marked as misses on his board.
+//Fleet is the tally of the ships of the opponent of the player who receives the response, from the
+//longest class.
+type ShotPayload struct {
+	X     int          `json:"x"`
+	Y     int          `json:"y"`
+	Hit   bool         `json:"hit"`
+	Sunk  bool         `json:"sunk"`
+	Class string       `json:"class,omitempty"`
+	Cells []Cell       `json:"cells,omitempty"`
+	Water []Cell       `json:"water,omitempty"`
+	Fleet []FleetClass `json:"fleet,omitempty"`
+}
+
+//InfoPayload is the payload of info response. Rooms, Total, Page and PageSize are set when listing
+//rooms, Spectators when the count of spectators changes and Name and Muted when muting a player.
+type InfoPayload struct {
+	Rooms      []RoomInfo `json:"rooms,omitempty"`
+	Total      int        `json:"total,omitempty"`
+	Page       int        `json:"page,omitempty"`
+	PageSize   int        `json:"pageSize,omitempty"`
+	Spectators int        `json:"spectators,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	Muted      bool       `json:"muted,omitempty"`
+}
+
+//ChatMessagePayload is the payload of chat response. The text of the message is sent as message of
+//the response.
+type ChatMessagePayload struct {
+	From    string `json:"from"`
+	Channel string `json:"channel"`
+}
+
+//SpectatingPayload is the payload of spectate response.
+type SpectatingPayload struct {
+	Id       string `json:"id"`
+	FullView bool   `json:"fullView"`
+	Phase    string `json:"phase"`
+}
+
+//EventPayload is the payload of event response sent to the spectators. The fields of the boards are
+//set only for spectators with full view. Turn is the name of the player who shoots next, it is empty
+//after the shot which has ended the game.
+type EventPayload struct {
+	Shooter       string   `json:"shooter"`
+	Target        string   `json:"target"`
+	X             int      `json:"x"`
+	Y             int      `json:"y"`
+	Hit           bool     `json:"hit"`
+	Sunk          bool     `json:"sunk"`
+	Turn          string   `json:"turn,omitempty"`
+	ShooterFields []string `json:"shooterFields,omitempty"`
+	TargetFields  []string `json:"targetFields,omitempty"`
+}
+
+//GameOverPayload is the payload of game-over response sent to the spectators.
+type GameOverPayload struct {
+	Winner       string   `json:"winner"`
+	Loser        string   `json:"loser"`
+	WinnerFields []string `json:"winnerFields"`
+	LoserFields  []string `json:"loserFields"`
+}
+
+//PlayerSummary is the final board of the player in the game which has just ended together with his
+//stats: the count of the shots he has fired and of the hits, the percentage of the hits, the longest
+//run of hits in a row, the count of his turns, both placements and shots, and the time in seconds he
+//has spent on them.
+type PlayerSummary struct {
+	Name          string   `json:"name"`
+	Fields        []string `json:"fields"`
+	Shots         int      `json:"shots"`
+	Hits          int      `json:"hits"`
+	HitPercentage float64  `json:"hitPercentage"`
+	LongestStreak int      `json:"longestStreak"`
+	Turns         int      `json:"turns"`
+	TimeSpent     float64  `json:"timeSpent"`
+}
+
+//SummaryPayload is the payload of win and lose responses. It reveals the boards of both players with
+//their stats, both when the last ship is sunk and when the opponent leaves the game.
+type SummaryPayload struct {
+	Winner *PlayerSummary `json:"winner,omitempty"`
+	Loser  *PlayerSummary `json:"loser,omitempty"`
+}
+
+//StatePayload is the payload of state response, the snapshot of the game of the player kept by the
+//server. Phase is lobby, waiting, placing, shooting or rematch and Turn is true when the player is on
+//turn. OwnFields and EnemyFields are the rows of the boards of the player. NextShip is the length of
+//the ship the player has to place now. ToPlace and Afloat map the length of the ships to the count
+//of the ships the player has yet to place and of his ships which are not sunk yet. Fleet is the tally
+//of the ships of the opponent once the shooting has started.
+type StatePayload struct {
+	Phase       string       `json:"phase"`
+	Rules       string       `json:"rules,omitempty"`
+	Turn        bool         `json:"turn"`
+	NextShip    int          `json:"nextShip,omitempty"`
+	OwnFields   []string     `json:"ownFields,omitempty"`
+	EnemyFields []string     `json:"enemyFields,omitempty"`
+	ToPlace     map[int]int  `json:"toPlace,omitempty"`
+	Afloat      map[int]int  `json:"afloat,omitempty"`
+	Fleet       []FleetClass `json:"fleet,omitempty"`
+}
+
+//requestPayloads maps the action of every request to the type of its payload.
+var requestPayloads = map[string]func() interface{}{
+	Hello:          func() interface{} { return &HelloPayload{} },
+	pkg.Exit:       func() interface{} { return &EmptyPayload{} },
+	pkg.ListRooms:  func() interface{} { return &ListRoomsPayload{} },
+	pkg.CreateRoom: func() interface{} { return &CreateRoomPayload{} },
+	pkg.JoinRoom:   func() interface{} { return &JoinRoomPayload{} },
+	pkg.JoinRandom: func() interface{} { return &EmptyPayload{} },
+	pkg.Spectate:   func() interface{} { return &SpectatePayload{} },
+	pkg.Chat:       func() interface{} { return &ChatPayload{} },
+	pkg.Mute:       func() interface{} { return &MutePayload{} },
+	pkg.PlaceShip:  func() interface{} { return &PlacePayload{} },
+	pkg.Shoot:      func() interface{} { return &ShootPayload{} },
+	pkg.Rematch:    func() interface{} { return &RematchPayload{} },
+	pkg.State:      func() interface{} { return &EmptyPayload{} },
+}
+
+//responsePayloads maps the action of every response to the type of its payload.
+var responsePayloads = map[string]func() interface{}{
+	Hello:            func() interface{} { return &HelloPayload{} },
+	pkg.Register:     func() interface{} { return &RegisterPayload{} },
+	pkg.Wait:         func() interface{} { return &WaitPayload{} },
+	pkg.Retry:        func() interface{} { return &EmptyPayload{} },
+	pkg.PlaceShip:    func() interface{} { return &PlaceShipPayload{} },
+	pkg.Placed:       func() interface{} { return &PlacedPayload{} },
+	pkg.Shoot:        func() interface{} { return &ShotPayload{} },
+	pkg.ShootOutcome: func() interface{} { return &ShotPayload{} },
+	pkg.Win:          func() interface{} { return &SummaryPayload{} },
+	pkg.Lose:         func() interface{} { return &SummaryPayload{} },
+	pkg.Info:         func() interface{} { return &InfoPayload{} },
+	pkg.Chat:         func() interface{} { return &ChatMessagePayload{} },
+	pkg.Spectate:     func() interface{} { return &SpectatingPayload{} },
+	pkg.Event:        func() interface{} { return &EventPayload{} },
+	pkg.GameOver:     func() interface{} { return &GameOverPayload{} },
+	pkg.Rematch:      func() interface{} { return &EmptyPayload{} },
+	pkg.Lobby:        func() interface{} { return &EmptyPayload{} },
+	pkg.Ack:          func() interface{} { return &EmptyPayload{} },
+	pkg.State:        func() interface{} { return &StatePayload{} },
+}
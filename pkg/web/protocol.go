@@ -0,0 +1,239 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+//ProtocolV1 is the original wire protocol, in which Request and Response are encoded as JSON with
+//untyped args. In ProtocolV2 every message is wrapped into Message, whose payload has fixed type
+//according to the type of the message.
+const (
+	ProtocolV1 = 1
+	ProtocolV2 = 2
+)
+
+//Hello is the action of the message through which the protocol version is negotiated. The client
+//sends the versions it supports and the server answers with the version used from then on. Clients
+//which never send hello are served with ProtocolV1.
+const Hello = "hello"
+
+//SupportedVersions lists the protocol versions supported by this package from the oldest to the latest.
+var SupportedVersions = []int{ProtocolV1, ProtocolV2}
+
+//Message is the envelope of all messages in ProtocolV2. Type discriminates the payload: for requests
+//it is the action of the request and for responses it is the action of the response. PlayerId and Ack
+//are set only for requests, Message and Code only for responses. RequestId is set by the client for
+//requests and echoed in the responses to them.
+type Message struct {
+	Version   int             `json:"v"`
+	Type      string          `json:"type"`
+	PlayerId  string          `json:"playerId,omitempty"`
+	Message   string          `json:"message,omitempty"`
+	Code      string          `json:"code,omitempty"`
+	RequestId string          `json:"requestId,omitempty"`
+	Ack       bool            `json:"ack,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+//Negotiate returns the latest protocol version supported both by this package and by the other side.
+//An error is returned if there is no such version.
+func Negotiate(versions []int) (int, error) {
+	chosen := 0
+	for _, v := range versions {
+		for _, supported := range SupportedVersions {
+			if v == supported && v > chosen {
+				chosen = v
+			}
+		}
+	}
+	if chosen == 0 {
+		return 0, fmt.Errorf("none of the protocol versions %v is supported", versions)
+	}
+	return chosen, nil
+}
+
+//GetVersion returns the protocol version of the encoded message. Messages without version are
+//considered ProtocolV1 messages.
+func GetVersion(data []byte) (int, error) {
+	var header struct {
+		Version int `json:"v"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, err
+	}
+	if header.Version == 0 {
+		return ProtocolV1, nil
+	}
+	return header.Version, nil
+}
+
+//EncodeRequest encodes the request according to the provided protocol version. The args of the
+//request must match the payload type of its action.
+func EncodeRequest(req Request, version int) ([]byte, error) {
+	if version == ProtocolV1 {
+		return json.Marshal(req)
+	}
+	if version != ProtocolV2 {
+		return nil, fmt.Errorf("unsupported protocol version %d", version)
+	}
+
+	payload, err := encodePayload(requestPayloads[req.Action], req.Action, req.Args)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Message{
+		Version:   version,
+		Type:      req.Action,
+		PlayerId:  req.PlayerId,
+		RequestId: req.RequestId,
+		Ack:       req.Ack,
+		Payload:   payload,
+	})
+}
+
+//DecodeRequest decodes request encoded in any of the supported protocol versions and returns it
+//together with the version it was encoded in. An error is returned if the payload of ProtocolV2
+//request doesn't match the type of its action.
+func DecodeRequest(data []byte) (Request, int, error) {
+	version, err := GetVersion(data)
+	if err != nil {
+		return Request{}, 0, err
+	}
+
+	switch version {
+	case ProtocolV1:
+		var req Request
+		err = json.Unmarshal(data, &req)
+		return req, version, err
+	case ProtocolV2:
+		var msg Message
+		if err = json.Unmarshal(data, &msg); err != nil {
+			return Request{}, version, err
+		}
+		args, err := decodePayload(requestPayloads[msg.Type], msg.Type, msg.Payload)
+		req := BuildRequest(msg.PlayerId, msg.Type, args)
+		req.RequestId = msg.RequestId
+		req.Ack = msg.Ack
+		return req, version, err
+	default:
+		return Request{}, version, fmt.Errorf("unsupported protocol version %d", version)
+	}
+}
+
+//EncodeResponse encodes the response according to the provided protocol version. The args of the
+//response must match the payload type of its action.
+func EncodeResponse(resp Response, version int) ([]byte, error) {
+	if version == ProtocolV1 {
+		return json.Marshal(resp)
+	}
+	if version != ProtocolV2 {
+		return nil, fmt.Errorf("unsupported protocol version %d", version)
+	}
+
+	payload, err := encodePayload(responsePayloads[resp.Action], resp.Action, resp.Args)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Message{
+		Version:   version,
+		Type:      resp.Action,
+		Message:   resp.Message,
+		Code:      resp.Code,
+		RequestId: resp.RequestId,
+		Payload:   payload,
+	})
+}
+
+//DecodeResponse decodes response encoded in any of the supported protocol versions and returns it
+//together with the version it was encoded in.
+func DecodeResponse(data []byte) (Response, int, error) {
+	version, err := GetVersion(data)
+	if err != nil {
+		return Response{}, 0, err
+	}
+
+	switch version {
+	case ProtocolV1:
+		var resp Response
+		err = json.Unmarshal(data, &resp)
+		return resp, version, err
+	case ProtocolV2:
+		var msg Message
+		if err = json.Unmarshal(data, &msg); err != nil {
+			return Response{}, version, err
+		}
+		args, err := decodePayload(responsePayloads[msg.Type], msg.Type, msg.Payload)
+		resp := BuildResponse(msg.Type, msg.Message, args)
+		resp.Code = msg.Code
+		resp.RequestId = msg.RequestId
+		return resp, version, err
+	default:
+		return Response{}, version, fmt.Errorf("unsupported protocol version %d", version)
+	}
+}
+
+//DecodePayload decodes the args of the message into the typed payload v, e.g. *ShotPayload.
+func DecodePayload(args map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+//encodePayload decodes the args into the payload type created by newPayload and encodes the typed
+//payload, so only its fields are sent. The args of messages with unknown type are encoded as they are.
+func encodePayload(newPayload func() interface{}, messageType string, args map[string]interface{}) (json.RawMessage, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	if newPayload == nil {
+		return json.Marshal(args)
+	}
+	payload := newPayload()
+	if err := DecodePayload(args, payload); err != nil {
+		return nil, fmt.Errorf("invalid payload for %s: %s", messageType, err)
+	}
+	return json.Marshal(payload)
+}
+
+//decodePayload decodes the payload into the type created by newPayload and returns the fields of the
+//typed payload which were sent as args, the form in which ProtocolV1 messages are passed on. The payload
+//of messages with unknown type is returned as it is.
+func decodePayload(newPayload func() interface{}, messageType string, payload json.RawMessage) (map[string]interface{}, error) {
+	if len(payload) == 0 || string(payload) == "null" {
+		return nil, nil
+	}
+	var typed interface{}
+	if newPayload != nil {
+		typed = newPayload()
+		if err := json.Unmarshal(payload, typed); err != nil {
+			return nil, fmt.Errorf("invalid payload for %s: %s", messageType, err)
+		}
+	}
+
+	var sent map[string]interface{}
+	if err := json.Unmarshal(payload, &sent); err != nil {
+		return nil, errors.New("payload must be an object")
+	}
+	if typed == nil {
+		return sent, nil
+	}
+
+	data, err := json.Marshal(typed)
+	if err != nil {
+		return nil, err
+	}
+	var args map[string]interface{}
+	if err = json.Unmarshal(data, &args); err != nil {
+		return nil, err
+	}
+	for key := range args {
+		if _, ok := sent[key]; !ok {
+			delete(args, key)
+		}
+	}
+	return args, nil
+}
@@ -0,0 +1,136 @@
+package web
+
+import (
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	t.Run("choose the latest common version", func(t *testing.T) {
+		// then
+		version, err := Negotiate([]int{ProtocolV1, ProtocolV2, 7})
+		assert.Nil(t, err)
+		assert.Equal(t, ProtocolV2, version)
+	})
+	t.Run("fail when there is no common version", func(t *testing.T) {
+		// then
+		_, err := Negotiate([]int{7})
+		assert.EqualError(t, err, "none of the protocol versions [7] is supported")
+	})
+}
+
+func TestRequest_Encoding(t *testing.T) {
+	t.Run("decode version 1 request", func(t *testing.T) {
+		// then
+		req, version, err := DecodeRequest([]byte(`{"playerId":"id","action":"shoot","args":{"x":"1","y":"2"}}`))
+		assert.Nil(t, err)
+		assert.Equal(t, ProtocolV1, version)
+		assert.Equal(t, BuildRequest("id", pkg.Shoot, map[string]interface{}{"x": "1", "y": "2"}), req)
+	})
+	t.Run("decode version 2 request", func(t *testing.T) {
+		// then
+		req, version, err := DecodeRequest([]byte(`{"v":2,"type":"shoot","playerId":"id","payload":{"x":1,"y":2}}`))
+		assert.Nil(t, err)
+		assert.Equal(t, ProtocolV2, version)
+		assert.Equal(t, BuildRequest("id", pkg.Shoot, map[string]interface{}{"x": float64(1), "y": float64(2)}), req)
+	})
+	t.Run("pass only the fields of the payload type", func(t *testing.T) {
+		// then
+		req, _, err := DecodeRequest([]byte(`{"v":2,"type":"shoot","playerId":"id","payload":{"x":1,"y":2,"z":3}}`))
+		assert.Nil(t, err)
+		assert.Equal(t, BuildRequest("id", pkg.Shoot, map[string]interface{}{"x": float64(1), "y": float64(2)}), req)
+	})
+	t.Run("fail when payload does not match the type", func(t *testing.T) {
+		// then
+		_, version, err := DecodeRequest([]byte(`{"v":2,"type":"shoot","playerId":"id","payload":{"x":"1","y":2}}`))
+		assert.Equal(t, ProtocolV2, version)
+		assert.Contains(t, err.Error(), "invalid payload for shoot")
+	})
+	t.Run("encode and decode version 2 request", func(t *testing.T) {
+		// when
+		req := BuildRequest("id", pkg.CreateRoom, map[string]interface{}{"name": "captain", "rules": "compact"})
+
+		// then
+		data, err := EncodeRequest(req, ProtocolV2)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"v":2,"type":"create-room","playerId":"id","payload":{"name":"captain","rules":"compact"}}`, string(data))
+
+		decoded, _, err := DecodeRequest(data)
+		assert.Nil(t, err)
+		assert.Equal(t, req, decoded)
+	})
+	t.Run("keep request id and ack in version 2 request", func(t *testing.T) {
+		// when
+		req := BuildRequest("id", pkg.Chat, map[string]interface{}{"text": "hello"})
+		req.RequestId = "42"
+		req.Ack = true
+
+		// then
+		data, err := EncodeRequest(req, ProtocolV2)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"v":2,"type":"chat","playerId":"id","requestId":"42","ack":true,"payload":{"text":"hello"}}`, string(data))
+
+		decoded, _, err := DecodeRequest(data)
+		assert.Nil(t, err)
+		assert.Equal(t, req, decoded)
+	})
+}
+
+func TestResponse_Encoding(t *testing.T) {
+	t.Run("encode version 2 response", func(t *testing.T) {
+		// when
+		resp := BuildResponse(pkg.ShootOutcome, "", map[string]interface{}{"x": 1, "y": 2, "hit": true, "sunk": false})
+
+		// then
+		data, err := EncodeResponse(resp, ProtocolV2)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"v":2,"type":"shoot-outcome","payload":{"x":1,"y":2,"hit":true,"sunk":false}}`, string(data))
+
+		decoded, version, err := DecodeResponse(data)
+		assert.Nil(t, err)
+		assert.Equal(t, ProtocolV2, version)
+
+		var shot ShotPayload
+		assert.Nil(t, DecodePayload(decoded.Args, &shot))
+		assert.Equal(t, ShotPayload{X: 1, Y: 2, Hit: true}, shot)
+	})
+	t.Run("keep turn in version 2 event", func(t *testing.T) {
+		// when
+		resp := BuildResponse(pkg.Event, "first shot at A0.", map[string]interface{}{
+			"shooter": "first", "target": "second", "x": 0, "y": 0, "hit": false, "sunk": false, "turn": "second"})
+
+		// then
+		data, err := EncodeResponse(resp, ProtocolV2)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"v":2,"type":"event","message":"first shot at A0.","payload":{"shooter":"first","target":"second","x":0,"y":0,"hit":false,"sunk":false,"turn":"second"}}`, string(data))
+	})
+	t.Run("encode version 1 response", func(t *testing.T) {
+		// when
+		resp := BuildResponse(pkg.Retry, "message", nil)
+
+		// then
+		data, err := EncodeResponse(resp, ProtocolV1)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"action":"retry","message":"message","args":null}`, string(data))
+	})
+	t.Run("keep request id in version 2 response", func(t *testing.T) {
+		// when
+		resp := BuildResponse(pkg.Ack, "", nil)
+		resp.RequestId = "42"
+
+		// then
+		data, err := EncodeResponse(resp, ProtocolV2)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"v":2,"type":"ack","requestId":"42"}`, string(data))
+
+		decoded, _, err := DecodeResponse(data)
+		assert.Nil(t, err)
+		assert.Equal(t, resp, decoded)
+	})
+	t.Run("fail when args do not match the type", func(t *testing.T) {
+		// then
+		_, err := EncodeResponse(BuildResponse(pkg.Register, "", map[string]interface{}{"id": 1}), ProtocolV2)
+		assert.Contains(t, err.Error(), "invalid payload for register")
+	})
+}
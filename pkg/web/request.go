@@ -1,9 +1,14 @@
 package web
 
+//Request is a message sent by the client. RequestId is chosen by the client and is echoed in every
+//response sent directly back to him while the request is processed. If Ack is set and the request
+//doesn't produce any such response, the server acknowledges it with Response with status Ack.
 type Request struct {
-	PlayerId string                 `json:"playerId"`
-	Action   string                 `json:"action"`
-	Args     map[string]interface{} `json:"args"`
+	PlayerId  string                 `json:"playerId"`
+	Action    string                 `json:"action"`
+	Args      map[string]interface{} `json:"args"`
+	RequestId string                 `json:"requestId,omitempty"`
+	Ack       bool                   `json:"ack,omitempty"`
 }
 
 func BuildRequest(id string, action string, args map[string]interface{}) Request {
@@ -26,10 +31,17 @@ func (r *Request) GetArgs() map[string]interface{} {
 	return r.Args
 }
 
+//GetRequestId returns the id chosen by the client for the request or empty string if there is none.
+func (r *Request) GetRequestId() string {
+	return r.RequestId
+}
+
 type Response struct {
-	Action  string                 `json:"action"`
-	Message string                 `json:"message"`
-	Args    map[string]interface{} `json:"args"`
+	Action    string                 `json:"action"`
+	Message   string                 `json:"message"`
+	Args      map[string]interface{} `json:"args"`
+	Code      string                 `json:"code,omitempty"`
+	RequestId string                 `json:"requestId,omitempty"`
 }
 
 func BuildResponse(action string, message string, args map[string]interface{}) Response {
@@ -51,3 +63,15 @@ func (r *Response) GetMessage() string {
 func (r *Response) GetArgs() map[string]interface{} {
 	return r.Args
 }
+
+//GetCode returns the code of the error reported by the response or empty string if it doesn't
+//report an error.
+func (r *Response) GetCode() string {
+	return r.Code
+}
+
+//GetRequestId returns the id of the request which the response answers or empty string if the
+//response is not a direct answer to a request.
+func (r *Response) GetRequestId() string {
+	return r.RequestId
+}
@@ -0,0 +1,43 @@
+package web
+
+import "time"
+
+//RoomInfo is the public description of a room which is sent to the players in the lobby.
+//Fleet maps ship length to the count of ships with that length each player has to place.
+type RoomInfo struct {
+	Id         string      `json:"id"`
+	Creator    string      `json:"creator"`
+	Players    int         `json:"players"`
+	Spectators int         `json:"spectators"`
+	Rules      string      `json:"rules"`
+	BoardSize  int         `json:"boardSize"`
+	Fleet      map[int]int `json:"fleet"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	Phase      string      `json:"phase"`
+}
+
+//GameRecord describes a finished game. The id of the game is made of the id of the room and the number
+//of the game in that room, as the players may play several games in the same room. Forfeit is true if
+//the game has ended because one of the players has left the room. The fields of both players are
+//revealed, as they are to the spectators when the game ends.
+type GameRecord struct {
+	Id           string    `json:"id"`
+	RoomId       string    `json:"roomId"`
+	Rules        string    `json:"rules"`
+	Winner       string    `json:"winner"`
+	Loser        string    `json:"loser"`
+	Forfeit      bool      `json:"forfeit"`
+	WinnerFields []string  `json:"winnerFields"`
+	LoserFields  []string  `json:"loserFields"`
+	StartedAt    time.Time `json:"startedAt"`
+	FinishedAt   time.Time `json:"finishedAt"`
+}
+
+//PlayerStats counts the finished games of a player since he has connected to the server. Name is the
+//name the player has used in his last game.
+type PlayerStats struct {
+	Name   string `json:"name"`
+	Played int    `json:"played"`
+	Wins   int    `json:"wins"`
+	Losses int    `json:"losses"`
+}
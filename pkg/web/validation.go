@@ -0,0 +1,199 @@
+package web
+
+import (
+	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"math"
+	"strconv"
+)
+
+//ArgKind describes which values are accepted for an argument of a request.
+type ArgKind int
+
+const (
+	//StringArg accepts strings.
+	StringArg ArgKind = iota
+	//IntArg accepts integral numbers and strings containing integers, as sent by ProtocolV1 clients.
+	IntArg
+	//BoolArg accepts booleans and strings containing booleans.
+	BoolArg
+	//IntListArg accepts lists of integral numbers.
+	IntListArg
+	//StringListArg accepts lists of strings.
+	StringListArg
+)
+
+//ArgSchema describes a single argument of a request.
+type ArgSchema struct {
+	Name     string
+	Kind     ArgKind
+	Required bool
+}
+
+//requestSchemas maps the action of every request which can be sent by a client to the schema of its
+//args, in the order in which they are checked. Args which are not present in the schema are ignored.
+var requestSchemas = map[string][]ArgSchema{
+	Hello: {
+		{Name: "versions", Kind: IntListArg},
+		{Name: "version", Kind: IntArg},
+		{Name: "encodings", Kind: StringListArg},
+	},
+	pkg.Exit: {},
+	pkg.ListRooms: {
+		{Name: "open", Kind: BoolArg},
+		{Name: "rules", Kind: StringArg},
+		{Name: "page", Kind: IntArg},
+		{Name: "pageSize", Kind: IntArg},
+	},
+	pkg.CreateRoom: {
+		{Name: "name", Kind: StringArg},
+		{Name: "rules", Kind: StringArg},
+	},
+	pkg.JoinRoom: {
+		{Name: "roomId", Kind: StringArg, Required: true},
+		{Name: "name", Kind: StringArg},
+	},
+	pkg.JoinRandom: {},
+	pkg.Spectate: {
+		{Name: "roomId", Kind: StringArg, Required: true},
+		{Name: "full", Kind: BoolArg},
+	},
+	pkg.Chat: {
+		{Name: "text", Kind: StringArg, Required: true},
+	},
+	pkg.Mute: {
+		{Name: "name", Kind: StringArg, Required: true},
+	},
+	pkg.PlaceShip: {
+		{Name: "x", Kind: IntArg, Required: true},
+		{Name: "y", Kind: IntArg, Required: true},
+		{Name: "direction", Kind: StringArg, Required: true},
+	},
+	pkg.Shoot: {
+		{Name: "x", Kind: IntArg, Required: true},
+		{Name: "y", Kind: IntArg, Required: true},
+	},
+	pkg.Rematch: {
+		{Name: "accept", Kind: BoolArg},
+	},
+	pkg.State: {},
+}
+
+//ValidateRequest checks the request against the schema of its action. Error with code UnknownAction
+//is returned if the action can't be sent by clients, MissingArgument if a required argument is
+//missing and InvalidArgument if an argument has value of unexpected type. Only the first failure
+//in the order of the schema is reported.
+func ValidateRequest(req Request) *Error {
+	schema, ok := requestSchemas[req.Action]
+	if !ok {
+		return NewError(UnknownAction, fmt.Sprintf("unknown action %q", req.Action))
+	}
+
+	for _, arg := range schema {
+		v, ok := req.Args[arg.Name]
+		if !ok || v == nil {
+			if arg.Required {
+				return NewError(MissingArgument, fmt.Sprintf("missing value for %s", arg.Name))
+			}
+			continue
+		}
+		if !arg.Kind.accepts(v) {
+			return NewError(InvalidArgument, fmt.Sprintf("invalid value for %s", arg.Name))
+		}
+	}
+	return nil
+}
+
+//DecodeArgs decodes the args of the request into the typed payload of its action, e.g. *ShootPayload.
+//It is the adapter of the untyped args of ProtocolV1, in which every request reaches the handlers: the
+//request is checked by ValidateRequest, the numbers and booleans sent as strings are converted and
+//the args which are not present in the schema of the action are dropped.
+func DecodeArgs(req Request, v interface{}) error {
+	if err := ValidateRequest(req); err != nil {
+		return err
+	}
+
+	args := make(map[string]interface{}, len(req.Args))
+	for _, arg := range requestSchemas[req.Action] {
+		if value, ok := req.Args[arg.Name]; ok && value != nil {
+			args[arg.Name] = arg.Kind.convert(value)
+		}
+	}
+	if err := DecodePayload(args, v); err != nil {
+		return NewError(InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+func (k ArgKind) accepts(v interface{}) bool {
+	switch k {
+	case StringArg:
+		_, ok := v.(string)
+		return ok
+	case IntArg:
+		return isInt(v)
+	case BoolArg:
+		switch value := v.(type) {
+		case bool:
+			return true
+		case string:
+			_, err := strconv.ParseBool(value)
+			return err == nil
+		}
+		return false
+	case IntListArg:
+		list, ok := v.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range list {
+			if n, ok := item.(float64); !ok || !isInt(n) {
+				return false
+			}
+		}
+		return true
+	case StringListArg:
+		list, ok := v.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range list {
+			if _, ok := item.(string); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+//convert returns the value accepted by the kind with its own type, the strings sent by ProtocolV1
+//clients are parsed.
+func (k ArgKind) convert(v interface{}) interface{} {
+	value, ok := v.(string)
+	if !ok {
+		return v
+	}
+	switch k {
+	case IntArg:
+		n, _ := strconv.Atoi(value)
+		return n
+	case BoolArg:
+		b, _ := strconv.ParseBool(value)
+		return b
+	}
+	return v
+}
+
+func isInt(v interface{}) bool {
+	switch value := v.(type) {
+	case float64:
+		return value == math.Trunc(value) && math.Abs(value) <= math.MaxInt32
+	case int:
+		return true
+	case string:
+		_, err := strconv.Atoi(value)
+		return err == nil
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package web
+
+import (
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidateRequest(t *testing.T) {
+	// given
+	testCases := []struct {
+		Name    string
+		Request Request
+		Err     *Error
+	}{
+		{
+			Name:    "valid shoot with string coordinates",
+			Request: BuildRequest("id", pkg.Shoot, map[string]interface{}{"x": "1", "y": "2"}),
+		},
+		{
+			Name:    "valid shoot with numeric coordinates",
+			Request: BuildRequest("id", pkg.Shoot, map[string]interface{}{"x": float64(1), "y": float64(2)}),
+		},
+		{
+			Name:    "valid request without optional args",
+			Request: BuildRequest("id", pkg.ListRooms, nil),
+		},
+		{
+			Name:    "unknown action",
+			Request: BuildRequest("id", "dance", nil),
+			Err:     NewError(UnknownAction, `unknown action "dance"`),
+		},
+		{
+			Name:    "internal action",
+			Request: BuildRequest("id", pkg.Disconnect, nil),
+			Err:     NewError(UnknownAction, `unknown action "disconnect"`),
+		},
+		{
+			Name:    "missing required arg",
+			Request: BuildRequest("id", pkg.PlaceShip, map[string]interface{}{"x": 1, "y": 2}),
+			Err:     NewError(MissingArgument, "missing value for direction"),
+		},
+		{
+			Name:    "first failure in the order of the schema",
+			Request: BuildRequest("id", pkg.PlaceShip, map[string]interface{}{"x": 1, "y": "B"}),
+			Err:     NewError(InvalidArgument, "invalid value for y"),
+		},
+		{
+			Name:    "fractional number",
+			Request: BuildRequest("id", pkg.Shoot, map[string]interface{}{"x": 1.5, "y": 2}),
+			Err:     NewError(InvalidArgument, "invalid value for x"),
+		},
+		{
+			Name:    "wrong type",
+			Request: BuildRequest("id", pkg.JoinRoom, map[string]interface{}{"roomId": true}),
+			Err:     NewError(InvalidArgument, "invalid value for roomId"),
+		},
+		{
+			Name:    "invalid boolean string",
+			Request: BuildRequest("id", pkg.Spectate, map[string]interface{}{"roomId": "room", "full": "maybe"}),
+			Err:     NewError(InvalidArgument, "invalid value for full"),
+		},
+		{
+			Name:    "invalid list",
+			Request: BuildRequest("id", Hello, map[string]interface{}{"versions": []interface{}{"2"}}),
+			Err:     NewError(InvalidArgument, "invalid value for versions"),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			// then
+			assert.Equal(t, testCase.Err, ValidateRequest(testCase.Request))
+		})
+	}
+}
+
+func TestDecodeArgs(t *testing.T) {
+	t.Run("decode args sent as strings", func(t *testing.T) {
+		// when
+		var payload ShootPayload
+		err := DecodeArgs(BuildRequest("id", pkg.Shoot, map[string]interface{}{"x": "1", "y": float64(2)}), &payload)
+
+		// then
+		assert.NoError(t, err)
+		assert.Equal(t, ShootPayload{X: 1, Y: 2}, payload)
+	})
+	t.Run("decode optional boolean", func(t *testing.T) {
+		// when
+		var payload RematchPayload
+		err := DecodeArgs(BuildRequest("id", pkg.Rematch, map[string]interface{}{"accept": "false"}), &payload)
+
+		// then
+		assert.NoError(t, err)
+		assert.NotNil(t, payload.Accept)
+		assert.False(t, *payload.Accept)
+	})
+	t.Run("drop args which are not in the schema", func(t *testing.T) {
+		// when
+		var payload ListRoomsPayload
+		err := DecodeArgs(BuildRequest("id", pkg.ListRooms, map[string]interface{}{"page": "2", "color": "red"}), &payload)
+
+		// then
+		assert.NoError(t, err)
+		assert.Equal(t, ListRoomsPayload{Page: 2}, payload)
+	})
+	t.Run("fail when the request doesn't match the schema", func(t *testing.T) {
+		// when
+		var payload PlacePayload
+		err := DecodeArgs(BuildRequest("id", pkg.PlaceShip, map[string]interface{}{"x": 1, "y": 2}), &payload)
+
+		// then
+		assert.Equal(t, NewError(MissingArgument, "missing value for direction"), err)
+	})
+}
+
+func FuzzDecodeRequest(f *testing.F) {
+	f.Add([]byte(`{"playerId":"id","action":"shoot","args":{"x":"1","y":"2"}}`))
+	f.Add([]byte(`{"v":2,"type":"place","playerId":"id","payload":{"x":1,"y":2,"direction":"up"}}`))
+	f.Add([]byte(`{"v":2,"type":"hello","payload":{"versions":[1,2]}}`))
+	f.Add([]byte(`{"action":"ls-rooms","args":{"page":1e300,"open":"yes"}}`))
+	f.Add([]byte(`{"v":3}`))
+	f.Add([]byte(`[]`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		req, version, err := DecodeRequest(data)
+		if err != nil {
+			return
+		}
+		if ValidateRequest(req) != nil {
+			return
+		}
+		if err = DecodeArgs(req, requestPayloads[req.Action]()); err != nil {
+			t.Errorf("valid request can't be decoded: %s", err)
+		}
+		if _, err = EncodeRequest(req, version); err != nil && version == ProtocolV1 {
+			t.Errorf("valid request can't be encoded: %s", err)
+		}
+	})
+}
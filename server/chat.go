@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/StanislavStefanov/Battleships/server/player"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxChatLength = 200
+	chatLimit     = 5
+	chatWindow    = 10 * time.Second
+)
+
+const (
+	lobbyChannel      = "lobby"
+	roomChannel       = "room"
+	spectatorsChannel = "spectators"
+)
+
+//LobbyChat sends the chat message of the player to every other player in the lobby, who hasn't
+//muted him. If the message is invalid or the player sends messages too often Response with status
+//Retry is sent back to him through the sender.
+func (s *Server) LobbyChat(from *player.Player, request web.Request, sender ResponseSender) {
+	text, err := getChatMessage(from, request)
+	if err != nil {
+		resp := buildErrorResponse(err)
+		sender.SendResponse(resp, from.Conn)
+		return
+	}
+
+	s.mu.RLock()
+	var recipients []*player.Player
+	for _, p := range s.clients {
+		if p.Id != from.Id {
+			recipients = append(recipients, p)
+		}
+	}
+	s.mu.RUnlock()
+
+	deliverChat(sender, from, text, lobbyChannel, recipients)
+}
+
+//processChat forwards the chat message of a player in the room to his opponent and to the spectators.
+//The chat is available during the whole game regardless of whose turn it is.
+func (r *Room) processChat(request web.Request) {
+	sender, opponent := r.getPlayers(request.GetId())
+	if sender == nil {
+		return
+	}
+
+	text, err := getChatMessage(sender, request)
+	if err != nil {
+		resp := buildErrorResponse(err)
+		r.Sender.SendResponse(resp, sender.Conn)
+		return
+	}
+
+	recipients := r.getSpectatorPlayers()
+	if opponent != nil {
+		recipients = append(recipients, opponent)
+	}
+	deliverChat(r.Sender, sender, text, roomChannel, recipients)
+}
+
+//processSpectatorChat forwards the chat message of a spectator to the other spectators. The players
+//don't receive messages from the spectators, so they can't be helped during the game.
+func (r *Room) processSpectatorChat(spectator *Spectator, request web.Request) {
+	text, err := getChatMessage(spectator.Player, request)
+	if err != nil {
+		resp := buildErrorResponse(err)
+		r.Sender.SendResponse(resp, spectator.Conn)
+		return
+	}
+
+	var recipients []*player.Player
+	for _, p := range r.getSpectatorPlayers() {
+		if p.Id != spectator.Id {
+			recipients = append(recipients, p)
+		}
+	}
+	deliverChat(r.Sender, spectator.Player, text, spectatorsChannel, recipients)
+}
+
+//getPlayers returns the player in the room with the provided id and his opponent. If there is no
+//such player nil, nil is returned.
+func (r *Room) getPlayers(id string) (*player.Player, *player.Player) {
+	if r.Current != nil && r.Current.Id == id {
+		return r.Current, r.Next
+	}
+	if r.Next != nil && r.Next.Id == id {
+		return r.Next, r.Current
+	}
+	return nil, nil
+}
+
+//getSpectatorPlayers returns the spectators who take part in the chat of the room, which are all of
+//them except those watching only the events of the room.
+func (r *Room) getSpectatorPlayers() []*player.Player {
+	var players []*player.Player
+	for _, spectator := range r.Spectators {
+		if !spectator.EventsOnly {
+			players = append(players, spectator.Player)
+		}
+	}
+	return players
+}
+
+//processMute toggles muting of the players with the name provided in the payload of the request
+//for the player who sent the request. The player is notified with Response with status Info.
+func processMute(p *player.Player, request web.Request, sender ResponseSender) {
+	var payload web.MutePayload
+	if err := web.DecodeArgs(request, &payload); err != nil {
+		sender.SendResponse(buildErrorResponse(err), p.Conn)
+		return
+	}
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		resp := buildErrorResponse(invalidValue("name"))
+		sender.SendResponse(resp, p.Conn)
+		return
+	}
+
+	message := fmt.Sprintf("Messages from %s are unmuted.", name)
+	muted := p.ToggleMute(name)
+	if muted {
+		message = fmt.Sprintf("Messages from %s are muted.", name)
+	}
+	resp := web.BuildResponse(pkg.Info, message, map[string]interface{}{"name": name, "muted": muted})
+	sender.SendResponse(resp, p.Conn)
+}
+
+//getChatMessage returns the trimmed text of the chat message decoded from the payload of the request.
+//An error is returned if the text is missing, empty or longer than maxChatLength characters, or if the
+//player has exceeded the count of messages he is allowed to send.
+func getChatMessage(p *player.Player, request web.Request) (string, error) {
+	var payload web.ChatPayload
+	if err := web.DecodeArgs(request, &payload); err != nil {
+		return "", err
+	}
+	text := strings.TrimSpace(payload.Text)
+	if text == "" {
+		return "", web.NewError(web.EmptyMessage, "message is empty")
+	}
+	if utf8.RuneCountInString(text) > maxChatLength {
+		return "", web.NewError(web.MessageTooLong, fmt.Sprintf("message is longer than %d characters", maxChatLength))
+	}
+	if p.ChatLimiter != nil && !p.ChatLimiter.Allow(time.Now()) {
+		return "", web.NewError(web.RateLimited, fmt.Sprintf("you can send at most %d messages per %s", chatLimit, chatWindow))
+	}
+	return text, nil
+}
+
+func deliverChat(sender ResponseSender, from *player.Player, text string, channel string, recipients []*player.Player) {
+	resp := web.BuildResponse(pkg.Chat, text, map[string]interface{}{"from": from.GetName(), "channel": channel})
+	for _, p := range recipients {
+		if !p.IsMuted(from.GetName()) {
+			sender.SendResponse(resp, p.Conn)
+		}
+	}
+}
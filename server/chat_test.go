@@ -0,0 +1,261 @@
+package main
+
+import (
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/StanislavStefanov/Battleships/server/automock"
+	"github.com/StanislavStefanov/Battleships/server/player"
+	connection "github.com/StanislavStefanov/Battleships/server/player/automock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+func countSent(sender *automock.ResponseSender, conn player.Connection) int {
+	count := 0
+	for _, call := range sender.Calls {
+		if call.Arguments.Get(1) == conn {
+			count++
+		}
+	}
+	return count
+}
+
+func TestServer_LobbyChat(t *testing.T) {
+	t.Run("deliver message to the other players in the lobby", func(t *testing.T) {
+		// when
+		senderConn := &connection.Connection{}
+		otherConn := &connection.Connection{}
+		mutingConn := &connection.Connection{}
+
+		sender := &player.Player{Id: "sender", Name: "captain", Conn: senderConn}
+		other := &player.Player{Id: "other", Conn: otherConn}
+		muting := &player.Player{Id: "muting", Conn: mutingConn}
+		muting.ToggleMute("captain")
+
+		chat := web.BuildResponse(pkg.Chat, "hello", map[string]interface{}{"from": "captain", "channel": lobbyChannel})
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", chat, mock.Anything).Return(nil).Once()
+
+		s := &Server{
+			clients: map[string]*player.Player{"sender": sender, "other": other, "muting": muting},
+			sender:  responseSender,
+		}
+
+		// then
+		s.LobbyChat(sender, web.BuildRequest("sender", pkg.Chat, map[string]interface{}{"text": " hello "}), s.sender)
+		responseSender.AssertExpectations(t)
+		assert.Equal(t, 1, countSent(responseSender, otherConn))
+	})
+	t.Run("fail when message is too long", func(t *testing.T) {
+		// when
+		senderConn := &connection.Connection{}
+		sender := &player.Player{Id: "sender", Conn: senderConn}
+
+		resp := web.BuildErrorResponse(web.NewError(web.MessageTooLong, "message is longer than 200 characters"))
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", resp, senderConn).Return(nil).Once()
+
+		s := &Server{
+			clients: map[string]*player.Player{"sender": sender},
+			sender:  responseSender,
+		}
+
+		// then
+		s.LobbyChat(sender, web.BuildRequest("sender", pkg.Chat, map[string]interface{}{"text": strings.Repeat("a", maxChatLength+1)}), s.sender)
+		responseSender.AssertExpectations(t)
+	})
+	t.Run("fail when player sends messages too often", func(t *testing.T) {
+		// when
+		senderConn := &connection.Connection{}
+		limiter := player.NewRateLimiter(chatLimit, chatWindow)
+		for i := 0; i < chatLimit; i++ {
+			limiter.Allow(time.Now())
+		}
+		sender := &player.Player{Id: "sender", Conn: senderConn, ChatLimiter: limiter}
+
+		resp := web.BuildErrorResponse(web.NewError(web.RateLimited, "you can send at most 5 messages per 10s"))
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", resp, senderConn).Return(nil).Once()
+
+		s := &Server{
+			clients: map[string]*player.Player{"sender": sender},
+			sender:  responseSender,
+		}
+
+		// then
+		s.LobbyChat(sender, web.BuildRequest("sender", pkg.Chat, map[string]interface{}{"text": "hello"}), s.sender)
+		responseSender.AssertExpectations(t)
+	})
+}
+
+func TestRoom_Chat(t *testing.T) {
+	t.Run("forward message of the player waiting for his turn to opponent and spectators", func(t *testing.T) {
+		// when
+		currentConn := &connection.Connection{}
+		nextConn := &connection.Connection{}
+		spectatorConn := &connection.Connection{}
+
+		chat := web.BuildResponse(pkg.Chat, "good luck", map[string]interface{}{"from": "second", "channel": roomChannel})
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", chat, mock.Anything).Return(nil).Twice()
+
+		room := &Room{
+			Current:    &player.Player{Id: "first", Conn: currentConn},
+			Next:       &player.Player{Id: "second", Name: "second", Conn: nextConn},
+			Spectators: map[string]*Spectator{"spectator": {Player: &player.Player{Id: "spectator", Conn: spectatorConn}}},
+			Phase:      pkg.Shoot,
+			Sender:     responseSender,
+		}
+
+		// then
+		room.ProcessCommand(web.BuildRequest("second", pkg.Chat, map[string]interface{}{"text": "good luck"}))
+		responseSender.AssertExpectations(t)
+		assert.Equal(t, 1, countSent(responseSender, currentConn))
+		assert.Equal(t, 1, countSent(responseSender, spectatorConn))
+		assert.Equal(t, "first", room.Current.Id)
+	})
+	t.Run("acknowledge message of the player who asks for it", func(t *testing.T) {
+		// when
+		currentConn := &connection.Connection{}
+		nextConn := &connection.Connection{}
+
+		chat := web.BuildResponse(pkg.Chat, "good luck", map[string]interface{}{"from": "first", "channel": roomChannel})
+		ack := web.BuildResponse(pkg.Ack, "", nil)
+		ack.RequestId = "7"
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", chat, nextConn).Return(nil).Once()
+		responseSender.On("SendResponse", ack, currentConn).Return(nil).Once()
+
+		room := &Room{
+			Current: &player.Player{Id: "first", Name: "first", Conn: currentConn},
+			Next:    &player.Player{Id: "second", Conn: nextConn},
+			Phase:   pkg.Shoot,
+			Sender:  responseSender,
+		}
+		request := web.BuildRequest("first", pkg.Chat, map[string]interface{}{"text": "good luck"})
+		request.RequestId = "7"
+		request.Ack = true
+
+		// then
+		room.ProcessCommand(request)
+		responseSender.AssertExpectations(t)
+		assert.Equal(t, 1, countSent(responseSender, nextConn))
+		assert.Equal(t, 1, countSent(responseSender, currentConn))
+		assert.Equal(t, responseSender, room.Sender)
+	})
+	t.Run("forward message of spectator only to the other spectators", func(t *testing.T) {
+		// when
+		spectatorConn := &connection.Connection{}
+		otherConn := &connection.Connection{}
+
+		chat := web.BuildResponse(pkg.Chat, "nice shot", map[string]interface{}{"from": "anonymous", "channel": spectatorsChannel})
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", chat, mock.Anything).Return(nil).Once()
+
+		room := &Room{
+			Current: &player.Player{Id: "first", Conn: firstConn},
+			Next:    &player.Player{Id: "second", Conn: secondConn},
+			Spectators: map[string]*Spectator{
+				"spectator": {Player: &player.Player{Id: "spectator", Conn: spectatorConn}},
+				"other":     {Player: &player.Player{Id: "other", Conn: otherConn}},
+			},
+			Sender: responseSender,
+		}
+
+		// then
+		room.ProcessSpectatorCommand(web.BuildRequest("spectator", pkg.Chat, map[string]interface{}{"text": "nice shot"}))
+		responseSender.AssertExpectations(t)
+		assert.Equal(t, 1, countSent(responseSender, otherConn))
+	})
+	t.Run("don't forward messages to spectators watching only the events", func(t *testing.T) {
+		// when
+		currentConn := &connection.Connection{}
+		spectatorConn := &connection.Connection{}
+		streamConn := &connection.Connection{}
+
+		chat := web.BuildResponse(pkg.Chat, "good luck", map[string]interface{}{"from": "second", "channel": roomChannel})
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", chat, mock.Anything).Return(nil).Twice()
+
+		room := &Room{
+			Current: &player.Player{Id: "first", Conn: currentConn},
+			Next:    &player.Player{Id: "second", Name: "second", Conn: secondConn},
+			Spectators: map[string]*Spectator{
+				"spectator": {Player: &player.Player{Id: "spectator", Conn: spectatorConn}},
+				"stream":    {Player: &player.Player{Id: "stream", Conn: streamConn}, EventsOnly: true},
+			},
+			Phase:  pkg.Shoot,
+			Sender: responseSender,
+		}
+
+		// then
+		room.ProcessCommand(web.BuildRequest("second", pkg.Chat, map[string]interface{}{"text": "good luck"}))
+		responseSender.AssertExpectations(t)
+		assert.Equal(t, 1, countSent(responseSender, spectatorConn))
+		assert.Equal(t, 0, countSent(responseSender, streamConn))
+	})
+}
+
+func TestRoom_Mute(t *testing.T) {
+	t.Run("mute and unmute opponent", func(t *testing.T) {
+		// when
+		muted := web.BuildResponse(pkg.Info, "Messages from second are muted.", map[string]interface{}{"name": "second", "muted": true})
+		unmuted := web.BuildResponse(pkg.Info, "Messages from second are unmuted.", map[string]interface{}{"name": "second", "muted": false})
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", muted, firstConn).Return(nil).Once()
+		responseSender.On("SendResponse", unmuted, firstConn).Return(nil).Once()
+
+		first := &player.Player{Id: "first", Conn: firstConn}
+		room := &Room{
+			Current: first,
+			Next:    &player.Player{Id: "second", Name: "second", Conn: secondConn},
+			Sender:  responseSender,
+		}
+
+		// then
+		room.ProcessCommand(web.BuildRequest("first", pkg.Mute, map[string]interface{}{"name": "second"}))
+		assert.True(t, first.IsMuted("second"))
+		room.ProcessCommand(web.BuildRequest("first", pkg.Mute, map[string]interface{}{"name": "second"}))
+		assert.False(t, first.IsMuted("second"))
+		responseSender.AssertExpectations(t)
+	})
+	t.Run("mute opponent regardless of the case of his name", func(t *testing.T) {
+		// when
+		muted := web.BuildResponse(pkg.Info, "Messages from SECOND are muted.", map[string]interface{}{"name": "SECOND", "muted": true})
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", muted, firstConn).Return(nil).Once()
+
+		first := &player.Player{Id: "first", Conn: firstConn}
+		room := &Room{
+			Current:    first,
+			Next:       &player.Player{Id: "second", Name: "Second", Conn: secondConn},
+			Spectators: map[string]*Spectator{},
+			Sender:     responseSender,
+		}
+		room.ProcessCommand(web.BuildRequest("first", pkg.Mute, map[string]interface{}{"name": "SECOND"}))
+		room.ProcessCommand(web.BuildRequest("second", pkg.Chat, map[string]interface{}{"text": "hello"}))
+
+		// then
+		assert.True(t, first.IsMuted("Second"))
+		responseSender.AssertExpectations(t)
+	})
+	t.Run("fail when name is missing", func(t *testing.T) {
+		// when
+		resp := web.BuildErrorResponse(web.NewError(web.MissingArgument, "missing value for name"))
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", resp, firstConn).Return(nil).Once()
+
+		room := &Room{
+			Current: &player.Player{Id: "first", Conn: firstConn},
+			Next:    &player.Player{Id: "second", Conn: secondConn},
+			Sender:  responseSender,
+		}
+
+		// then
+		room.ProcessCommand(web.BuildRequest("first", pkg.Mute, nil))
+		responseSender.AssertExpectations(t)
+	})
+}
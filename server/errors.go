@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+)
+
+//buildErrorResponse returns Response with status Retry describing the error. The code of the
+//response is taken from the error if it is web.Error, errors returned by the board are mapped
+//to their codes and any other error is reported as internal error.
+func buildErrorResponse(err error) web.Response {
+	var webErr *web.Error
+	if errors.As(err, &webErr) {
+		return web.BuildErrorResponse(webErr)
+	}
+	return web.BuildErrorResponse(web.NewError(getErrorCode(err), err.Error()))
+}
+
+func getErrorCode(err error) string {
+	switch {
+	case errors.Is(err, game.ErrShipOutOfBounds), errors.Is(err, game.ErrPositionOutOfBounds):
+		return web.OutOfBounds
+	case errors.Is(err, game.ErrFieldsTaken):
+		return web.Overlap
+	case errors.Is(err, game.ErrAlreadyShot):
+		return web.AlreadyShot
+	case errors.Is(err, game.ErrUnknownDirection):
+		return web.InvalidDirection
+	case errors.Is(err, game.ErrUnknownRules):
+		return web.UnknownRules
+	default:
+		return web.InternalError
+	}
+}
+
+//invalidValue returns error with code InvalidArgument for the arg with the provided key.
+func invalidValue(key string) error {
+	return web.NewError(web.InvalidArgument, fmt.Sprintf("invalid value for %s", key))
+}
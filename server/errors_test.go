@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBuildErrorResponse(t *testing.T) {
+	// given
+	_, rulesErr := game.GetRules("unknown")
+	testCases := []struct {
+		Name    string
+		Err     error
+		Code    string
+		Message string
+	}{
+		{
+			Name:    "code of web error is kept",
+			Err:     web.NewError(web.RoomFull, "room is already full"),
+			Code:    web.RoomFull,
+			Message: "room is already full",
+		},
+		{
+			Name:    "ship out of bounds",
+			Err:     game.ErrShipOutOfBounds,
+			Code:    web.OutOfBounds,
+			Message: "ship goes out of bounds",
+		},
+		{
+			Name:    "position out of bounds",
+			Err:     game.ErrPositionOutOfBounds,
+			Code:    web.OutOfBounds,
+			Message: "position out of bounds",
+		},
+		{
+			Name:    "overlapping ships",
+			Err:     game.ErrFieldsTaken,
+			Code:    web.Overlap,
+			Message: "some of the fields are already taken",
+		},
+		{
+			Name:    "unknown direction",
+			Err:     game.ErrUnknownDirection,
+			Code:    web.InvalidDirection,
+			Message: "unknown positioning direction",
+		},
+		{
+			Name:    "unknown rules",
+			Err:     rulesErr,
+			Code:    web.UnknownRules,
+			Message: "unknown rule set unknown",
+		},
+		{
+			Name:    "unexpected error",
+			Err:     errors.New("unexpected"),
+			Code:    web.InternalError,
+			Message: "unexpected",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			// when
+			resp := buildErrorResponse(testCase.Err)
+
+			// then
+			assert.Equal(t, pkg.Retry, resp.GetAction())
+			assert.Equal(t, testCase.Code, resp.GetCode())
+			assert.Equal(t, testCase.Message, resp.GetMessage())
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/StanislavStefanov/Battleships/server/player"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+//serveRoomEvents streams the public events of the room as Server-Sent Events. The client is attached to
+//the room as spectator without full view through EventConnection, so it receives the same feed of the
+//game as the websocket spectators, but not their chat - every response is sent as event named by its
+//action with the JSON of the response as data. The stream ends when the room is closed, when the client disconnects or when it
+//doesn't keep up with the events.
+func (s *Server) serveRoomEvents(w http.ResponseWriter, r *http.Request, room *Room) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeHTTPError(w, web.NewError(web.InternalError, "streaming is not supported"))
+		return
+	}
+
+	conn := player.NewEventConnection()
+	defer conn.Close()
+	spectator := &Spectator{Player: &player.Player{
+		Conn:  conn,
+		Board: game.InitBoard(),
+		Id:    uuid.New().String(),
+	}, EventsOnly: true}
+
+	select {
+	case room.Watch <- spectator:
+	case <-room.Closed:
+		writeHTTPError(w, web.NewError(web.RoomClosed, fmt.Sprintf("room %s is already closed", room.Id)))
+		return
+	case <-r.Context().Done():
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case data := <-conn.Events():
+			if !writeEvent(w, data) {
+				return
+			}
+			flusher.Flush()
+		case <-conn.Done():
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+//writeEvent writes the response as Server-Sent Event named by its action. Returns false if the response
+//ends the stream, which happens when the spectator is sent back to the lobby.
+func writeEvent(w http.ResponseWriter, data []byte) bool {
+	var resp web.Response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		fmt.Println("Write event: unmarshal error: ", err)
+		return true
+	}
+	if resp.GetAction() == pkg.Lobby {
+		return false
+	}
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", resp.GetAction(), data)
+	return err == nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type serverSentEvent struct {
+	name string
+	resp web.Response
+}
+
+//readEvent reads the next Server-Sent Event from the stream. Returns false if the stream has ended.
+func readEvent(t *testing.T, reader *bufio.Reader) (serverSentEvent, bool) {
+	var event serverSentEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return event, false
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return event, true
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			assert.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.resp))
+		}
+	}
+}
+
+func TestServer_ServeRoomEvents(t *testing.T) {
+	t.Run("stream public events of the room until it is closed", func(t *testing.T) {
+		// when
+		s := NewServer()
+		s.Start()
+		mux := http.NewServeMux()
+		s.HandleHTTP(mux)
+		httpServer := httptest.NewServer(mux)
+		defer httpServer.Close()
+
+		room, _ := s.OpenRoom(game.DefaultRules())
+		go s.RunRoom(room, s.getConnectRoom(room.Id))
+
+		resp, err := http.Get(httpServer.URL + "/rooms/" + room.Id + "/events")
+		assert.Nil(t, err)
+		defer resp.Body.Close()
+		reader := bufio.NewReader(resp.Body)
+
+		// then
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		event, ok := readEvent(t, reader)
+		assert.True(t, ok)
+		assert.Equal(t, pkg.Spectate, event.name)
+		assert.Equal(t, room.Id, event.resp.GetArgs()["id"])
+
+		first := connectClient(t, s)
+		second := connectClient(t, s)
+		first.send(pkg.JoinRoom, map[string]interface{}{"roomId": room.Id})
+		first.expect(pkg.Wait)
+		second.send(pkg.JoinRoom, map[string]interface{}{"roomId": room.Id})
+		second.expect(pkg.Wait)
+		first.send(pkg.Exit, nil)
+
+		event, ok = readEvent(t, reader)
+		assert.True(t, ok)
+		assert.Equal(t, pkg.GameOver, event.name)
+		assert.Equal(t, "anonymous-2 wins the game.", event.resp.GetMessage())
+
+		_, ok = readEvent(t, reader)
+		assert.False(t, ok)
+	})
+	t.Run("fail when the room doesn't exist", func(t *testing.T) {
+		// when
+		rec := serveHTTP(NewServer(), http.MethodGet, "/rooms/missing/events", "")
+
+		// then
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, web.RoomNotFound, decodeHTTPError(t, rec).Code)
+	})
+	t.Run("fail when the room is already closed", func(t *testing.T) {
+		// when
+		s := NewServer()
+		room, _ := s.OpenRoom(game.DefaultRules())
+		close(room.Closed)
+		rec := serveHTTP(s, http.MethodGet, "/rooms/"+room.Id+"/events", "")
+
+		// then
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, web.RoomClosed, decodeHTTPError(t, rec).Code)
+	})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/StanislavStefanov/Battleships/server/automock"
+	"github.com/StanislavStefanov/Battleships/server/player"
+	connection "github.com/StanislavStefanov/Battleships/server/player/automock"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func fuzzRoom(phase string, sender ResponseSender) *Room {
+	room := &Room{
+		Current:    &player.Player{Id: "first", Conn: firstConn, Board: getBoard()},
+		Next:       &player.Player{Id: "second", Conn: secondConn, Board: getBoardWithOneTakenField(0, 0)},
+		Spectators: map[string]*Spectator{"spectator": {Player: &player.Player{Id: "spectator", Conn: &connection.Connection{}}}},
+		Phase:      phase,
+		Done:       make(chan struct{}, 1),
+		Sender:     sender,
+		rematch:    map[string]bool{},
+	}
+	room.ApplyRules(game.DefaultRules())
+	return room
+}
+
+//FuzzRequest checks that no request sent by a client can crash the server. Every input passes through the
+//same validation as the requests read from the connections and the valid ones are processed by the lobby
+//argument parsers and by rooms in every phase.
+func FuzzRequest(f *testing.F) {
+	f.Add([]byte(`{"playerId":"first","action":"shoot","args":{"x":"1","y":"2"}}`))
+	f.Add([]byte(`{"playerId":"first","action":"shoot","args":{"x":3,"y":-7}}`))
+	f.Add([]byte(`{"playerId":"first","action":"place","args":{"x":5,"y":50,"direction":"up"}}`))
+	f.Add([]byte(`{"playerId":"second","action":"rematch","args":{"accept":"false"}}`))
+	f.Add([]byte(`{"playerId":"first","action":"chat","args":{"text":"hello"}}`))
+	f.Add([]byte(`{"playerId":"first","action":"ls-rooms","args":{"page":"0","pageSize":1e9}}`))
+	f.Add([]byte(`{"playerId":"spectator","action":"exit","args":null}`))
+	f.Add([]byte(`{"action":`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", mock.Anything, mock.Anything).Return(nil)
+
+		conn := &connection.Connection{}
+		conn.On("ReadMessage").Return(0, data, nil).Once()
+		conn.On("ReadMessage").Return(0, nil, errors.New("read failure"))
+
+		s := &Server{sender: responseSender}
+		req, err := s.readRequest(&player.Player{Id: "first", Conn: conn})
+		if err != nil {
+			return
+		}
+		processFuzzRequest(req, responseSender)
+	})
+}
+
+//FuzzProtocolRequest checks that no ProtocolV2 request can crash the server regardless of the encoding
+//negotiated by the client. The client negotiates the version and the encoding through hello request and
+//every input is then decoded by ProtocolConnection in that encoding and processed as in FuzzRequest.
+func FuzzProtocolRequest(f *testing.F) {
+	msgpack, err := web.GetCodec(web.Msgpack)
+	if err != nil {
+		f.Fatal(err)
+	}
+	seeds := []string{
+		`{"v":2,"type":"shoot","playerId":"first","payload":{"x":1,"y":2}}`,
+		`{"v":2,"type":"place","playerId":"first","payload":{"x":5,"y":50,"direction":"up"}}`,
+		`{"v":2,"type":"rematch","playerId":"second","payload":{"accept":false}}`,
+		`{"v":2,"type":"ls-rooms","playerId":"first","payload":{"page":-1,"pageSize":1e9}}`,
+		`{"v":2,"type":"shoot","playerId":"first","payload":{"x":"1","y":2}}`,
+		`{"v":2,"type":"hello","payload":{"versions":[1],"encodings":["json"]}}`,
+		`{"v":2,"type":"chat","payload":[]}`,
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed), false)
+		encoded, err := web.Transcode([]byte(seed), web.DefaultCodec(), msgpack)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(encoded, true)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte, useMsgpack bool) {
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", mock.Anything, mock.Anything).Return(nil)
+
+		encoding := web.JSON
+		if useMsgpack {
+			encoding = web.Msgpack
+		}
+		hello, _ := json.Marshal(web.Message{Version: web.ProtocolV2, Type: web.Hello,
+			Payload: json.RawMessage(`{"versions":[2],"encodings":["` + encoding + `"]}`)})
+
+		conn := &connection.Connection{}
+		conn.On("WriteMessage", mock.Anything, mock.Anything).Return(nil)
+		conn.On("ReadMessage").Return(websocket.BinaryMessage, hello, nil).Once()
+		conn.On("ReadMessage").Return(websocket.BinaryMessage, data, nil).Once()
+		conn.On("ReadMessage").Return(0, nil, errors.New("read failure"))
+
+		s := &Server{sender: responseSender}
+		req, err := s.readRequest(&player.Player{Id: "first", Conn: player.NewProtocolConnection(conn)})
+		if err != nil {
+			return
+		}
+		processFuzzRequest(req, responseSender)
+	})
+}
+
+//processFuzzRequest passes the valid request to the lobby argument parsers and to rooms in every phase.
+func processFuzzRequest(req web.Request, sender ResponseSender) {
+	var filter web.ListRoomsPayload
+	if web.DecodeArgs(req, &filter) == nil {
+		_, _ = getRoomFilter(filter)
+	}
+	var room web.CreateRoomPayload
+	if web.DecodeArgs(req, &room) == nil {
+		_, _ = getRules(room.Rules)
+		_, _ = getPlayerName(room.Name)
+	}
+
+	for _, phase := range []string{"wait", pkg.PlaceShip, pkg.Shoot, pkg.Rematch} {
+		fuzzRoom(phase, sender).ProcessCommand(req)
+		fuzzRoom(phase, sender).ProcessSpectatorCommand(req)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/StanislavStefanov/Battleships/server/player"
+	"sync"
+	"time"
+)
+
+//maxRecordedGames is the count of finished games kept by History. When it is exceeded the oldest game
+//is forgotten, but it is still counted in the stats of its players.
+const maxRecordedGames = 1000
+
+//History keeps the finished games and the stats of the players who have played them, so they can be
+//looked up after the rooms are closed. It is safe for concurrent use, as the rooms record their games
+//from their own goroutines.
+type History struct {
+	games map[string]web.GameRecord
+	order []string
+	stats map[string]web.PlayerStats
+	mu    sync.RWMutex
+}
+
+//NewHistory returns History without any games.
+func NewHistory() *History {
+	return &History{
+		games: make(map[string]web.GameRecord),
+		stats: make(map[string]web.PlayerStats),
+	}
+}
+
+//RecordGame stores the finished game and updates the stats of its winner and loser, who are identified
+//by the provided ids. The ids are not part of the record, as they are known only to the players.
+func (h *History) RecordGame(record web.GameRecord, winnerId, loserId string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.games[record.Id]; !ok {
+		h.order = append(h.order, record.Id)
+	}
+	h.games[record.Id] = record
+	if len(h.order) > maxRecordedGames {
+		delete(h.games, h.order[0])
+		h.order = h.order[1:]
+	}
+
+	winner := h.stats[winnerId]
+	winner.Name = record.Winner
+	winner.Played++
+	winner.Wins++
+	h.stats[winnerId] = winner
+
+	loser := h.stats[loserId]
+	loser.Name = record.Loser
+	loser.Played++
+	loser.Losses++
+	h.stats[loserId] = loser
+}
+
+//GetGame returns the finished game with the provided id. If there is no such game an error with code
+//GameNotFound is returned.
+func (h *History) GetGame(id string) (web.GameRecord, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	record, ok := h.games[id]
+	if !ok {
+		return web.GameRecord{}, web.NewError(web.GameNotFound, fmt.Sprintf("game with id %s doesnt exist", id))
+	}
+	return record, nil
+}
+
+//GetStats returns the stats of the player with the provided id. If the player hasn't finished any game
+//yet an error with code PlayerNotFound is returned.
+func (h *History) GetStats(playerId string) (web.PlayerStats, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats, ok := h.stats[playerId]
+	if !ok {
+		return web.PlayerStats{}, web.NewError(web.PlayerNotFound,
+			fmt.Sprintf("player with id %s hasn't finished any game", playerId))
+	}
+	return stats, nil
+}
+
+//recordGame records the game which has just ended in the history of the room, if the room has one.
+func (r *Room) recordGame(winner, loser *player.Player) {
+	if r.History == nil {
+		return
+	}
+	r.games++
+	r.History.RecordGame(web.GameRecord{
+		Id:           fmt.Sprintf("%s-%d", r.Id, r.games),
+		RoomId:       r.Id,
+		Rules:        r.Rules.Name,
+		Winner:       winner.GetName(),
+		Loser:        loser.GetName(),
+		Forfeit:      r.leftId != "",
+		WinnerFields: getFields(winner),
+		LoserFields:  getFields(loser),
+		StartedAt:    r.startedAt,
+		FinishedAt:   time.Now(),
+	}, winner.Id, loser.Id)
+}
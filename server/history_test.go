@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHistory_RecordGame(t *testing.T) {
+	t.Run("store game and count it in the stats of both players", func(t *testing.T) {
+		// when
+		h := NewHistory()
+		h.RecordGame(web.GameRecord{Id: "room-1", Winner: "first", Loser: "second"}, "id1", "id2")
+		h.RecordGame(web.GameRecord{Id: "room-2", Winner: "second", Loser: "first"}, "id2", "id1")
+
+		record, err := h.GetGame("room-1")
+		firstStats, firstErr := h.GetStats("id1")
+		secondStats, secondErr := h.GetStats("id2")
+
+		// then
+		assert.Nil(t, err)
+		assert.Nil(t, firstErr)
+		assert.Nil(t, secondErr)
+		assert.Equal(t, "first", record.Winner)
+		assert.Equal(t, web.PlayerStats{Name: "first", Played: 2, Wins: 1, Losses: 1}, firstStats)
+		assert.Equal(t, web.PlayerStats{Name: "second", Played: 2, Wins: 1, Losses: 1}, secondStats)
+	})
+	t.Run("forget the oldest game when the limit is exceeded", func(t *testing.T) {
+		// when
+		h := NewHistory()
+		for i := 0; i <= maxRecordedGames; i++ {
+			h.RecordGame(web.GameRecord{Id: fmt.Sprintf("room-%d", i)}, "id1", "id2")
+		}
+
+		_, oldestErr := h.GetGame("room-0")
+		_, newestErr := h.GetGame(fmt.Sprintf("room-%d", maxRecordedGames))
+		stats, _ := h.GetStats("id1")
+
+		// then
+		assert.Equal(t, web.NewError(web.GameNotFound, "game with id room-0 doesnt exist"), oldestErr)
+		assert.Nil(t, newestErr)
+		assert.Equal(t, maxRecordedGames+1, stats.Played)
+	})
+}
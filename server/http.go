@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+//maxHTTPBodySize is the maximal size of the body of the requests to the HTTP API.
+const maxHTTPBodySize = 4096
+
+//HandleHTTP registers the handlers of the HTTP API into the provided mux. The API lets dashboards and
+//other tools query the server without holding websocket connection open. All responses are JSON and
+//failures are described by {"code": ..., "message": ...} with the same codes as in the retry responses.
+//The endpoints are:
+//GET /rooms - lists the rooms, accepts the same query parameters as ls-rooms (open, rules, page, pageSize),
+//POST /rooms - opens new room without players, accepts optional body {"rules": ...}, the room is closed if
+//nobody joins it in time,
+//GET /rooms/{id} - describes the room,
+//GET /rooms/{id}/events - streams the public events of the room as Server-Sent Events,
+//GET /games/{id} - describes the finished game,
+//GET /players/{id}/stats - returns the stats of the player.
+func (s *Server) HandleHTTP(mux *http.ServeMux) {
+	mux.HandleFunc("/rooms", s.serveRooms)
+	mux.HandleFunc("/rooms/", s.serveRoom)
+	mux.HandleFunc("/games/", s.serveGame)
+	mux.HandleFunc("/players/", s.servePlayerStats)
+}
+
+func (s *Server) serveRooms(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		args := make(map[string]interface{})
+		for key := range r.URL.Query() {
+			args[key] = r.URL.Query().Get(key)
+		}
+		var payload web.ListRoomsPayload
+		if err := web.DecodeArgs(web.BuildRequest("", pkg.ListRooms, args), &payload); err != nil {
+			writeHTTPError(w, err)
+			return
+		}
+		filter, err := getRoomFilter(payload)
+		if err != nil {
+			writeHTTPError(w, err)
+			return
+		}
+		rooms, total := s.ListRooms(filter)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"rooms":    rooms,
+			"total":    total,
+			"page":     filter.Page,
+			"pageSize": filter.PageSize,
+		})
+	case http.MethodPost:
+		args, err := readHTTPArgs(r)
+		if err != nil {
+			writeHTTPError(w, err)
+			return
+		}
+		var payload web.CreateRoomPayload
+		if err = web.DecodeArgs(web.BuildRequest("", pkg.CreateRoom, args), &payload); err != nil {
+			writeHTTPError(w, err)
+			return
+		}
+		rules, err := getRules(payload.Rules)
+		if err != nil {
+			writeHTTPError(w, err)
+			return
+		}
+		room, err := s.OpenRoom(rules)
+		if err != nil {
+			writeHTTPError(w, err)
+			return
+		}
+		info := room.Info()
+		go s.RunRoom(room, s.getConnectRoom(room.Id))
+		writeJSON(w, http.StatusCreated, info)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+func (s *Server) serveRoom(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/rooms/")
+	events := strings.HasSuffix(id, "/events")
+	id = strings.TrimSuffix(id, "/events")
+	room := s.getRoom(id)
+	if room == nil {
+		writeHTTPError(w, web.NewError(web.RoomNotFound, fmt.Sprintf("room with id %s doesnt exist", id)))
+		return
+	}
+	if events {
+		s.serveRoomEvents(w, r, room)
+		return
+	}
+	writeJSON(w, http.StatusOK, room.Info())
+}
+
+func (s *Server) serveGame(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	record, err := s.history.GetGame(strings.TrimPrefix(r.URL.Path, "/games/"))
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, record)
+}
+
+func (s *Server) servePlayerStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/players/")
+	if !strings.HasSuffix(id, "/stats") {
+		writeHTTPError(w, web.NewError(web.PlayerNotFound, fmt.Sprintf("unknown path %s", r.URL.Path)))
+		return
+	}
+	stats, err := s.history.GetStats(strings.TrimSuffix(id, "/stats"))
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+//readHTTPArgs decodes the optional JSON object sent in the body of the request. Empty body is treated
+//as object without any keys.
+func readHTTPArgs(r *http.Request) (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxHTTPBodySize+1))
+	if err != nil {
+		return nil, web.NewError(web.InvalidRequest, "failed to read the request body")
+	}
+	if len(body) > maxHTTPBodySize {
+		return nil, web.NewError(web.InvalidRequest, fmt.Sprintf("request body is longer than %d bytes", maxHTTPBodySize))
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return args, nil
+	}
+	if err = json.Unmarshal(body, &args); err != nil {
+		return nil, web.NewError(web.InvalidRequest, "request is not valid JSON")
+	}
+	return args, nil
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeJSON(w, http.StatusMethodNotAllowed, web.NewError(web.InvalidRequest, "method not allowed"))
+}
+
+//writeHTTPError writes the error with its code. The HTTP status is chosen by the code, errors without
+//code are reported as internal errors.
+func writeHTTPError(w http.ResponseWriter, err error) {
+	resp := buildErrorResponse(err)
+	writeJSON(w, getHTTPStatus(resp.Code), web.NewError(resp.Code, resp.Message))
+}
+
+func getHTTPStatus(code string) int {
+	switch code {
+	case web.RoomNotFound, web.GameNotFound, web.PlayerNotFound:
+		return http.StatusNotFound
+	case web.TooManyRooms:
+		return http.StatusServiceUnavailable
+	case web.InternalError:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		fmt.Println("Write HTTP response: marshal error: ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveHTTP(s *Server, method, path, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	s.HandleHTTP(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec
+}
+
+func decodeHTTPError(t *testing.T, rec *httptest.ResponseRecorder) web.Error {
+	var httpErr web.Error
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &httpErr))
+	return httpErr
+}
+
+func TestServer_HandleHTTP(t *testing.T) {
+	t.Run("open room and describe it", func(t *testing.T) {
+		// when
+		s := NewServer()
+
+		created := serveHTTP(s, http.MethodPost, "/rooms", `{"rules": "compact"}`)
+		var info web.RoomInfo
+		assert.Nil(t, json.Unmarshal(created.Body.Bytes(), &info))
+
+		found := serveHTTP(s, http.MethodGet, "/rooms/"+info.Id, "")
+		var foundInfo web.RoomInfo
+		assert.Nil(t, json.Unmarshal(found.Body.Bytes(), &foundInfo))
+
+		// then
+		assert.Equal(t, http.StatusCreated, created.Code)
+		assert.Equal(t, "application/json", created.Header().Get("Content-Type"))
+		assert.Equal(t, game.Compact, info.Rules)
+		assert.Equal(t, 0, info.Players)
+		assert.Equal(t, pkg.Waiting, info.Phase)
+		assert.Equal(t, http.StatusOK, found.Code)
+		assert.Equal(t, info.Id, foundInfo.Id)
+	})
+	t.Run("open room with default rules when body is empty", func(t *testing.T) {
+		// when
+		s := NewServer()
+		rec := serveHTTP(s, http.MethodPost, "/rooms", "")
+
+		var info web.RoomInfo
+		assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &info))
+
+		// then
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, game.DefaultRules().Name, info.Rules)
+	})
+	t.Run("list rooms filtered by query parameters", func(t *testing.T) {
+		// when
+		s := NewServer()
+		compact, _ := game.GetRules(game.Compact)
+		_, _ = s.OpenRoom(game.DefaultRules())
+		room, _ := s.OpenRoom(compact)
+
+		rec := serveHTTP(s, http.MethodGet, "/rooms?rules=compact&pageSize=10", "")
+		var list struct {
+			Rooms    []web.RoomInfo `json:"rooms"`
+			Total    int            `json:"total"`
+			Page     int            `json:"page"`
+			PageSize int            `json:"pageSize"`
+		}
+		assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &list))
+
+		// then
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 1, list.Total)
+		assert.Equal(t, 1, list.Page)
+		assert.Equal(t, 10, list.PageSize)
+		assert.Equal(t, room.Id, list.Rooms[0].Id)
+	})
+	t.Run("fail when too many rooms wait for players", func(t *testing.T) {
+		// when
+		s := NewServer()
+		for i := 0; i < maxEmptyRooms; i++ {
+			_, err := s.OpenRoom(game.DefaultRules())
+			assert.Nil(t, err)
+		}
+		rec := serveHTTP(s, http.MethodPost, "/rooms", "")
+
+		// then
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, web.TooManyRooms, decodeHTTPError(t, rec).Code)
+		assert.Len(t, s.rooms, maxEmptyRooms)
+	})
+	t.Run("fail with code of the failure", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			method string
+			path   string
+			body   string
+			status int
+			code   string
+		}{
+			{"invalid page", http.MethodGet, "/rooms?page=-1", "", http.StatusBadRequest, web.InvalidArgument},
+			{"unknown rules", http.MethodPost, "/rooms", `{"rules": "huge"}`, http.StatusBadRequest, web.UnknownRules},
+			{"invalid body", http.MethodPost, "/rooms", `{"rules":`, http.StatusBadRequest, web.InvalidRequest},
+			{"missing room", http.MethodGet, "/rooms/missing", "", http.StatusNotFound, web.RoomNotFound},
+			{"missing game", http.MethodGet, "/games/missing-1", "", http.StatusNotFound, web.GameNotFound},
+			{"missing player", http.MethodGet, "/players/missing/stats", "", http.StatusNotFound, web.PlayerNotFound},
+			{"unknown player path", http.MethodGet, "/players/missing", "", http.StatusNotFound, web.PlayerNotFound},
+			{"method not allowed", http.MethodDelete, "/rooms", "", http.StatusMethodNotAllowed, web.InvalidRequest},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				// when
+				rec := serveHTTP(NewServer(), test.method, test.path, test.body)
+
+				// then
+				assert.Equal(t, test.status, rec.Code)
+				assert.Equal(t, test.code, decodeHTTPError(t, rec).Code)
+			})
+		}
+	})
+	t.Run("describe finished game and stats of its players", func(t *testing.T) {
+		// when
+		s := NewServer()
+		s.Start()
+
+		var info web.RoomInfo
+		assert.Nil(t, json.Unmarshal(serveHTTP(s, http.MethodPost, "/rooms", "").Body.Bytes(), &info))
+
+		first := connectClient(t, s)
+		second := connectClient(t, s)
+
+		first.send(pkg.JoinRoom, map[string]interface{}{"roomId": info.Id, "name": "alice"})
+		first.expect(pkg.Wait)
+		second.send(pkg.JoinRoom, map[string]interface{}{"roomId": info.Id})
+		second.expect(pkg.Wait)
+		first.expect(pkg.PlaceShip)
+
+		first.send(pkg.Exit, nil)
+		second.expect(pkg.Win)
+		second.expect(pkg.Lobby)
+
+		gameRec := serveHTTP(s, http.MethodGet, fmt.Sprintf("/games/%s-1", info.Id), "")
+		var record web.GameRecord
+		assert.Nil(t, json.Unmarshal(gameRec.Body.Bytes(), &record))
+
+		winnerRec := serveHTTP(s, http.MethodGet, fmt.Sprintf("/players/%s/stats", second.id), "")
+		var winner web.PlayerStats
+		assert.Nil(t, json.Unmarshal(winnerRec.Body.Bytes(), &winner))
+
+		loserRec := serveHTTP(s, http.MethodGet, fmt.Sprintf("/players/%s/stats", first.id), "")
+		var loser web.PlayerStats
+		assert.Nil(t, json.Unmarshal(loserRec.Body.Bytes(), &loser))
+
+		// then
+		assert.Equal(t, http.StatusOK, gameRec.Code)
+		assert.Equal(t, info.Id, record.RoomId)
+		assert.Equal(t, true, record.Forfeit)
+		assert.Equal(t, game.BoardSize, len(record.WinnerFields))
+		assert.Equal(t, web.PlayerStats{Name: "anonymous-2", Played: 1, Wins: 1}, winner)
+		assert.Equal(t, web.PlayerStats{Name: "alice", Played: 1, Losses: 1}, loser)
+	})
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/StanislavStefanov/Battleships/server/player"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const readTimeout = 5 * time.Second
+
+//testClient drives the server through in-memory pipe the same way as a real client does.
+type testClient struct {
+	t    *testing.T
+	conn *player.PipeConnection
+	id   string
+}
+
+func connectClient(t *testing.T, s *Server) *testClient {
+	c := &testClient{t: t, conn: s.Connect()}
+	register := c.expect(pkg.Register)
+	c.id = register.GetArgs()["id"].(string)
+	return c
+}
+
+func (c *testClient) send(action string, args map[string]interface{}) {
+	data, err := json.Marshal(web.BuildRequest(c.id, action, args))
+	assert.Nil(c.t, err)
+	assert.Nil(c.t, c.conn.WriteMessage(websocket.BinaryMessage, data))
+}
+
+func (c *testClient) read() ([]byte, error) {
+	type result struct {
+		data []byte
+		err  error
+	}
+	read := make(chan result, 1)
+	go func() {
+		_, data, err := c.conn.ReadMessage()
+		read <- result{data: data, err: err}
+	}()
+
+	select {
+	case r := <-read:
+		return r.data, r.err
+	case <-time.After(readTimeout):
+		c.t.Fatal("timeout while waiting for response")
+		return nil, nil
+	}
+}
+
+//expect skips the responses until response with the provided action is received. The test fails if
+//unexpected retry response is received.
+func (c *testClient) expect(action string) web.Response {
+	c.t.Helper()
+	for {
+		data, err := c.read()
+		if err != nil {
+			c.t.Fatalf("expected %s, got error: %s", action, err)
+		}
+		resp, _, err := web.DecodeResponse(data)
+		assert.Nil(c.t, err)
+		if resp.GetAction() == action {
+			return resp
+		}
+		if resp.GetAction() == pkg.Retry {
+			c.t.Fatalf("expected %s, got retry: %s", action, resp.GetMessage())
+		}
+	}
+}
+
+func (c *testClient) expectClosed() {
+	c.t.Helper()
+	for {
+		if _, err := c.read(); err != nil {
+			assert.Equal(c.t, player.ErrPipeClosed, err)
+			return
+		}
+	}
+}
+
+func TestServer_EndToEnd(t *testing.T) {
+	t.Run("play whole game through in-memory connections", func(t *testing.T) {
+		// when
+		s := NewServer()
+		s.Start()
+
+		first := connectClient(t, s)
+		second := connectClient(t, s)
+
+		rows := []int{0, 2, 4, 6, 8}
+		rules, _ := game.GetRules(game.Compact)
+		var lengths []int
+		for length := 5; length >= 2; length-- {
+			for i := 0; i < rules.Fleet[length]; i++ {
+				lengths = append(lengths, length)
+			}
+		}
+
+		// then
+		first.send(pkg.CreateRoom, map[string]interface{}{"name": "first", "rules": game.Compact})
+		created := first.expect(pkg.Wait)
+		roomId := created.GetArgs()["id"].(string)
+
+		second.send(pkg.JoinRoom, map[string]interface{}{"roomId": roomId})
+		second.expect(pkg.Wait)
+
+		for i, length := range lengths {
+			for _, c := range []*testClient{first, second} {
+				c.expect(pkg.PlaceShip)
+				c.send(pkg.PlaceShip, map[string]interface{}{"x": rows[i], "y": 0, "direction": game.Right})
+				placed := c.expect(pkg.Placed)
+				assert.Equal(t, float64(length), placed.GetArgs()["length"])
+			}
+		}
+
+		var targets, misses []game.Position
+		for i, length := range lengths {
+			for y := 0; y < length; y++ {
+				targets = append(targets, game.Position{X: rows[i], Y: y})
+				misses = append(misses, game.Position{X: rows[i] + 1, Y: y})
+			}
+		}
+
+		first.expect(pkg.Shoot)
+		for i, target := range targets {
+			first.send(pkg.Shoot, map[string]interface{}{"x": target.X, "y": target.Y})
+			if i == len(targets)-1 {
+				break
+			}
+			outcome := first.expect(pkg.ShootOutcome)
+			assert.Equal(t, true, outcome.GetArgs()["hit"])
+
+			second.expect(pkg.Shoot)
+			second.send(pkg.Shoot, map[string]interface{}{"x": misses[i].X, "y": misses[i].Y})
+			outcome = second.expect(pkg.ShootOutcome)
+			assert.Equal(t, false, outcome.GetArgs()["hit"])
+			first.expect(pkg.Shoot)
+		}
+
+		first.expect(pkg.Win)
+		second.expect(pkg.Lose)
+		first.expect(pkg.Rematch)
+		second.expect(pkg.Rematch)
+
+		first.send(pkg.Rematch, map[string]interface{}{"accept": false})
+		first.expect(pkg.Lobby)
+		second.expect(pkg.Lobby)
+
+		second.send(pkg.Chat, map[string]interface{}{"text": "gg"})
+		chat := first.expect(pkg.Chat)
+		assert.Equal(t, "gg", chat.GetMessage())
+
+		first.send(pkg.Exit, nil)
+		first.expectClosed()
+	})
+}
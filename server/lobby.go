@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/StanislavStefanov/Battleships/server/player"
+	"strings"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	maxNameLength   = 32
+	anonymousPrefix = "anonymous"
+)
+
+//RoomFilter describes which rooms should be listed. If OpenOnly is true only rooms with free
+//place are listed. If Rules is not empty only rooms played by the rule set with that name are
+//listed. The matching rooms are split into pages of PageSize rooms and only the page with
+//number Page is listed. Pages are numbered from 1.
+type RoomFilter struct {
+	OpenOnly bool
+	Rules    string
+	Page     int
+	PageSize int
+}
+
+//Match returns true if the described room passes the filter, false otherwise.
+func (f RoomFilter) Match(info web.RoomInfo) bool {
+	if f.OpenOnly && info.Players >= 2 {
+		return false
+	}
+	if f.Rules != "" && f.Rules != info.Rules {
+		return false
+	}
+	return true
+}
+
+//releaseRoom hands the players and the spectators in the room back to the lobby. The player who
+//has left the room has already stopped reading from his connection, so new lobby read loop is
+//started for him, unless he got disconnected. Then his connection is closed instead. The requests
+//of the other players are passed to the lobby by their room read loops. The spectators watching only
+//the events of the room aren't players of the lobby, so their stream is just ended.
+func (s *Server) releaseRoom(r *Room) {
+	for _, p := range []*player.Player{r.Current, r.Next} {
+		if p == nil {
+			continue
+		}
+		if p.Id == r.leftId && r.disconnected {
+			_ = p.Conn.Close()
+			continue
+		}
+		s.returnToLobby(p)
+		if p.Id == r.leftId {
+			go ReadLoop(p, s)
+		}
+	}
+	for _, spectator := range r.Spectators {
+		if spectator.EventsOnly {
+			resp := web.BuildResponse(pkg.Lobby, "The room is closed.", nil)
+			s.sender.SendResponse(resp, spectator.Conn)
+			continue
+		}
+		s.returnToLobby(spectator.Player)
+	}
+	if r.Closed != nil {
+		close(r.Closed)
+	}
+}
+
+//returnToLobby stores the player into the server as if he has just registered and notifies him with
+//Response with action "lobby". His board is cleared for the next game.
+func (s *Server) returnToLobby(p *player.Player) {
+	p.Board = game.InitBoard()
+	s.mu.Lock()
+	s.clients[p.Id] = p
+	s.mu.Unlock()
+
+	resp := web.BuildResponse(pkg.Lobby, "You are back in the lobby.", nil)
+	s.sender.SendResponse(resp, p.Conn)
+}
+
+//getRoomFilter builds RoomFilter from the payload of ls-rooms request. All fields are optional:
+//open - list only rooms with free place, rules - list only rooms played by that rule set, page -
+//number of the listed page starting from 1, pageSize - count of rooms on a page, at most 100.
+func getRoomFilter(payload web.ListRoomsPayload) (RoomFilter, error) {
+	filter := RoomFilter{
+		OpenOnly: payload.Open,
+		Rules:    payload.Rules,
+		Page:     1,
+		PageSize: defaultPageSize,
+	}
+
+	if payload.Page != 0 {
+		filter.Page = payload.Page
+	}
+	if filter.Page < 1 {
+		return RoomFilter{}, invalidValue("page")
+	}
+
+	if payload.PageSize != 0 {
+		filter.PageSize = payload.PageSize
+	}
+	if filter.PageSize < 1 || filter.PageSize > maxPageSize {
+		return RoomFilter{}, invalidValue("pageSize")
+	}
+
+	return filter, nil
+}
+
+//getRules returns the rule set with the name requested in create-room request. If the name is
+//empty the default rules are returned.
+func getRules(name string) (game.Rules, error) {
+	if name == "" {
+		return game.DefaultRules(), nil
+	}
+	return game.GetRules(name)
+}
+
+//getPlayerName returns the trimmed name requested in create-room and join-room requests. An empty
+//string is returned if the name is missing.
+func getPlayerName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if len(name) > maxNameLength {
+		return "", web.NewError(web.NameTooLong, fmt.Sprintf("name is longer than %d characters", maxNameLength))
+	}
+	return name, nil
+}
+
+//claimPlayerName claims the name requested in create-room and join-room requests for the player. If the
+//name is missing he keeps his current name.
+func (s *Server) claimPlayerName(p *player.Player, name string) error {
+	name, err := getPlayerName(name)
+	if err != nil {
+		return err
+	}
+	return s.claimName(p, name)
+}
+
+//claimName sets the provided name to the player if no other connected player uses it, so the players can
+//be told apart, e.g. when muting them. The names are compared case-insensitively and the names starting
+//with "anonymous" are reserved for the players who haven't chosen any name. If the name is empty the
+//player keeps his current name. If the name is taken error with code NameTaken is returned.
+func (s *Server) claimName(p *player.Player, name string) error {
+	if name == "" || name == p.Name {
+		return nil
+	}
+
+	taken := s.roomNames()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, other := range s.clients {
+		if other.Id != p.Id {
+			taken = append(taken, other.GetName())
+		}
+	}
+	for _, other := range taken {
+		if strings.EqualFold(other, name) {
+			return web.NewError(web.NameTaken, fmt.Sprintf("name %s is already taken", name))
+		}
+	}
+	if strings.HasPrefix(strings.ToLower(name), anonymousPrefix) && !strings.EqualFold(name, p.Name) {
+		return web.NewError(web.NameTaken, fmt.Sprintf("name %s is reserved for anonymous players", name))
+	}
+
+	p.Name = name
+	return nil
+}
+
+//roomNames returns the names of the players and the spectators in all rooms.
+func (s *Server) roomNames() []string {
+	s.roomsMu.RLock()
+	defer s.roomsMu.RUnlock()
+
+	var names []string
+	for _, r := range s.rooms {
+		names = append(names, r.Names()...)
+	}
+	return names
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"errors"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/StanislavStefanov/Battleships/server/automock"
+	"github.com/StanislavStefanov/Battleships/server/player"
+	connection "github.com/StanislavStefanov/Battleships/server/player/automock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLobby_GetRoomFilter(t *testing.T) {
+	// given
+	testCases := []struct {
+		Name   string
+		Args   map[string]interface{}
+		Filter RoomFilter
+		Err    string
+	}{
+		{
+			Name:   "default filter when args are missing",
+			Args:   nil,
+			Filter: RoomFilter{Page: 1, PageSize: defaultPageSize},
+		},
+		{
+			Name:   "filter with values of their own types",
+			Args:   map[string]interface{}{"open": true, "rules": game.Compact, "page": float64(2), "pageSize": float64(5)},
+			Filter: RoomFilter{OpenOnly: true, Rules: game.Compact, Page: 2, PageSize: 5},
+		},
+		{
+			Name:   "filter with values sent as strings",
+			Args:   map[string]interface{}{"open": "true", "page": "3", "pageSize": "10"},
+			Filter: RoomFilter{OpenOnly: true, Page: 3, PageSize: 10},
+		},
+		{
+			Name: "fail when open has invalid value",
+			Args: map[string]interface{}{"open": "yes please"},
+			Err:  "invalid value for open",
+		},
+		{
+			Name: "fail when rules has invalid type",
+			Args: map[string]interface{}{"rules": float64(1)},
+			Err:  "invalid value for rules",
+		},
+		{
+			Name: "fail when page is not positive",
+			Args: map[string]interface{}{"page": float64(-1)},
+			Err:  "invalid value for page",
+		},
+		{
+			Name: "fail when page is not integer",
+			Args: map[string]interface{}{"page": 1.5},
+			Err:  "invalid value for page",
+		},
+		{
+			Name: "fail when page size is too big",
+			Args: map[string]interface{}{"pageSize": "101"},
+			Err:  "invalid value for pageSize",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			// when
+			var payload web.ListRoomsPayload
+			err := web.DecodeArgs(web.BuildRequest("id", pkg.ListRooms, testCase.Args), &payload)
+			filter := RoomFilter{}
+			if err == nil {
+				filter, err = getRoomFilter(payload)
+			}
+
+			// then
+			if testCase.Err != "" {
+				assert.EqualError(t, err, testCase.Err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.Filter, filter)
+		})
+	}
+}
+
+func TestLobby_GetRules(t *testing.T) {
+	t.Run("default rules when rules are missing", func(t *testing.T) {
+		// then
+		rules, err := getRules("")
+		assert.NoError(t, err)
+		assert.Equal(t, game.DefaultRules(), rules)
+	})
+	t.Run("requested rules", func(t *testing.T) {
+		// then
+		rules, err := getRules(game.Compact)
+		assert.NoError(t, err)
+		assert.Equal(t, game.Compact, rules.Name)
+	})
+	t.Run("fail when rule set is unknown", func(t *testing.T) {
+		// then
+		_, err := getRules("unknown")
+		assert.EqualError(t, err, "unknown rule set unknown")
+	})
+}
+
+func TestLobby_GetPlayerName(t *testing.T) {
+	t.Run("empty name when name is missing", func(t *testing.T) {
+		// then
+		name, err := getPlayerName("")
+		assert.NoError(t, err)
+		assert.Equal(t, "", name)
+	})
+	t.Run("trimmed name", func(t *testing.T) {
+		// then
+		name, err := getPlayerName("  captain ")
+		assert.NoError(t, err)
+		assert.Equal(t, "captain", name)
+	})
+	t.Run("fail when name is too long", func(t *testing.T) {
+		// then
+		_, err := getPlayerName(strings.Repeat("a", maxNameLength+1))
+		assert.EqualError(t, err, "name is longer than 32 characters")
+	})
+}
+
+func TestServer_ClaimName(t *testing.T) {
+	newServer := func() (*Server, *player.Player) {
+		room := &Room{Id: "room"}
+		room.names.Store([]string{"Bob"})
+		p := &player.Player{Id: "player", Name: "anonymous-1"}
+		s := &Server{
+			clients: map[string]*player.Player{
+				"player": p,
+				"other":  {Id: "other", Name: "alice"},
+			},
+			rooms: map[string]*Room{"room": room},
+		}
+		return s, p
+	}
+
+	t.Run("set free name", func(t *testing.T) {
+		// when
+		s, p := newServer()
+
+		// then
+		assert.NoError(t, s.claimName(p, "captain"))
+		assert.Equal(t, "captain", p.GetName())
+	})
+	t.Run("keep current name when name is empty", func(t *testing.T) {
+		// when
+		s, p := newServer()
+
+		// then
+		assert.NoError(t, s.claimName(p, ""))
+		assert.Equal(t, "anonymous-1", p.GetName())
+	})
+	t.Run("fail when name is used by player in the lobby", func(t *testing.T) {
+		// when
+		s, p := newServer()
+
+		// then
+		assert.EqualError(t, s.claimName(p, "Alice"), "name Alice is already taken")
+		assert.Equal(t, "anonymous-1", p.GetName())
+	})
+	t.Run("fail when name is used by player in room", func(t *testing.T) {
+		// when
+		s, p := newServer()
+
+		// then
+		assert.EqualError(t, s.claimName(p, "bob"), "name bob is already taken")
+	})
+	t.Run("fail when name is reserved for anonymous players", func(t *testing.T) {
+		// when
+		s, p := newServer()
+
+		// then
+		assert.EqualError(t, s.claimName(p, "anonymous-2"), "name anonymous-2 is reserved for anonymous players")
+	})
+}
+
+//waitForClients waits until the server keeps exactly the expected clients. The clients are changed by the
+//read loops running in the background, so they are read under the lock of the server and the players
+//are compared by identity, without reading their connections, which may still be in use.
+func waitForClients(t *testing.T, s *Server, expected map[string]*player.Player) {
+	assert.Eventually(t, func() bool {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		if len(s.clients) != len(expected) {
+			return false
+		}
+		for id, p := range expected {
+			if s.clients[id] != p {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestServer_ReleaseRoom(t *testing.T) {
+	t.Run("return players and spectators to the lobby", func(t *testing.T) {
+		// when
+		firstConn := &connection.Connection{}
+		secondConn := &connection.Connection{}
+		spectatorConn := &connection.Connection{}
+		first := &player.Player{Id: "first", Conn: firstConn, Board: getBoard()}
+		second := &player.Player{Id: "second", Conn: secondConn, Board: getBoard()}
+		spectator := &player.Player{Id: "spectator", Conn: spectatorConn}
+
+		lobby := web.BuildResponse(pkg.Lobby, "You are back in the lobby.", nil)
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", lobby, firstConn).Return(nil).Once()
+		responseSender.On("SendResponse", lobby, secondConn).Return(nil).Once()
+		responseSender.On("SendResponse", lobby, spectatorConn).Return(nil).Once()
+
+		closed := make(chan struct{})
+		room := &Room{
+			Current:    first,
+			Next:       second,
+			Spectators: map[string]*Spectator{"spectator": {Player: spectator}},
+			Closed:     closed,
+		}
+		s := &Server{
+			clients: map[string]*player.Player{},
+			sender:  responseSender,
+		}
+
+		// then
+		s.releaseRoom(room)
+		_, ok := <-closed
+		assert.False(t, ok)
+		assert.Equal(t, map[string]*player.Player{"first": first, "second": second, "spectator": spectator}, s.clients)
+		assert.Equal(t, game.InitBoard(), first.Board)
+		responseSender.AssertExpectations(t)
+	})
+	t.Run("end the stream of spectator watching only the events", func(t *testing.T) {
+		// when
+		firstConn := &connection.Connection{}
+		secondConn := &connection.Connection{}
+		streamConn := &connection.Connection{}
+		first := &player.Player{Id: "first", Conn: firstConn, Board: getBoard()}
+		second := &player.Player{Id: "second", Conn: secondConn, Board: getBoard()}
+
+		lobby := web.BuildResponse(pkg.Lobby, "You are back in the lobby.", nil)
+		closedRoom := web.BuildResponse(pkg.Lobby, "The room is closed.", nil)
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", lobby, firstConn).Return(nil).Once()
+		responseSender.On("SendResponse", lobby, secondConn).Return(nil).Once()
+		responseSender.On("SendResponse", closedRoom, streamConn).Return(nil).Once()
+
+		room := &Room{
+			Current: first,
+			Next:    second,
+			Spectators: map[string]*Spectator{
+				"stream": {Player: &player.Player{Id: "stream", Conn: streamConn}, EventsOnly: true},
+			},
+		}
+		s := &Server{
+			clients: map[string]*player.Player{},
+			sender:  responseSender,
+		}
+
+		// then
+		s.releaseRoom(room)
+		assert.Equal(t, map[string]*player.Player{"first": first, "second": second}, s.clients)
+		responseSender.AssertExpectations(t)
+	})
+	t.Run("start lobby read loop for player who left the room", func(t *testing.T) {
+		// when
+		firstConn := &connection.Connection{}
+		firstConn.On("ReadMessage").Return(0, nil, errors.New("read failure")).Once()
+		secondConn := &connection.Connection{}
+		first := &player.Player{Id: "first", Conn: firstConn}
+		second := &player.Player{Id: "second", Conn: secondConn}
+
+		//the connection of the first player is matched by identity, so it isn't printed while the response
+		//to the second player is matched, as it is already used by the lobby read loop of the first player
+		isFirstConn := mock.MatchedBy(func(conn player.Connection) bool { return conn == firstConn })
+		lobby := web.BuildResponse(pkg.Lobby, "You are back in the lobby.", nil)
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", lobby, isFirstConn).Return(nil).Once()
+		responseSender.On("SendResponse", lobby, secondConn).Return(nil).Once()
+
+		room := &Room{
+			Current: first,
+			Next:    second,
+			leftId:  "first",
+		}
+		s := &Server{
+			clients: map[string]*player.Player{},
+			sender:  responseSender,
+		}
+
+		// then
+		s.releaseRoom(room)
+		waitForClients(t, s, map[string]*player.Player{"second": second})
+		firstConn.AssertExpectations(t)
+		responseSender.AssertExpectations(t)
+	})
+	t.Run("disconnect player who lost connection", func(t *testing.T) {
+		// when
+		firstConn := &connection.Connection{}
+		firstConn.On("Close").Return(nil).Once()
+		secondConn := &connection.Connection{}
+		first := &player.Player{Id: "first", Conn: firstConn}
+		second := &player.Player{Id: "second", Conn: secondConn}
+
+		lobby := web.BuildResponse(pkg.Lobby, "You are back in the lobby.", nil)
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", lobby, secondConn).Return(nil).Once()
+
+		room := &Room{
+			Current:      first,
+			Next:         second,
+			leftId:       "first",
+			disconnected: true,
+		}
+		s := &Server{
+			clients: map[string]*player.Player{},
+			sender:  responseSender,
+		}
+
+		// then
+		s.releaseRoom(room)
+		assert.Equal(t, map[string]*player.Player{"second": second}, s.clients)
+		assert.Equal(t, 0, countSent(responseSender, firstConn))
+		firstConn.AssertExpectations(t)
+		responseSender.AssertExpectations(t)
+	})
+}
@@ -2,32 +2,28 @@ package main
 
 import (
 	"flag"
-	"github.com/StanislavStefanov/Battleships/server/player"
-	"github.com/google/uuid"
-	"github.com/gorilla/websocket"
 	"log"
 	"net/http"
 )
 
 func main() {
-	register := make(chan *websocket.Conn)
-	message := make(chan struct{})
+	var addr = flag.String("localhost", ":8080", "http service address")
+	var tcpAddr = flag.String("tcp", ":8081", "tcp service address, empty to disable the tcp transport")
+	flag.Parse()
 
-	server := Server{
-		clients:     make(map[string]*player.Player, 0),
-		rooms:       make(map[string]*Room, 0),
-		connectRoom: make(map[string]chan *player.Player, 0),
-		register:    register,
-		done:        message,
-		sender:      &Sender{},
-		UUID:        uuid.UUID{},
-	}
-	go server.run()
+	server := NewServer()
+	server.Start()
 	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		ServeWs(&server, w, r)
+		ServeWs(server, w, r)
 	})
+	server.HandleHTTP(http.DefaultServeMux)
+	http.Handle("/", StaticHandler())
 
-	var addr = flag.String("localhost", ":8080", "http service address")
+	if *tcpAddr != "" {
+		go func() {
+			log.Fatal("ListenTCP: ", server.ListenTCP(*tcpAddr))
+		}()
+	}
 
 	err := http.ListenAndServe(*addr, nil)
 	if err != nil {
@@ -0,0 +1,22 @@
+package player
+
+import "sync"
+
+//SyncConnection wraps Connection and serializes the writes to it, so responses can be safely
+//sent to the player from several goroutines.
+type SyncConnection struct {
+	Connection
+	mu sync.Mutex
+}
+
+//NewSyncConnection returns SyncConnection wrapping the provided connection.
+func NewSyncConnection(conn Connection) *SyncConnection {
+	return &SyncConnection{Connection: conn}
+}
+
+//WriteMessage writes the message to the wrapped connection. Only one message is written at a time.
+func (c *SyncConnection) WriteMessage(messageType int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Connection.WriteMessage(messageType, data)
+}
@@ -0,0 +1,73 @@
+package player
+
+import (
+	"errors"
+	"sync"
+)
+
+//EventBuffer is the count of messages which can be written to EventConnection before they are streamed
+//to the client. If the client doesn't keep up and the buffer is full the connection is closed, so slow
+//clients never block the room.
+const EventBuffer = 64
+
+//ErrEventsClosed is returned when writing to closed EventConnection and when reading from it.
+var ErrEventsClosed = errors.New("event stream is closed")
+
+//EventConnection is write-only connection whose messages are streamed to the client, e.g. as
+//Server-Sent Events. The client can't send any messages, so ReadMessage blocks until the connection
+//is closed.
+type EventConnection struct {
+	events chan []byte
+	closed chan struct{}
+	once   sync.Once
+}
+
+//NewEventConnection returns open EventConnection without any messages.
+func NewEventConnection() *EventConnection {
+	return &EventConnection{
+		events: make(chan []byte, EventBuffer),
+		closed: make(chan struct{}),
+	}
+}
+
+//Events returns the channel from which the written messages are read in the order they were written.
+func (c *EventConnection) Events() <-chan []byte {
+	return c.events
+}
+
+//Done returns channel which is closed when the connection is closed.
+func (c *EventConnection) Done() <-chan struct{} {
+	return c.closed
+}
+
+//ReadMessage blocks until the connection is closed and returns ErrEventsClosed.
+func (c *EventConnection) ReadMessage() (int, []byte, error) {
+	<-c.closed
+	return 0, nil, ErrEventsClosed
+}
+
+//WriteMessage queues copy of the message to be streamed. If the buffer is full the connection is
+//closed. ErrEventsClosed is returned if the connection is closed.
+func (c *EventConnection) WriteMessage(_ int, data []byte) error {
+	select {
+	case <-c.closed:
+		return ErrEventsClosed
+	default:
+	}
+
+	select {
+	case c.events <- append([]byte(nil), data...):
+		return nil
+	default:
+		_ = c.Close()
+		return ErrEventsClosed
+	}
+}
+
+//Close closes the connection. Closing already closed connection has no effect.
+func (c *EventConnection) Close() error {
+	c.once.Do(func() {
+		close(c.closed)
+	})
+	return nil
+}
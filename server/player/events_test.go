@@ -0,0 +1,48 @@
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEventConnection(t *testing.T) {
+	t.Run("queue copies of the written messages in order", func(t *testing.T) {
+		// when
+		conn := NewEventConnection()
+		data := []byte("first")
+
+		// then
+		assert.Nil(t, conn.WriteMessage(2, data))
+		assert.Nil(t, conn.WriteMessage(2, []byte("second")))
+		data[0] = 'F'
+
+		assert.Equal(t, "first", string(<-conn.Events()))
+		assert.Equal(t, "second", string(<-conn.Events()))
+	})
+	t.Run("close connection when the buffer is full", func(t *testing.T) {
+		// when
+		conn := NewEventConnection()
+		for i := 0; i < EventBuffer; i++ {
+			assert.Nil(t, conn.WriteMessage(2, []byte("event")))
+		}
+
+		// then
+		assert.Equal(t, ErrEventsClosed, conn.WriteMessage(2, []byte("event")))
+		<-conn.Done()
+		assert.Equal(t, ErrEventsClosed, conn.WriteMessage(2, []byte("event")))
+	})
+	t.Run("block reading until the connection is closed", func(t *testing.T) {
+		// when
+		conn := NewEventConnection()
+		read := make(chan error, 1)
+		go func() {
+			_, _, err := conn.ReadMessage()
+			read <- err
+		}()
+
+		// then
+		assert.Nil(t, conn.Close())
+		assert.Nil(t, conn.Close())
+		assert.Equal(t, ErrEventsClosed, <-read)
+	})
+}
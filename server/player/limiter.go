@@ -0,0 +1,34 @@
+package player
+
+import "time"
+
+//RateLimiter allows at most Limit events in every time window with length Window.
+type RateLimiter struct {
+	Limit  int
+	Window time.Duration
+	events []time.Time
+}
+
+//NewRateLimiter returns RateLimiter which allows at most limit events per window.
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		Limit:  limit,
+		Window: window,
+	}
+}
+
+//Allow returns true and records the event if less than Limit events happened in the last Window
+//before now, otherwise returns false.
+func (l *RateLimiter) Allow(now time.Time) bool {
+	start := 0
+	for start < len(l.events) && !l.events[start].After(now.Add(-l.Window)) {
+		start++
+	}
+	l.events = l.events[start:]
+
+	if len(l.events) >= l.Limit {
+		return false
+	}
+	l.events = append(l.events, now)
+	return true
+}
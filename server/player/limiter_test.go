@@ -0,0 +1,23 @@
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	t.Run("allow at most limit events per window", func(t *testing.T) {
+		// when
+		now := time.Now()
+		limiter := NewRateLimiter(2, time.Second)
+
+		// then
+		assert.True(t, limiter.Allow(now))
+		assert.True(t, limiter.Allow(now.Add(100*time.Millisecond)))
+		assert.False(t, limiter.Allow(now.Add(500*time.Millisecond)))
+		assert.True(t, limiter.Allow(now.Add(time.Second)))
+		assert.False(t, limiter.Allow(now.Add(time.Second+50*time.Millisecond)))
+		assert.True(t, limiter.Allow(now.Add(time.Second+100*time.Millisecond)))
+	})
+}
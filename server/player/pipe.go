@@ -0,0 +1,88 @@
+package player
+
+import (
+	"errors"
+	"sync"
+)
+
+//PipeBuffer is the count of messages which can be written to PipeConnection before the other end
+//reads them. Writing more messages blocks until some of them are read.
+const PipeBuffer = 256
+
+//ErrPipeClosed is returned when reading from or writing to closed PipeConnection.
+var ErrPipeClosed = errors.New("pipe is closed")
+
+type pipeMessage struct {
+	messageType int
+	data        []byte
+}
+
+//PipeConnection is one end of in-memory connection created by Pipe. The messages written to one end
+//are read from the other one in the same order. It is used to connect clients living in the same
+//process, e.g. in tests, without opening sockets.
+type PipeConnection struct {
+	in     <-chan pipeMessage
+	out    chan<- pipeMessage
+	closed chan struct{}
+	once   *sync.Once
+}
+
+//Pipe returns both ends of new in-memory connection. Closing any of the ends closes the whole
+//connection.
+func Pipe() (*PipeConnection, *PipeConnection) {
+	first := make(chan pipeMessage, PipeBuffer)
+	second := make(chan pipeMessage, PipeBuffer)
+	closed := make(chan struct{})
+	once := &sync.Once{}
+
+	return &PipeConnection{in: first, out: second, closed: closed, once: once},
+		&PipeConnection{in: second, out: first, closed: closed, once: once}
+}
+
+//ReadMessage returns the next message written to the other end. It blocks until there is a message
+//or the connection is closed. The messages written before the connection was closed can still be read.
+func (c *PipeConnection) ReadMessage() (int, []byte, error) {
+	select {
+	case m := <-c.in:
+		return m.messageType, m.data, nil
+	default:
+	}
+
+	select {
+	case m := <-c.in:
+		return m.messageType, m.data, nil
+	case <-c.closed:
+		select {
+		case m := <-c.in:
+			return m.messageType, m.data, nil
+		default:
+			return 0, nil, ErrPipeClosed
+		}
+	}
+}
+
+//WriteMessage passes copy of the message to the other end. ErrPipeClosed is returned if the connection
+//is closed.
+func (c *PipeConnection) WriteMessage(messageType int, data []byte) error {
+	select {
+	case <-c.closed:
+		return ErrPipeClosed
+	default:
+	}
+
+	message := pipeMessage{messageType: messageType, data: append([]byte(nil), data...)}
+	select {
+	case c.out <- message:
+		return nil
+	case <-c.closed:
+		return ErrPipeClosed
+	}
+}
+
+//Close closes both ends of the connection. Closing already closed connection has no effect.
+func (c *PipeConnection) Close() error {
+	c.once.Do(func() {
+		close(c.closed)
+	})
+	return nil
+}
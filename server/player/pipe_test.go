@@ -0,0 +1,64 @@
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPipe(t *testing.T) {
+	t.Run("pass messages between both ends in order", func(t *testing.T) {
+		// when
+		first, second := Pipe()
+		data := []byte("first")
+
+		// then
+		assert.Nil(t, first.WriteMessage(2, data))
+		assert.Nil(t, first.WriteMessage(1, []byte("second")))
+		data[0] = 'F'
+		assert.Nil(t, second.WriteMessage(2, []byte("reply")))
+
+		messageType, message, err := second.ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, 2, messageType)
+		assert.Equal(t, "first", string(message))
+
+		messageType, message, err = second.ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, 1, messageType)
+		assert.Equal(t, "second", string(message))
+
+		_, message, err = first.ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, "reply", string(message))
+	})
+	t.Run("read pending messages after close", func(t *testing.T) {
+		// when
+		first, second := Pipe()
+
+		// then
+		assert.Nil(t, first.WriteMessage(2, []byte("bye")))
+		assert.Nil(t, first.Close())
+		assert.Nil(t, second.Close())
+
+		_, message, err := second.ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, "bye", string(message))
+
+		_, _, err = second.ReadMessage()
+		assert.Equal(t, ErrPipeClosed, err)
+		assert.Equal(t, ErrPipeClosed, second.WriteMessage(2, []byte("late")))
+	})
+	t.Run("unblock reader when the other end is closed", func(t *testing.T) {
+		// when
+		first, second := Pipe()
+		done := make(chan error)
+		go func() {
+			_, _, err := second.ReadMessage()
+			done <- err
+		}()
+
+		// then
+		assert.Nil(t, first.Close())
+		assert.Equal(t, ErrPipeClosed, <-done)
+	})
+}
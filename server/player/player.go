@@ -2,6 +2,8 @@ package player
 
 import (
 	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"strings"
+	"sync"
 )
 
 //go:generate mockery -name=Connection -output=automock -outpkg=automock -case=underscore
@@ -12,11 +14,52 @@ type Connection interface {
 }
 
 type Player struct {
-	Conn  Connection
-	Board *game.Board
-	Id    string
+	Conn        Connection
+	Board       *game.Board
+	Id          string
+	Name        string
+	ChatLimiter *RateLimiter
+	muted       map[string]bool
+	mu          sync.Mutex
+}
+
+const anonymous = "anonymous"
+
+//GetName returns the name chosen by the player or "anonymous" if he hasn't chosen any.
+func (p *Player) GetName() string {
+	if p.Name == "" {
+		return anonymous
+	}
+	return p.Name
 }
 
 func (p *Player) PlaceShip(ship game.Ship) error {
 	return p.Board.PlaceShip(ship)
 }
+
+//ToggleMute mutes the chat messages from players with the provided name if they are not muted yet,
+//otherwise unmutes them. The names are compared case-insensitively, as they are when claimed by the
+//players. Returns true if the players are muted after the call, false otherwise.
+func (p *Player) ToggleMute(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := strings.ToLower(name)
+	if p.muted[key] {
+		delete(p.muted, key)
+		return false
+	}
+	if p.muted == nil {
+		p.muted = make(map[string]bool)
+	}
+	p.muted[key] = true
+	return true
+}
+
+//IsMuted returns true if the chat messages from players with the provided name are muted. The names
+//are compared case-insensitively.
+func (p *Player) IsMuted(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.muted[strings.ToLower(name)]
+}
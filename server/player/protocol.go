@@ -0,0 +1,182 @@
+package player
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"sync"
+)
+
+//ProtocolConnection wraps Connection and translates the messages between the protocol version and the
+//encoding negotiated with the client and ProtocolV1 encoded as JSON, which is used by the server internally.
+//Until the client negotiates other version or encoding through hello request the messages are passed as
+//they are, so old clients keep working.
+type ProtocolConnection struct {
+	Connection
+	version  int
+	codec    web.Codec
+	textOnly bool
+	mu       sync.RWMutex
+}
+
+//NewProtocolConnection returns ProtocolConnection wrapping the provided connection. The connection
+//uses ProtocolV1 and JSON until other version or encoding is negotiated.
+func NewProtocolConnection(conn Connection) *ProtocolConnection {
+	return &ProtocolConnection{Connection: conn, version: web.ProtocolV1, codec: web.DefaultCodec()}
+}
+
+//NewTextProtocolConnection returns ProtocolConnection wrapping connection which can carry only text
+//messages, e.g. TCPConnection. Only JSON encoding can be negotiated on such connection.
+func NewTextProtocolConnection(conn Connection) *ProtocolConnection {
+	c := NewProtocolConnection(conn)
+	c.textOnly = true
+	return c
+}
+
+//GetVersion returns the protocol version negotiated with the client.
+func (c *ProtocolConnection) GetVersion() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.version
+}
+
+//GetCodec returns the codec of the encoding negotiated with the client.
+func (c *ProtocolConnection) GetCodec() web.Codec {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.codec
+}
+
+//ReadMessage reads the next request sent by the client and returns it encoded in ProtocolV1 as JSON. Hello
+//requests are answered directly and are not returned. Requests which can't be decoded with the negotiated
+//codec are answered with Response with status Retry and code InvalidRequest and requests with payload which
+//doesn't match their type with code InvalidArgument. Such requests are not returned either. The responses
+//carry the id of the request they answer.
+func (c *ProtocolConnection) ReadMessage() (int, []byte, error) {
+	for {
+		messageType, data, err := c.Connection.ReadMessage()
+		if err != nil {
+			return messageType, data, err
+		}
+
+		codec := c.GetCodec()
+		data, err = web.Transcode(data, codec, web.DefaultCodec())
+		if err != nil {
+			message := fmt.Sprintf("request is not valid %s", codec.Name())
+			c.reply(messageType, web.Request{}, web.BuildErrorResponse(web.NewError(web.InvalidRequest, message)))
+			continue
+		}
+
+		req, version, err := web.DecodeRequest(data)
+		if err != nil {
+			if version != web.ProtocolV2 {
+				return messageType, data, nil
+			}
+			c.reply(messageType, req, web.BuildErrorResponse(web.NewError(web.InvalidArgument, err.Error())))
+			continue
+		}
+		if req.Action == web.Hello {
+			c.negotiate(messageType, req)
+			continue
+		}
+		if version == web.ProtocolV1 {
+			return messageType, data, nil
+		}
+
+		data, err = json.Marshal(req)
+		return messageType, data, err
+	}
+}
+
+//WriteMessage encodes the ProtocolV1 response in the negotiated protocol version and encoding and writes
+//it to the wrapped connection.
+func (c *ProtocolConnection) WriteMessage(messageType int, data []byte) error {
+	c.mu.RLock()
+	version, codec := c.version, c.codec
+	c.mu.RUnlock()
+
+	if version != web.ProtocolV1 {
+		var resp web.Response
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		encoded, err := web.EncodeResponse(resp, version)
+		if err != nil {
+			return err
+		}
+		data = encoded
+	}
+
+	data, err := web.Transcode(data, web.DefaultCodec(), codec)
+	if err != nil {
+		return err
+	}
+	return c.Connection.WriteMessage(messageType, data)
+}
+
+//negotiate chooses the latest protocol version supported by the client and the server and the first
+//encoding preferred by the client which is supported by the server. If the client doesn't list any
+//versions or encodings the current ones are kept. The client is notified with hello response encoded
+//in the chosen version, but still in the previous encoding, and the chosen encoding is used for all
+//further messages. If there is no common version or encoding the client is notified with Response with
+//status Retry and code UnsupportedVersion or UnsupportedEncoding and nothing changes.
+func (c *ProtocolConnection) negotiate(messageType int, req web.Request) {
+	var hello web.HelloPayload
+	_ = web.DecodePayload(req.GetArgs(), &hello)
+	if len(hello.Versions) == 0 && hello.Version != 0 {
+		hello.Versions = []int{hello.Version}
+	}
+	if len(hello.Versions) == 0 {
+		hello.Versions = []int{c.GetVersion()}
+	}
+
+	version, err := web.Negotiate(hello.Versions)
+	if err != nil {
+		c.reply(messageType, req, web.BuildErrorResponse(web.NewError(web.UnsupportedVersion, err.Error())))
+		return
+	}
+
+	codec := c.GetCodec()
+	if len(hello.Encodings) > 0 {
+		codec, err = c.negotiateEncoding(hello.Encodings)
+		if err != nil {
+			c.reply(messageType, req, web.BuildErrorResponse(web.NewError(web.UnsupportedEncoding, err.Error())))
+			return
+		}
+	}
+
+	c.mu.Lock()
+	c.version = version
+	c.mu.Unlock()
+
+	c.reply(messageType, req, web.BuildResponse(web.Hello,
+		"Protocol version negotiated.",
+		map[string]interface{}{"version": version, "encoding": codec.Name()}))
+
+	c.mu.Lock()
+	c.codec = codec
+	c.mu.Unlock()
+}
+
+//negotiateEncoding returns the codec of the first encoding preferred by the client which can be carried
+//by the wrapped connection. Text only connections can carry only JSON.
+func (c *ProtocolConnection) negotiateEncoding(encodings []string) (web.Codec, error) {
+	if !c.textOnly {
+		return web.NegotiateEncoding(encodings)
+	}
+	for _, name := range encodings {
+		if name == web.JSON {
+			return web.DefaultCodec(), nil
+		}
+	}
+	return nil, fmt.Errorf("none of the encodings %v is supported by this transport", encodings)
+}
+
+func (c *ProtocolConnection) reply(messageType int, req web.Request, resp web.Response) {
+	resp.RequestId = req.GetRequestId()
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	_ = c.WriteMessage(messageType, data)
+}
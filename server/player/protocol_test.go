@@ -0,0 +1,205 @@
+package player
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/StanislavStefanov/Battleships/server/player/automock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestProtocolConnection(t *testing.T) {
+	t.Run("pass version 1 messages as they are", func(t *testing.T) {
+		// when
+		request := []byte(`{"playerId":"id","action":"ls-rooms","args":null}`)
+		response := []byte(`{"action":"info","message":"","args":null}`)
+
+		conn := &automock.Connection{}
+		conn.On("ReadMessage").Return(2, request, nil).Once()
+		conn.On("WriteMessage", 2, response).Return(nil).Once()
+
+		c := NewProtocolConnection(conn)
+
+		// then
+		_, data, err := c.ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, request, data)
+		assert.Nil(t, c.WriteMessage(2, response))
+		conn.AssertExpectations(t)
+	})
+	t.Run("negotiate version 2 and translate messages", func(t *testing.T) {
+		// when
+		hello := []byte(`{"v":2,"type":"hello","payload":{"versions":[1,2]}}`)
+		helloResp, _ := web.EncodeResponse(web.BuildResponse(web.Hello, "Protocol version negotiated.", map[string]interface{}{"version": 2, "encoding": web.JSON}), web.ProtocolV2)
+		shoot := []byte(`{"v":2,"type":"shoot","playerId":"id","payload":{"x":1,"y":2}}`)
+		wait, _ := json.Marshal(web.BuildResponse(pkg.Wait, "wait", nil))
+		waitResp, _ := web.EncodeResponse(web.BuildResponse(pkg.Wait, "wait", nil), web.ProtocolV2)
+
+		conn := &automock.Connection{}
+		conn.On("ReadMessage").Return(2, hello, nil).Once()
+		conn.On("WriteMessage", 2, helloResp).Return(nil).Once()
+		conn.On("ReadMessage").Return(2, shoot, nil).Once()
+		conn.On("WriteMessage", 2, waitResp).Return(nil).Once()
+
+		c := NewProtocolConnection(conn)
+
+		// then
+		_, data, err := c.ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, web.ProtocolV2, c.GetVersion())
+
+		var req web.Request
+		assert.Nil(t, json.Unmarshal(data, &req))
+		assert.Equal(t, web.BuildRequest("id", pkg.Shoot, map[string]interface{}{"x": float64(1), "y": float64(2)}), req)
+
+		assert.Nil(t, c.WriteMessage(2, wait))
+		conn.AssertExpectations(t)
+	})
+	t.Run("reject version 2 message with invalid payload", func(t *testing.T) {
+		// when
+		invalid := []byte(`{"v":2,"type":"join-room","payload":{"roomId":1}}`)
+		retry, _ := json.Marshal(web.BuildErrorResponse(web.NewError(web.InvalidArgument,
+			"invalid payload for join-room: json: cannot unmarshal number into Go struct field JoinRoomPayload.roomId of type string")))
+
+		conn := &automock.Connection{}
+		conn.On("ReadMessage").Return(2, invalid, nil).Once()
+		conn.On("WriteMessage", 2, retry).Return(nil).Once()
+		conn.On("ReadMessage").Return(0, nil, errors.New("read failure")).Once()
+
+		c := NewProtocolConnection(conn)
+
+		// then
+		_, _, err := c.ReadMessage()
+		assert.EqualError(t, err, "read failure")
+		conn.AssertExpectations(t)
+	})
+	t.Run("echo request id in hello response", func(t *testing.T) {
+		// when
+		hello := []byte(`{"v":2,"type":"hello","requestId":"1","payload":{"versions":[2]}}`)
+		resp := web.BuildResponse(web.Hello, "Protocol version negotiated.", map[string]interface{}{"version": 2, "encoding": web.JSON})
+		resp.RequestId = "1"
+		helloResp, _ := web.EncodeResponse(resp, web.ProtocolV2)
+
+		conn := &automock.Connection{}
+		conn.On("ReadMessage").Return(2, hello, nil).Once()
+		conn.On("WriteMessage", 2, helloResp).Return(nil).Once()
+		conn.On("ReadMessage").Return(0, nil, errors.New("read failure")).Once()
+
+		c := NewProtocolConnection(conn)
+
+		// then
+		_, _, err := c.ReadMessage()
+		assert.EqualError(t, err, "read failure")
+		conn.AssertExpectations(t)
+	})
+	t.Run("negotiate msgpack encoding and translate messages", func(t *testing.T) {
+		// when
+		codec, _ := web.GetCodec(web.Msgpack)
+		hello := []byte(`{"action":"hello","args":{"encodings":["msgpack"]}}`)
+		helloResp, _ := json.Marshal(web.BuildResponse(web.Hello, "Protocol version negotiated.", map[string]interface{}{"version": 1, "encoding": web.Msgpack}))
+		shoot := web.BuildRequest("id", pkg.Shoot, map[string]interface{}{"x": float64(1), "y": float64(2)})
+		packedShoot, _ := codec.Marshal(shoot)
+		wait := web.BuildResponse(pkg.Wait, "wait", nil)
+		waitJSON, _ := json.Marshal(wait)
+		packedWait, _ := codec.Marshal(wait)
+
+		conn := &automock.Connection{}
+		conn.On("ReadMessage").Return(2, hello, nil).Once()
+		conn.On("WriteMessage", 2, helloResp).Return(nil).Once()
+		conn.On("ReadMessage").Return(2, packedShoot, nil).Once()
+		conn.On("WriteMessage", 2, packedWait).Return(nil).Once()
+
+		c := NewProtocolConnection(conn)
+
+		// then
+		_, data, err := c.ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, web.Msgpack, c.GetCodec().Name())
+		assert.Equal(t, web.ProtocolV1, c.GetVersion())
+
+		var req web.Request
+		assert.Nil(t, json.Unmarshal(data, &req))
+		assert.Equal(t, shoot, req)
+
+		assert.Nil(t, c.WriteMessage(2, waitJSON))
+		conn.AssertExpectations(t)
+	})
+	t.Run("reject message which is not encoded with the negotiated encoding", func(t *testing.T) {
+		// when
+		codec, _ := web.GetCodec(web.Msgpack)
+		hello := []byte(`{"action":"hello","args":{"encodings":["msgpack"]}}`)
+		helloResp, _ := json.Marshal(web.BuildResponse(web.Hello, "Protocol version negotiated.", map[string]interface{}{"version": 1, "encoding": web.Msgpack}))
+		retry, _ := codec.Marshal(web.BuildErrorResponse(web.NewError(web.InvalidRequest, "request is not valid msgpack")))
+
+		conn := &automock.Connection{}
+		conn.On("ReadMessage").Return(2, hello, nil).Once()
+		conn.On("WriteMessage", 2, helloResp).Return(nil).Once()
+		conn.On("ReadMessage").Return(2, []byte{0xc1}, nil).Once()
+		conn.On("WriteMessage", 2, retry).Return(nil).Once()
+		conn.On("ReadMessage").Return(0, nil, errors.New("read failure")).Once()
+
+		c := NewProtocolConnection(conn)
+
+		// then
+		_, _, err := c.ReadMessage()
+		assert.EqualError(t, err, "read failure")
+		conn.AssertExpectations(t)
+	})
+	t.Run("negotiate only json on text connection", func(t *testing.T) {
+		// when
+		hello := []byte(`{"action":"hello","args":{"encodings":["msgpack"]}}`)
+		retry, _ := json.Marshal(web.BuildErrorResponse(web.NewError(web.UnsupportedEncoding,
+			"none of the encodings [msgpack] is supported by this transport")))
+
+		conn := &automock.Connection{}
+		conn.On("ReadMessage").Return(1, hello, nil).Once()
+		conn.On("WriteMessage", 1, retry).Return(nil).Once()
+		conn.On("ReadMessage").Return(0, nil, errors.New("read failure")).Once()
+
+		c := NewTextProtocolConnection(conn)
+
+		// then
+		_, _, err := c.ReadMessage()
+		assert.EqualError(t, err, "read failure")
+		assert.Equal(t, web.JSON, c.GetCodec().Name())
+		conn.AssertExpectations(t)
+	})
+	t.Run("keep json when no encoding is supported", func(t *testing.T) {
+		// when
+		hello := []byte(`{"action":"hello","args":{"encodings":["protobuf"]}}`)
+		retry, _ := json.Marshal(web.BuildErrorResponse(web.NewError(web.UnsupportedEncoding, "none of the encodings [protobuf] is supported")))
+
+		conn := &automock.Connection{}
+		conn.On("ReadMessage").Return(2, hello, nil).Once()
+		conn.On("WriteMessage", 2, retry).Return(nil).Once()
+		conn.On("ReadMessage").Return(0, nil, errors.New("read failure")).Once()
+
+		c := NewProtocolConnection(conn)
+
+		// then
+		_, _, err := c.ReadMessage()
+		assert.EqualError(t, err, "read failure")
+		assert.Equal(t, web.JSON, c.GetCodec().Name())
+		conn.AssertExpectations(t)
+	})
+	t.Run("keep version 1 when no version is supported", func(t *testing.T) {
+		// when
+		hello := []byte(`{"playerId":"id","action":"hello","args":{"versions":[7]}}`)
+		retry, _ := json.Marshal(web.BuildErrorResponse(web.NewError(web.UnsupportedVersion, "none of the protocol versions [7] is supported")))
+
+		conn := &automock.Connection{}
+		conn.On("ReadMessage").Return(2, hello, nil).Once()
+		conn.On("WriteMessage", 2, retry).Return(nil).Once()
+		conn.On("ReadMessage").Return(0, nil, errors.New("read failure")).Once()
+
+		c := NewProtocolConnection(conn)
+
+		// then
+		_, _, err := c.ReadMessage()
+		assert.EqualError(t, err, "read failure")
+		assert.Equal(t, web.ProtocolV1, c.GetVersion())
+		conn.AssertExpectations(t)
+	})
+}
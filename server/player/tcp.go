@@ -0,0 +1,70 @@
+package player
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"github.com/gorilla/websocket"
+	"io"
+	"net"
+)
+
+//MaxLineLength is the maximal length of a single message read from TCPConnection.
+const MaxLineLength = 64 * 1024
+
+//ErrLineTooLong is returned by TCPConnection if the client sends message longer than MaxLineLength.
+var ErrLineTooLong = errors.New("message is too long")
+
+//TCPConnection adapts raw TCP connection to Connection. Every message is a single line terminated by
+//'\n', so clients can play without websocket library, e.g. with netcat. The messages are read and
+//written as text messages.
+type TCPConnection struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+//NewTCPConnection returns TCPConnection reading and writing lines through the provided connection.
+func NewTCPConnection(conn net.Conn) *TCPConnection {
+	return &TCPConnection{
+		conn:   conn,
+		reader: bufio.NewReaderSize(conn, MaxLineLength),
+	}
+}
+
+//ReadMessage returns the next non empty line read from the connection without its line terminator.
+//If the line is longer than MaxLineLength ErrLineTooLong is returned.
+func (c *TCPConnection) ReadMessage() (int, []byte, error) {
+	for {
+		line, err := c.reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			return 0, nil, ErrLineTooLong
+		}
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return 0, nil, err
+		}
+
+		line = bytes.TrimRight(line, "\r\n")
+		if len(bytes.TrimSpace(line)) > 0 {
+			message := make([]byte, len(line))
+			copy(message, line)
+			return websocket.TextMessage, message, nil
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+}
+
+//WriteMessage writes the message to the connection as a single line. The message type is ignored.
+func (c *TCPConnection) WriteMessage(_ int, data []byte) error {
+	line := make([]byte, 0, len(data)+1)
+	line = append(line, data...)
+	line = append(line, '\n')
+	_, err := c.conn.Write(line)
+	return err
+}
+
+//Close closes the underlying TCP connection.
+func (c *TCPConnection) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,65 @@
+package player
+
+import (
+	"bytes"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestTCPConnection(t *testing.T) {
+	t.Run("read messages line by line", func(t *testing.T) {
+		// when
+		server, client := net.Pipe()
+		c := NewTCPConnection(server)
+		go func() {
+			_, _ = client.Write([]byte("{\"action\":\"exit\"}\r\n\n  \n{\"action\":\"chat\"}"))
+			_ = client.Close()
+		}()
+
+		// then
+		messageType, data, err := c.ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, websocket.TextMessage, messageType)
+		assert.Equal(t, `{"action":"exit"}`, string(data))
+
+		_, data, err = c.ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, `{"action":"chat"}`, string(data))
+
+		_, _, err = c.ReadMessage()
+		assert.Equal(t, io.EOF, err)
+	})
+	t.Run("fail when line is too long", func(t *testing.T) {
+		// when
+		server, client := net.Pipe()
+		c := NewTCPConnection(server)
+		go func() {
+			_, _ = client.Write(bytes.Repeat([]byte("a"), MaxLineLength+1))
+			_ = client.Close()
+		}()
+
+		// then
+		_, _, err := c.ReadMessage()
+		assert.Equal(t, ErrLineTooLong, err)
+		assert.Nil(t, c.Close())
+	})
+	t.Run("write message as single line", func(t *testing.T) {
+		// when
+		server, client := net.Pipe()
+		c := NewTCPConnection(server)
+		received := make(chan []byte)
+		go func() {
+			buf := make([]byte, 64)
+			n, _ := client.Read(buf)
+			received <- buf[:n]
+		}()
+
+		// then
+		assert.Nil(t, c.WriteMessage(websocket.BinaryMessage, []byte(`{"action":"wait"}`)))
+		assert.Equal(t, "{\"action\":\"wait\"}\n", string(<-received))
+		assert.Nil(t, c.Close())
+	})
+}
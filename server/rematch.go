@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"time"
+)
+
+//offerRematch is called after the game ends. The players stay connected to the room and both of them
+//are offered a rematch. The loser is set as current player, so he will make the first move if the
+//rematch is accepted.
+func (r *Room) offerRematch() {
+	r.Phase = pkg.Rematch
+	r.rematch = make(map[string]bool)
+	r.switchPlayers()
+
+	resp := web.BuildResponse(pkg.Rematch, "Do you want a rematch?", nil)
+	r.Sender.SendResponse(resp, r.Current.Conn)
+	r.Sender.SendResponse(resp, r.Next.Conn)
+}
+
+//processRematch processes the requests sent after the game has ended. The players answer the offer
+//through requests with action "rematch" and optional arg accept(bool), which is true by default.
+//When both players accept the game is started again. If one of the players declines or exits the
+//room the other one is notified and message is passed through the room's done channel, after which
+//both players are handed back to the lobby. Any other action is rejected with Response with status
+//Retry.
+func (r *Room) processRematch(request web.Request) {
+	p, opponent := r.getPlayers(request.GetId())
+	if p == nil {
+		return
+	}
+
+	switch request.GetAction() {
+	case pkg.Exit:
+		r.leftId = p.Id
+		resp := web.BuildResponse(pkg.Info, "Your opponent left the room. The rematch is cancelled.", nil)
+		r.Sender.SendResponse(resp, opponent.Conn)
+		r.finish()
+	case pkg.Rematch:
+		var payload web.RematchPayload
+		if err := web.DecodeArgs(request, &payload); err != nil {
+			resp := buildErrorResponse(err)
+			r.Sender.SendResponse(resp, p.Conn)
+			return
+		}
+		if payload.Accept != nil && !*payload.Accept {
+			resp := web.BuildResponse(pkg.Info, fmt.Sprintf("%s declined the rematch.", p.GetName()), nil)
+			r.Sender.SendResponse(resp, p.Conn)
+			r.Sender.SendResponse(resp, opponent.Conn)
+			r.finish()
+			return
+		}
+
+		r.rematch[p.Id] = true
+		if !r.rematch[opponent.Id] {
+			resp := web.BuildResponse(pkg.Wait, "Wait for your opponent to accept the rematch.", nil)
+			r.Sender.SendResponse(resp, p.Conn)
+			return
+		}
+		r.restart()
+	default:
+		resp := web.BuildErrorResponse(web.NewError(web.WrongPhase,
+			fmt.Sprintf("Invalid action during Phase: %s.", r.Phase)))
+		r.Sender.SendResponse(resp, p.Conn)
+	}
+}
+
+//restart clears the boards of the players and starts the placement of the ships again. The current
+//player, who has lost the previous game, places his ship first.
+func (r *Room) restart() {
+	r.Current.Board = game.InitBoard()
+	r.Next.Board = game.InitBoard()
+	r.ApplyRules(r.Rules)
+	r.delayed = nil
+	r.rematch = nil
+	r.Phase = pkg.PlaceShip
+	r.startedAt = time.Now()
+	r.resetStats()
+
+	resp := web.BuildResponse(pkg.Wait, "Rematch accepted. Wait for your opponent to make his turn.", nil)
+	r.Sender.SendResponse(resp, r.Next.Conn)
+
+	resp = placeShipResponse(r.Placement.NextShip())
+	r.Sender.SendResponse(resp, r.Current.Conn)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/StanislavStefanov/Battleships/server/automock"
+	"github.com/StanislavStefanov/Battleships/server/player"
+	connection "github.com/StanislavStefanov/Battleships/server/player/automock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestRoom_ProcessRematch(t *testing.T) {
+	t.Run("restart the game when both players accept", func(t *testing.T) {
+		// when
+		loserConn := &connection.Connection{}
+		winnerConn := &connection.Connection{}
+
+		waitOpponent := web.BuildResponse(pkg.Wait, "Wait for your opponent to accept the rematch.", nil)
+		accepted := web.BuildResponse(pkg.Wait, "Rematch accepted. Wait for your opponent to make his turn.", nil)
+		place := web.BuildResponse(pkg.PlaceShip, "Select where to place ship with length 5", map[string]interface{}{"length": 5})
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", waitOpponent, winnerConn).Return(nil).Once()
+		responseSender.On("SendResponse", accepted, winnerConn).Return(nil).Once()
+		responseSender.On("SendResponse", place, loserConn).Return(nil).Once()
+
+		room := &Room{
+			Current: &player.Player{Id: "loser", Conn: loserConn, Board: getBoard()},
+			Next:    &player.Player{Id: "winner", Conn: winnerConn, Board: getBoard()},
+			Phase:   pkg.Rematch,
+			Rules:   game.DefaultRules(),
+			Sender:  responseSender,
+			rematch: map[string]bool{},
+		}
+
+		// then
+		room.ProcessCommand(web.BuildRequest("winner", pkg.Rematch, nil))
+		assert.Equal(t, pkg.Rematch, room.Phase)
+		room.ProcessCommand(web.BuildRequest("loser", pkg.Rematch, map[string]interface{}{"accept": true}))
+
+		assert.Equal(t, pkg.PlaceShip, room.Phase)
+		assert.Equal(t, "loser", room.Current.Id)
+		assert.Equal(t, game.InitBoard(), room.Current.Board)
+		assert.Equal(t, game.InitBoard(), room.Next.Board)
+		assert.Equal(t, game.NewPlacement(game.DefaultRules()), room.Placement)
+		assert.Equal(t, 1, countSent(responseSender, loserConn))
+		assert.Equal(t, 2, countSent(responseSender, winnerConn))
+	})
+	t.Run("finish when player declines", func(t *testing.T) {
+		// when
+		declined := web.BuildResponse(pkg.Info, "winner declined the rematch.", nil)
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", declined, firstConn).Return(nil).Once()
+		responseSender.On("SendResponse", declined, secondConn).Return(nil).Once()
+
+		room := &Room{
+			Current: &player.Player{Id: "loser", Conn: firstConn},
+			Next:    &player.Player{Id: "winner", Name: "winner", Conn: secondConn},
+			Phase:   pkg.Rematch,
+			Done:    make(chan struct{}, 1),
+			Sender:  responseSender,
+			rematch: map[string]bool{"loser": true},
+		}
+
+		// then
+		room.ProcessCommand(web.BuildRequest("winner", pkg.Rematch, map[string]interface{}{"accept": "false"}))
+		assert.Equal(t, 1, len(room.Done))
+		assert.Equal(t, "", room.leftId)
+		responseSender.AssertExpectations(t)
+	})
+	t.Run("finish when player exits", func(t *testing.T) {
+		// when
+		loserConn := &connection.Connection{}
+		winnerConn := &connection.Connection{}
+
+		cancelled := web.BuildResponse(pkg.Info, "Your opponent left the room. The rematch is cancelled.", nil)
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", cancelled, winnerConn).Return(nil).Once()
+
+		room := &Room{
+			Current: &player.Player{Id: "loser", Conn: loserConn},
+			Next:    &player.Player{Id: "winner", Conn: winnerConn},
+			Phase:   pkg.Rematch,
+			Done:    make(chan struct{}, 1),
+			Sender:  responseSender,
+			rematch: map[string]bool{},
+		}
+
+		// then
+		room.ProcessCommand(web.BuildRequest("loser", pkg.Exit, nil))
+		assert.Equal(t, 1, len(room.Done))
+		assert.Equal(t, "loser", room.leftId)
+		assert.Equal(t, 0, countSent(responseSender, loserConn))
+		responseSender.AssertExpectations(t)
+	})
+	t.Run("finish only once when both players decline", func(t *testing.T) {
+		// when
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", mock.Anything, mock.Anything).Return(nil)
+
+		room := &Room{
+			Current: &player.Player{Id: "loser", Name: "loser", Conn: firstConn},
+			Next:    &player.Player{Id: "winner", Name: "winner", Conn: secondConn},
+			Phase:   pkg.Rematch,
+			Done:    make(chan struct{}, 1),
+			Sender:  responseSender,
+			rematch: map[string]bool{},
+		}
+		processed := make(chan struct{})
+		go func() {
+			room.ProcessCommand(web.BuildRequest("winner", pkg.Rematch, map[string]interface{}{"accept": false}))
+			room.ProcessCommand(web.BuildRequest("loser", pkg.Rematch, map[string]interface{}{"accept": false}))
+			room.ProcessCommand(web.BuildRequest("loser", pkg.Exit, nil))
+			close(processed)
+		}()
+
+		// then
+		select {
+		case <-processed:
+		case <-time.After(time.Second):
+			t.Fatal("the room is blocked on its done channel")
+		}
+		assert.Equal(t, 1, len(room.Done))
+		assert.Equal(t, 2, len(responseSender.Calls))
+	})
+	t.Run("reject game actions", func(t *testing.T) {
+		// when
+		resp := web.BuildErrorResponse(web.NewError(web.WrongPhase, "Invalid action during Phase: rematch."))
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", resp, firstConn).Return(nil).Once()
+
+		room := &Room{
+			Current: &player.Player{Id: "loser", Conn: firstConn},
+			Next:    &player.Player{Id: "winner", Conn: secondConn},
+			Phase:   pkg.Rematch,
+			Sender:  responseSender,
+			rematch: map[string]bool{},
+		}
+
+		// then
+		room.ProcessCommand(web.BuildRequest("loser", pkg.Shoot, map[string]interface{}{"x": "1", "y": "1"}))
+		assert.Equal(t, pkg.Rematch, room.Phase)
+		responseSender.AssertExpectations(t)
+	})
+}
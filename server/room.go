@@ -1,28 +1,46 @@
 package main
 
 import (
-	"errors"
 	"fmt"
 	"github.com/StanislavStefanov/Battleships/pkg"
 	"github.com/StanislavStefanov/Battleships/pkg/game"
 	"github.com/StanislavStefanov/Battleships/pkg/web"
 	"github.com/StanislavStefanov/Battleships/server/player"
-	"strconv"
+	"sync/atomic"
+	"time"
 )
 
 type Room struct {
-	Current         *player.Player
-	Next            *player.Player
-	First           chan web.Request
-	Second          chan web.Request
-	FirstExit       chan struct{}
-	SecondExit      chan struct{}
-	Done            chan struct{}
-	Phase           string
-	ShipSizeToCount map[int]int
-	NextShipSize    int
-	Id              string
-	Sender          ResponseSender
+	Current       *player.Player
+	Next          *player.Player
+	First         chan web.Request
+	Second        chan web.Request
+	FirstExit     chan struct{}
+	SecondExit    chan struct{}
+	Done          chan struct{}
+	Phase         string
+	Placement     *game.Placement
+	Id            string
+	Sender        ResponseSender
+	Rules         game.Rules
+	Creator       string
+	CreatedAt     time.Time
+	Spectators    map[string]*Spectator
+	Watch         chan *Spectator
+	Watching      chan web.Request
+	Closed        chan struct{}
+	delayed       []web.Response
+	rematch       map[string]bool
+	leftId        string
+	disconnected  bool
+	finished      bool
+	History       *History
+	games         int
+	startedAt     time.Time
+	stats         map[string]*gameStats
+	turnStartedAt time.Time
+	info          atomic.Value
+	names         atomic.Value
 }
 
 const (
@@ -32,44 +50,58 @@ const (
 	boat       = 2
 )
 
-const (
-	destroyerCount  = 1
-	battleshipCount = 4
-	shipCount       = 6
-	boatCount       = 8
-)
-
 //CreateRoom creates and returns new room with the provided player as First to play. The second player
-// is nil until it is set through the Join method.
+// is nil until it is set through the Join method. The room is played by the default rules until
+//other rules are applied through the ApplyRules method.
 func CreateRoom(id string, player *player.Player, done chan struct{}) Room {
 	r := Room{
-		Current:         player,
-		Next:            nil,
-		First:           make(chan web.Request),
-		Second:          make(chan web.Request),
-		FirstExit:       make(chan struct{}),
-		SecondExit:      make(chan struct{}),
-		Done:            done,
-		Phase:           "wait",
-		ShipSizeToCount: map[int]int{destroyer: destroyerCount, battleship: battleshipCount, ship: shipCount, boat: boatCount},
-		NextShipSize:    destroyer,
-		Id:              id,
-		Sender:          &Sender{},
+		Current:    player,
+		Next:       nil,
+		First:      make(chan web.Request),
+		Second:     make(chan web.Request),
+		FirstExit:  make(chan struct{}),
+		SecondExit: make(chan struct{}),
+		Done:       done,
+		Phase:      "wait",
+		Id:         id,
+		Sender:     &Sender{},
+		CreatedAt:  time.Now(),
+		Spectators: make(map[string]*Spectator),
+		Watch:      make(chan *Spectator),
+		Watching:   make(chan web.Request),
+		Closed:     make(chan struct{}),
 	}
+	r.ApplyRules(game.DefaultRules())
 	fmt.Println(r)
 	return r
 }
 
+//ApplyRules sets the rules by which the room is played and prepares the placement of the fleet
+//described by them. The ships are placed in the order kept by game.Placement - from the longest to
+//the shortest one and the players take turns, starting with the current player.
+func (r *Room) ApplyRules(rules game.Rules) {
+	r.Rules = rules
+	r.Placement = game.NewPlacement(rules)
+}
+
 //Join adds second player to the room if there is free place. If the room is already full
 //an error is returned.
 func (r *Room) Join(player *player.Player) error {
 	if r.Next != nil {
-		return errors.New("room is already full")
+		return web.NewError(web.RoomFull, "room is already full")
 	}
 	r.Next = player
 	return nil
 }
 
+//placeShipResponse builds the Response asking the player to place the ship with the provided length. The
+//length is sent in args (key: "length") as well, so the clients don't have to parse the message.
+func placeShipResponse(length int) web.Response {
+	return web.BuildResponse(pkg.PlaceShip,
+		fmt.Sprintf("Select where to place ship with length %d", length),
+		map[string]interface{}{"length": length})
+}
+
 //GetRoomInfo returns the room id and the count of players currently in the room.
 //The valid counts of players and their meanings are: 1 - the second player hasn't
 //joined yet and the game hasn't started, 2 - all players are on their seats and
@@ -85,26 +117,142 @@ func (r *Room) GetRoomInfo() (string, int) {
 	return r.Id, playersCount
 }
 
+//Info returns the public description of the room published by publishInfo after the last change of
+//the room. Unlike GetInfo it is safe to call it from other goroutines than the one running the room,
+//e.g. while listing the rooms in the lobby. If nothing has been published yet the room isn't running,
+//so the description is built directly.
+func (r *Room) Info() web.RoomInfo {
+	if info, ok := r.info.Load().(web.RoomInfo); ok {
+		return info
+	}
+	return r.GetInfo()
+}
+
+//Names returns the names of the players and the spectators in the room as they were published by the
+//goroutine running the room. Like Info it is safe to call it from other goroutines. The names are used
+//to keep the names of all connected players unique.
+func (r *Room) Names() []string {
+	names, _ := r.names.Load().([]string)
+	return names
+}
+
+//publishInfo stores snapshot of the public description of the room, which is returned by Info, and of
+//the names of the players and the spectators in it, which are returned by Names. It is called by the
+//goroutine running the room after every change of the room.
+func (r *Room) publishInfo() {
+	r.info.Store(r.GetInfo())
+
+	var names []string
+	for _, p := range append([]*player.Player{r.Current, r.Next}, r.getSpectatorPlayers()...) {
+		if p != nil {
+			names = append(names, p.GetName())
+		}
+	}
+	r.names.Store(names)
+}
+
+//GetInfo returns the public description of the room which is shown to the players in the lobby. It
+//reads the room without any synchronization, so it may be called only by the goroutine running the
+//room, other goroutines use Info.
+func (r *Room) GetInfo() web.RoomInfo {
+	id, playersCount := r.GetRoomInfo()
+	return web.RoomInfo{
+		Id:         id,
+		Creator:    r.Creator,
+		Players:    playersCount,
+		Spectators: len(r.Spectators),
+		Rules:      r.Rules.Name,
+		BoardSize:  r.Rules.BoardSize,
+		Fleet:      r.Rules.Fleet,
+		CreatedAt:  r.CreatedAt,
+		Phase:      r.getPhaseInfo(),
+	}
+}
+
+//replyTo replaces the sender of the room with replySender for the request made through the provided
+//connection. The returned function acknowledges the request and restores the sender. The room processes
+//its requests one by one, so the replySender is never shared between two requests.
+func (r *Room) replyTo(conn player.Connection, request web.Request) func() {
+	sender := r.Sender
+	reply := newReplySender(sender, conn, request)
+	r.Sender = reply
+	return func() {
+		reply.Acknowledge()
+		r.Sender = sender
+	}
+}
+
+func (r *Room) getPhaseInfo() string {
+	switch r.Phase {
+	case pkg.PlaceShip:
+		return pkg.Placing
+	case pkg.Shoot:
+		return pkg.Shooting
+	default:
+		return pkg.Waiting
+	}
+}
+
 //ProcessCommand checks some preconditions before processing the request. If the request
 //is not from the player whose turn it is it will be rejected and Response with status Wait
-//will be sent back. Exception is if the Request action is Exit. Then the player will leave
+//and code NotYourTurn will be sent back. Exception is if the Request action is Exit. Then the player will leave
 //the room and its opponent will be notified. If the request is made from the player on turn
 //but the request action doesn't match the room's Phase the request will be rejected and
 //Response with status Retry will be sent back. Exception is if the Request action is Exit.
 //If the preconditions are met then the request is processed according to it's action. The
 //allowed actions are: place, shoot, exit. If the request action is Exit message is passed
-//through the room's done channel as notification about the event.
+//through the room's done channel as notification about the event and the opponent wins the game, he is
+//sent the same summary of the game as when the last ship is sunk. Chat, mute and state requests
+//are processed regardless of whose turn it is. After the game ends the requests are processed
+//by processRematch. Request with action Disconnect is processed as Exit, but the player who
+//has sent it is not handed back to the lobby. The responses sent to the player who has made
+//the request carry its id and the request is acknowledged if the player has asked for it. Once
+//the room is finished the requests which are still queued are ignored.
 func (r *Room) ProcessCommand(request web.Request) {
+	if r.finished {
+		return
+	}
+	if request.GetAction() == pkg.Disconnect {
+		r.disconnected = true
+		request.Action = pkg.Exit
+	}
+	if p, _ := r.getPlayers(request.GetId()); p != nil {
+		defer r.replyTo(p.Conn, request)()
+	}
+
+	switch request.GetAction() {
+	case pkg.Chat:
+		r.processChat(request)
+		return
+	case pkg.Mute:
+		if p, _ := r.getPlayers(request.GetId()); p != nil {
+			processMute(p, request, r.Sender)
+		}
+		return
+	case pkg.State:
+		if p, _ := r.getPlayers(request.GetId()); p != nil {
+			r.processState(p)
+		}
+		return
+	}
+
+	if r.Phase == pkg.Rematch {
+		r.processRematch(request)
+		return
+	}
 
 	id := request.GetId()
 	if id != r.Current.Id {
 		var resp web.Response
 		if request.GetAction() == pkg.Exit {
-			resp = web.BuildResponse(pkg.Win, "Your opponent exited the game. Congratulations, you win!", nil)
+			r.leftId = id
+			resp = web.BuildResponse(pkg.Win, "Your opponent exited the game. Congratulations, you win!", r.buildSummary(r.Current, r.Next))
 			r.Sender.SendResponse(resp, r.Current.Conn)
-			r.Done <- struct{}{}
+			r.broadcastResult(r.Current, r.Next)
+			r.finish()
 		} else {
 			resp = web.BuildResponse(pkg.Wait, "Wait for enemy to make his turn.", nil)
+			resp.Code = web.NotYourTurn
 			r.Sender.SendResponse(resp, r.Next.Conn)
 		}
 		return
@@ -112,37 +260,58 @@ func (r *Room) ProcessCommand(request web.Request) {
 
 	action := request.GetAction()
 	if action != r.Phase && action != pkg.Exit {
-		resp := web.BuildResponse(pkg.Retry,
-			fmt.Sprintf("Invalid action during Phase: %s.", r.Phase),
-			nil)
+		resp := web.BuildErrorResponse(web.NewError(web.WrongPhase,
+			fmt.Sprintf("Invalid action during Phase: %s.", r.Phase)))
 		r.Sender.SendResponse(resp, r.Current.Conn)
 		return
 	}
 
 	switch action {
 	case pkg.PlaceShip:
-		r.processShipPlacement(request)
+		var payload web.PlacePayload
+		if err := web.DecodeArgs(request, &payload); err != nil {
+			r.Sender.SendResponse(buildErrorResponse(err), r.Current.Conn)
+			return
+		}
+		r.processShipPlacement(payload)
 	case pkg.Shoot:
-		r.processShoot(request)
+		var payload web.ShootPayload
+		if err := web.DecodeArgs(request, &payload); err != nil {
+			r.Sender.SendResponse(buildErrorResponse(err), r.Current.Conn)
+			return
+		}
+		r.processShoot(payload)
 	case pkg.Exit:
+		r.leftId = id
 		if r.Next != nil {
-			resp := web.BuildResponse(pkg.Win, "Your opponent exited the game. Congratulations, you win!", nil)
+			resp := web.BuildResponse(pkg.Win, "Your opponent exited the game. Congratulations, you win!", r.buildSummary(r.Next, r.Current))
 			r.Sender.SendResponse(resp, r.Next.Conn)
+			r.broadcastResult(r.Next, r.Current)
 		}
-		r.Done <- struct{}{}
+		r.finish()
 	}
 }
 
+//finish passes message through the room's done channel, so the room is released. The room may
+//be finished only once - the done channel is drained by the goroutine running the room, which is
+//the one calling finish, so the second message would block it forever.
+func (r *Room) finish() {
+	if r.finished {
+		return
+	}
+	r.finished = true
+	r.Done <- struct{}{}
+}
+
 //processShipPlacement processes requests with action "place". Response with status "placed"
 //and args containing info about the placed ship(keys: x, y, direction, length) is returned
 //to the player who sent the request and response with action "place" is sent to the next player.
 //If the next player has already placed all of his ships his response has action "shoot".
-//If the method fails to retrieve the ship from the request response wit status "retry" is sent
-//to the player who sent the request.
-func (r *Room) processShipPlacement(request web.Request) {
-	ship, err := r.placeShip(request)
+//If the ship can't be placed response wit status "retry" is sent to the player who sent the request.
+func (r *Room) processShipPlacement(payload web.PlacePayload) {
+	ship, err := r.placeShip(payload)
 	if err != nil {
-		resp := web.BuildResponse(pkg.Retry, err.Error(), nil)
+		resp := buildErrorResponse(err)
 		r.Sender.SendResponse(resp, r.Current.Conn)
 		return
 	}
@@ -151,189 +320,123 @@ func (r *Room) processShipPlacement(request web.Request) {
 		"Ship placed successfully. Wait for opponent to make his turn.",
 		map[string]interface{}{"x": ship.GetX(), "y": ship.GetY(), "direction": ship.GetDirection(), "length": ship.GetLength()})
 	r.Sender.SendResponse(resp, r.Current.Conn)
+	r.endTurn()
 
-	length, err := r.getNextShipSize()
-	if err != nil {
+	r.Placement.ShipPlaced()
+	if r.Placement.Done() {
 		r.Phase = pkg.Shoot
 		response := web.BuildResponse(pkg.Shoot, "Select filed to attack.", nil)
 		r.Sender.SendResponse(response, r.Next.Conn)
 		r.switchPlayers()
+		r.broadcastTurn()
 		return
 	}
 
-	resp = web.BuildResponse(pkg.PlaceShip,
-		fmt.Sprintf("Select where to place ship with length %d", length),
-		nil)
+	resp = placeShipResponse(r.Placement.NextShip())
 	r.Sender.SendResponse(resp, r.Next.Conn)
 
 	r.switchPlayers()
 }
 
-func (r *Room) placeShip(req web.Request) (*game.Ship, error) {
-	ship, err := getShip(req)
-	if err != nil {
-		return nil, err
-	}
-
-	ship.SetLength(r.NextShipSize)
-
-	return ship, r.Current.PlaceShip(*ship)
-}
-
-func getShip(req web.Request) (*game.Ship, error) {
-	args := req.GetArgs()
-
-	x, err := extractIntFromArgs("x", args)
-	if err != nil {
-		return nil, err
-	}
-
-	y, err := extractIntFromArgs("y", args)
-	if err != nil {
-		return nil, err
-	}
-
-	direction, err := extractStringFromArgs("direction", args)
-	if err != nil {
-		return nil, err
-	}
-
-	ship := game.CreateShip(x, y, direction, 0)
-	return &ship, err
-}
-
-func (r *Room) getNextShipSize() (int, error) {
-	count := r.ShipSizeToCount[r.NextShipSize]
-	if count > 0 {
-		r.ShipSizeToCount[r.NextShipSize] = count - 1
-		return r.NextShipSize, nil
-	}
-
-	r.NextShipSize--
-	if r.NextShipSize < 2 {
-		return 0, errors.New("all ships already placed")
-	}
-	count = r.ShipSizeToCount[r.NextShipSize]
-	r.ShipSizeToCount[r.NextShipSize] = count - 1
-
-	return r.NextShipSize, nil
+//placeShip places the next ship of the current player at the position and in the direction requested
+//in the payload.
+func (r *Room) placeShip(payload web.PlacePayload) (*game.Ship, error) {
+	ship := game.CreateShip(payload.X, payload.Y, payload.Direction, r.Placement.NextShip())
+	return &ship, r.Current.PlaceShip(ship)
 }
 
 //processShoot processes requests with action "shoot". Response with status "shoot outcome"
 //is returned to the player who sent the request and response with action "shoot" is sent to
 //the next player. Both responses have args containing info about the shot ship(keys: hit -
 //true if enemy ship is hit and false if not, sunk - true if all fields of the hit enemy ship
-//are destroyed and false if not, x, y - coordinates that were targeted by the shoot).
-//If the method fails to retrieve the coordinates from the request or an error occurs while
-//shooting response with status "retry" is sent to the player who sent the request. If all
-//of the enemy fields are already hit requestwith status "win" is returned to the player who
-//sent the request and response with action "shoot" is sent to the next player.
-func (r *Room) processShoot(request web.Request) {
-	position, err := getPosition(request)
+//are destroyed and false if not, x, y - coordinates that were targeted by the shoot). When the
+//ship is sunk the args contain also its class, all its fields (cells) and the fields around it
+//(water), which are marked as misses on both boards, as there can't be any ship.
+//Every response carries also the tally of the ships of the enemy of its recipient by class (fleet).
+//If an error occurs while shooting response with status "retry" is sent to the player who
+//sent the request. If all
+//of the enemy fields are already hit the response with status "shoot outcome" and the final
+//tally is followed by response with status "win" sent to the player who sent the request,
+//response with status "lose" is sent to the next player and both players are offered a rematch. Both responses reveal the boards of both players together with their
+//stats (keys: winner, loser).
+func (r *Room) processShoot(payload web.ShootPayload) {
+	position := game.Position{X: payload.X, Y: payload.Y}
+	shot, err := game.Fire(r.Current.Board, r.Next.Board, position)
 	if err != nil {
-		resp := web.BuildResponse(pkg.Retry, err.Error(), nil)
+		resp := buildErrorResponse(err)
 		r.Sender.SendResponse(resp, r.Current.Conn)
 		return
 	}
+	r.broadcastShot(position, shot.Hit, shot.Sunk)
+	r.recordShot(shot.Hit)
+	r.endTurn()
 
-	success, sunk, err := r.shootAtField(*position)
-	if err != nil {
-		resp := web.BuildResponse(pkg.Retry, err.Error(), nil)
-		r.Sender.SendResponse(resp, r.Current.Conn)
-		return
+	args := make(map[string]interface{})
+	args["hit"] = shot.Hit
+	args["sunk"] = shot.Sunk
+	args["x"] = position.X
+	args["y"] = position.Y
+	if shot.Sunk {
+		args["class"] = game.ShipClass(len(shot.Ship))
+		args["cells"] = toCells(shot.Ship)
+		args["water"] = toCells(shot.Water)
 	}
 
-	if success && r.Next.Board.IsBeaten() {
-		resp := web.BuildResponse(pkg.Win, "Congratulations, you win!", nil)
+	outcome := withFleet(args, r.Next.Board)
+	resp := web.BuildResponse(pkg.ShootOutcome, "", outcome)
+	r.Sender.SendResponse(resp, r.Current.Conn)
+
+	if shot.Won {
+		summary := r.buildSummary(r.Current, r.Next)
+		resp = web.BuildResponse(pkg.Win, "Congratulations, you win!", summary)
 		r.Sender.SendResponse(resp, r.Current.Conn)
 
-		resp = web.BuildResponse(pkg.Lose, "Defeat!", nil)
+		resp = web.BuildResponse(pkg.Lose, "Defeat!", summary)
 		r.Sender.SendResponse(resp, r.Next.Conn)
+		r.broadcastResult(r.Current, r.Next)
 
-		r.Done <- struct{}{}
+		r.offerRematch()
 		return
 	}
 
-	args := make(map[string]interface{})
-	args["hit"] = success
-	args["sunk"] = sunk
-	args["x"] = position.X
-	args["y"] = position.Y
-
-	resp := web.BuildResponse(pkg.ShootOutcome, "", args)
-	r.Sender.SendResponse(resp, r.Current.Conn)
-
-	resp = web.BuildResponse(pkg.Shoot, "Select filed to attack.", args)
+	prompt := withFleet(args, r.Current.Board)
+	resp = web.BuildResponse(pkg.Shoot, "Select filed to attack.", prompt)
 	r.Sender.SendResponse(resp, r.Next.Conn)
 
 	r.switchPlayers()
-
 }
 
-func (r *Room) shootAtField(position game.Position) (bool, bool, error) {
-	success, sunk, err := r.Next.Board.ReceiveAttack(position)
-	if err != nil {
-		return false, false, err
+//toCells converts the positions on the board to the cells sent to the clients.
+func toCells(positions []game.Position) []web.Cell {
+	cells := make([]web.Cell, 0, len(positions))
+	for _, p := range positions {
+		cells = append(cells, web.Cell{X: p.X, Y: p.Y})
 	}
-
-	r.Current.Board.Attack(position, success)
-	return success, sunk, nil
+	return cells
 }
 
-func (r *Room) switchPlayers() {
-	p := r.Next
-	r.Next = r.Current
-	r.Current = p
-}
-
-func (r *Room) closeRoom() {
-	_ = r.Current.Conn.Close()
-	if r.Next != nil {
-		_ = r.Next.Conn.Close()
+//withFleet returns copy of the args of the shot with the tally of the ships on the board of the enemy
+//of the player who receives them.
+func withFleet(args map[string]interface{}, enemy *game.Board) map[string]interface{} {
+	withFleet := make(map[string]interface{}, len(args)+1)
+	for key, value := range args {
+		withFleet[key] = value
 	}
+	withFleet["fleet"] = toFleet(enemy.Tally())
+	return withFleet
 }
 
-func getPosition(req web.Request) (*game.Position, error) {
-	args := req.GetArgs()
-
-	x, err := extractIntFromArgs("x", args)
-	if err != nil {
-		return nil, err
-	}
-
-	y, err := extractIntFromArgs("y", args)
-	if err != nil {
-		return nil, err
+//toFleet converts the tally of the ships to the fleet sent to the clients.
+func toFleet(tally []game.ClassTally) []web.FleetClass {
+	fleet := make([]web.FleetClass, 0, len(tally))
+	for _, class := range tally {
+		fleet = append(fleet, web.FleetClass{Class: class.Class, Length: class.Length, Afloat: class.Afloat, Sunk: class.Sunk})
 	}
-
-	return &game.Position{
-		X: x,
-		Y: y,
-	}, nil
-}
-
-func extractIntFromArgs(key string, args map[string]interface{}) (int, error) {
-	v, ok := args[key]
-	if !ok {
-		return 0, errors.New(fmt.Sprintf("missing value for %s", key))
-	}
-	value, err := strconv.Atoi(v.(string))
-	if err != nil {
-		return 0, errors.New(fmt.Sprintf("invalid value for %s", key))
-	}
-
-	return value, nil
+	return fleet
 }
 
-func extractStringFromArgs(key string, args map[string]interface{}) (string, error) {
-	v, ok := args[key]
-	if !ok {
-		return "", errors.New(fmt.Sprintf("missing value for %s", key))
-	}
-	value, ok := v.(string)
-	if !ok {
-		return "", errors.New(fmt.Sprintf("invalid value for %s", key))
-	}
-
-	return value, nil
+func (r *Room) switchPlayers() {
+	p := r.Next
+	r.Next = r.Current
+	r.Current = p
 }
@@ -8,33 +8,10 @@ import (
 	"github.com/StanislavStefanov/Battleships/server/player"
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"testing"
 )
 
-func TestRoom_GetNextShipSize(t *testing.T) {
-	t.Run("get all ship sizes", func(t *testing.T) {
-		// when
-		room := CreateRoom("", nil, nil)
-		expectedSizes := []int{5, 4, 4, 4, 4, 3, 3, 3, 3, 3, 3, 2, 2, 2, 2, 2, 2, 2, 2}
-		// then
-		for i := 0; i < 19; i++ {
-			size, err := room.getNextShipSize()
-			assert.NoError(t, err)
-			assert.Equal(t, expectedSizes[i], size)
-		}
-	})
-
-	t.Run("fail, all ships already placed", func(t *testing.T) {
-		// when
-		room := CreateRoom("", nil, nil)
-		room.ShipSizeToCount = map[int]int{2: 0}
-		room.NextShipSize = 2
-		// then
-		_, err := room.getNextShipSize()
-		assert.Equal(t, "all ships already placed", err.Error())
-	})
-}
-
 func TestRoom_Join(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		// when
@@ -96,6 +73,28 @@ func TestRoom_GetRoomInfo(t *testing.T) {
 	})
 }
 
+func TestRoom_Info(t *testing.T) {
+	t.Run("build the info before the room is published", func(t *testing.T) {
+		// when
+		room := CreateRoom("room-id", &player.Player{}, nil)
+
+		// then
+		assert.Equal(t, 1, room.Info().Players)
+	})
+	t.Run("return the published info until the next change is published", func(t *testing.T) {
+		// when
+		room := CreateRoom("room-id", &player.Player{}, nil)
+		room.publishInfo()
+		room.Next = &player.Player{}
+		before := room.Info()
+		room.publishInfo()
+
+		// then
+		assert.Equal(t, 1, before.Players)
+		assert.Equal(t, 2, room.Info().Players)
+	})
+}
+
 var firstConn = &websocket.Conn{}
 var secondConn = &websocket.Conn{}
 
@@ -113,42 +112,101 @@ func getBoardWithOneTakenField(x, y int) *game.Board {
 	return b
 }
 
+//getBoardWithHitBoat returns the board returned by getBoard with additional boat at A0-A1, whose
+//field A0 is already hit.
+func getBoardWithHitBoat() *game.Board {
+	b := getBoard()
+	b.PlaceShip(game.CreateShip(0, 0, "right", 2))
+	b.ReceiveAttack(game.Position{X: 0, Y: 0})
+	return b
+}
+
+//getBeatenBoard returns the board returned by getBoardWithOneTakenField(3, 3) after its only
+//field is shot, so the water around the sunk ship is marked as well.
+func getBeatenBoard() *game.Board {
+	b := getBoardWithOneTakenField(3, 3)
+	game.Fire(game.InitBoard(), b, game.Position{X: 3, Y: 3})
+	return b
+}
+
+func getBoardWithEnemyHit(x, y int) *game.Board {
+	b := game.InitBoard()
+	b.Attack(game.Position{X: x, Y: y}, true)
+	return b
+}
+
+//lastShipPlacement returns the placement of the default fleet in which only the last boat is not placed yet.
+func lastShipPlacement() *game.Placement {
+	placement := game.NewPlacement(game.DefaultRules())
+	for i := 0; i < 2*len(game.DefaultRules().PlacementOrder())-1; i++ {
+		placement.ShipPlaced()
+	}
+	return placement
+}
+
+//sunkArgs returns the args of the shot which sinks the boat returned by getBoardWithHitBoat with the
+//tally of the enemy fleet of the recipient.
+func sunkArgs(fleet []web.FleetClass) map[string]interface{} {
+	return map[string]interface{}{
+		"hit":   true,
+		"sunk":  true,
+		"x":     0,
+		"y":     1,
+		"class": "boat",
+		"cells": []web.Cell{{X: 0, Y: 0}, {X: 0, Y: 1}},
+		"water": []web.Cell{{X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 2}},
+		"fleet": fleet,
+	}
+}
+
 func TestShip_ProcessCommand(t *testing.T) {
 	var (
 		waitResp = web.Response{
 			Action:  pkg.Wait,
 			Message: "Wait for enemy to make his turn.",
 			Args:    nil,
+			Code:    web.NotYourTurn,
+		}
+
+		rematchResp = web.Response{
+			Action:  pkg.Rematch,
+			Message: "Do you want a rematch?",
+			Args:    nil,
 		}
 
 		invalidActionResp = web.Response{
 			Action:  pkg.Retry,
 			Message: "Invalid action during Phase: place.",
 			Args:    nil,
+			Code:    web.WrongPhase,
 		}
 
 		missingXResp = web.Response{
 			Action:  pkg.Retry,
 			Message: "missing value for x",
 			Args:    nil,
+			Code:    web.MissingArgument,
 		}
 
 		incorrectValueTypeResp = web.Response{
 			Action:  pkg.Retry,
 			Message: "invalid value for y",
 			Args:    nil,
+			Code:    web.InvalidArgument,
 		}
 
 		missingValueForDirectionResp = web.Response{
 			Action:  pkg.Retry,
 			Message: "missing value for direction",
 			Args:    nil,
+			Code:    web.MissingArgument,
 		}
 
 		incorrectValueTypeForDirectionResp = web.Response{
 			Action:  pkg.Retry,
 			Message: "invalid value for direction",
 			Args:    nil,
+			Code:    web.InvalidArgument,
 		}
 
 		shipPlacedSuccessfullyResp = web.Response{
@@ -166,7 +224,7 @@ func TestShip_ProcessCommand(t *testing.T) {
 		placeShipResp = web.Response{
 			Action:  pkg.PlaceShip,
 			Message: "Select where to place ship with length 5",
-			Args:    nil,
+			Args:    map[string]interface{}{"length": 5},
 		}
 
 		shootResp = web.Response{
@@ -179,35 +237,96 @@ func TestShip_ProcessCommand(t *testing.T) {
 			Action:  pkg.Retry,
 			Message: "position out of bounds",
 			Args:    nil,
+			Code:    web.OutOfBounds,
+		}
+
+		shootAlreadyShotResp = web.Response{
+			Action:  pkg.Retry,
+			Message: "the field has already been shot",
+			Args:    nil,
+			Code:    web.AlreadyShot,
 		}
 
 		shootHitResp = web.Response{
 			Action:  pkg.ShootOutcome,
 			Message: "",
-			Args:    map[string]interface{}{"hit": true, "sunk": false, "x": 3, "y": 3},
+			Args: map[string]interface{}{"hit": true, "sunk": false, "x": 3, "y": 3,
+				"fleet": []web.FleetClass{{Class: "battleship", Length: 4, Afloat: 1}}},
 		}
 
 		shootWithArgsResp = web.Response{
 			Action:  pkg.Shoot,
 			Message: "Select filed to attack.",
-			Args:    map[string]interface{}{"hit": true, "sunk": false, "x": 3, "y": 3},
+			Args:    map[string]interface{}{"hit": true, "sunk": false, "x": 3, "y": 3, "fleet": []web.FleetClass{}},
+		}
+
+		shootSunkResp = web.Response{
+			Action:  pkg.ShootOutcome,
+			Message: "",
+			Args: sunkArgs([]web.FleetClass{
+				{Class: "battleship", Length: 4, Afloat: 1},
+				{Class: "boat", Length: 2, Sunk: 1},
+			}),
+		}
+
+		shootSunkWithArgsResp = web.Response{
+			Action:  pkg.Shoot,
+			Message: "Select filed to attack.",
+			Args:    sunkArgs([]web.FleetClass{}),
+		}
+
+		summaryArgs = map[string]interface{}{
+			"winner": web.PlayerSummary{
+				Name:          "anonymous",
+				Fields:        game.InitBoard().GetOwnFields(),
+				Shots:         1,
+				Hits:          1,
+				HitPercentage: 100,
+				LongestStreak: 1,
+				Turns:         1,
+			},
+			"loser": web.PlayerSummary{
+				Name:   "anonymous",
+				Fields: getBeatenBoard().GetOwnFields(),
+			},
+		}
+
+		winShotResp = web.Response{
+			Action:  pkg.ShootOutcome,
+			Message: "",
+			Args: map[string]interface{}{"hit": true, "sunk": true, "x": 3, "y": 3,
+				"class": "1-field ship",
+				"cells": []web.Cell{{X: 3, Y: 3}},
+				"water": []web.Cell{{X: 2, Y: 3}, {X: 4, Y: 3}, {X: 3, Y: 2}, {X: 3, Y: 4}},
+				"fleet": []web.FleetClass{{Class: "1-field ship", Length: 1, Sunk: 1}}},
 		}
 
 		winResp = web.Response{
 			Action:  pkg.Win,
 			Message: "Congratulations, you win!",
-			Args:    nil,
+			Args:    summaryArgs,
 		}
 
 		defeatResp = web.Response{
 			Action:  pkg.Lose,
 			Message: "Defeat!",
-			Args:    nil,
+			Args:    summaryArgs,
 		}
 		exitResp = web.Response{
 			Action:  pkg.Win,
 			Message: "Your opponent exited the game. Congratulations, you win!",
-			Args:    nil,
+			Args: map[string]interface{}{
+				"winner": web.PlayerSummary{Name: "anonymous"},
+				"loser":  web.PlayerSummary{Name: "anonymous", Fields: game.InitBoard().GetOwnFields()},
+			},
+		}
+		disconnectResp = web.Response{
+			Action:  pkg.Win,
+			Message: "Your opponent exited the game. Congratulations, you win!",
+			Args: map[string]interface{}{
+				"winner": web.PlayerSummary{Name: "anonymous", Fields: game.InitBoard().GetOwnFields()},
+				"loser":  web.PlayerSummary{Name: "anonymous"},
+			},
 		}
 	)
 	firstID := "first"
@@ -386,9 +505,8 @@ func TestShip_ProcessCommand(t *testing.T) {
 					Id:   secondID,
 					Conn: secondConn,
 				},
-				Phase:           pkg.PlaceShip,
-				ShipSizeToCount: map[int]int{destroyer: destroyerCount},
-				NextShipSize:    destroyer,
+				Phase:     pkg.PlaceShip,
+				Placement: game.NewPlacement(game.DefaultRules()),
 			},
 			Request: web.Request{
 				PlayerId: firstID,
@@ -417,9 +535,8 @@ func TestShip_ProcessCommand(t *testing.T) {
 					Id:   secondID,
 					Conn: secondConn,
 				},
-				Phase:           pkg.PlaceShip,
-				ShipSizeToCount: map[int]int{boat: 0},
-				NextShipSize:    boat,
+				Phase:     pkg.PlaceShip,
+				Placement: lastShipPlacement(),
 			},
 			Request: web.Request{
 				PlayerId: firstID,
@@ -513,6 +630,35 @@ func TestShip_ProcessCommand(t *testing.T) {
 				return sender
 			},
 		},
+		{
+			Name: "fail when shooting at field which has already been shot, player should keep the turn",
+			Room: &Room{
+				Current: &player.Player{
+					Id:    firstID,
+					Conn:  firstConn,
+					Board: getBoardWithEnemyHit(0, 0),
+				},
+				Next: &player.Player{
+					Id:    secondID,
+					Conn:  secondConn,
+					Board: getBoardWithHitBoat(),
+				},
+				Phase: pkg.Shoot,
+			},
+			Request: web.Request{
+				PlayerId: firstID,
+				Action:   pkg.Shoot,
+				Args:     map[string]interface{}{"x": "0", "y": "0"},
+			},
+			Phase:     pkg.Shoot,
+			CurrentID: firstID,
+			NextID:    secondID,
+			ResponseSender: func() *automock.ResponseSender {
+				sender := &automock.ResponseSender{}
+				sender.On("SendResponse", shootAlreadyShotResp, firstConn).Return(nil).Once()
+				return sender
+			},
+		},
 		{
 			Name: "success when shooting at field, next player should shoot",
 			Room: &Room{
@@ -543,6 +689,36 @@ func TestShip_ProcessCommand(t *testing.T) {
 				return sender
 			},
 		},
+		{
+			Name: "reveal the sunk ship and the water around it",
+			Room: &Room{
+				Current: &player.Player{
+					Id:    firstID,
+					Conn:  firstConn,
+					Board: getBoardWithEnemyHit(0, 0),
+				},
+				Next: &player.Player{
+					Id:    secondID,
+					Conn:  secondConn,
+					Board: getBoardWithHitBoat(),
+				},
+				Phase: pkg.Shoot,
+			},
+			Request: web.Request{
+				PlayerId: firstID,
+				Action:   pkg.Shoot,
+				Args:     map[string]interface{}{"x": "0", "y": "1"},
+			},
+			Phase:     pkg.Shoot,
+			CurrentID: secondID,
+			NextID:    firstID,
+			ResponseSender: func() *automock.ResponseSender {
+				sender := &automock.ResponseSender{}
+				sender.On("SendResponse", shootSunkResp, firstConn).Return(nil).Once()
+				sender.On("SendResponse", shootSunkWithArgsResp, secondConn).Return(nil).Once()
+				return sender
+			},
+		},
 		{
 			Name: "enemy is defeated",
 			Room: &Room{
@@ -564,13 +740,16 @@ func TestShip_ProcessCommand(t *testing.T) {
 				Action:   pkg.Shoot,
 				Args:     map[string]interface{}{"x": "3", "y": "3"},
 			},
-			Phase:     pkg.Shoot,
-			CurrentID: firstID,
-			NextID:    secondID,
+			Phase:     pkg.Rematch,
+			CurrentID: secondID,
+			NextID:    firstID,
 			ResponseSender: func() *automock.ResponseSender {
 				sender := &automock.ResponseSender{}
+				sender.On("SendResponse", winShotResp, firstConn).Return(nil).Once()
 				sender.On("SendResponse", winResp, firstConn).Return(nil).Once()
 				sender.On("SendResponse", defeatResp, secondConn).Return(nil).Once()
+				sender.On("SendResponse", rematchResp, secondConn).Return(nil).Once()
+				sender.On("SendResponse", rematchResp, firstConn).Return(nil).Once()
 				return sender
 			},
 		},
@@ -603,6 +782,35 @@ func TestShip_ProcessCommand(t *testing.T) {
 				return sender
 			},
 		},
+		{
+			Name: "opponent disconnected",
+			Room: &Room{
+				Current: &player.Player{
+					Id:    firstID,
+					Conn:  firstConn,
+					Board: game.InitBoard(),
+				},
+				Next: &player.Player{
+					Id:   secondID,
+					Conn: secondConn,
+				},
+				Phase: pkg.Shoot,
+				Done:  make(chan struct{}, 1),
+			},
+			Request: web.Request{
+				PlayerId: secondID,
+				Action:   pkg.Disconnect,
+				Args:     nil,
+			},
+			Phase:     pkg.Shoot,
+			CurrentID: firstID,
+			NextID:    secondID,
+			ResponseSender: func() *automock.ResponseSender {
+				sender := &automock.ResponseSender{}
+				sender.On("SendResponse", disconnectResp, firstConn).Return(nil).Once()
+				return sender
+			},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -620,4 +828,29 @@ func TestShip_ProcessCommand(t *testing.T) {
 			respSender.AssertExpectations(t)
 		})
 	}
+
+	t.Run("send outcome of the winning shot before the summary", func(t *testing.T) {
+		// given
+		sender := &automock.ResponseSender{}
+		sender.On("SendResponse", mock.Anything, mock.Anything).Return(nil)
+		room := &Room{
+			Current: &player.Player{Id: firstID, Conn: firstConn, Board: game.InitBoard()},
+			Next:    &player.Player{Id: secondID, Conn: secondConn, Board: getBoardWithOneTakenField(3, 3)},
+			Phase:   pkg.Shoot,
+			Done:    make(chan struct{}, 1),
+			Sender:  sender,
+		}
+
+		// when
+		room.ProcessCommand(web.Request{PlayerId: firstID, Action: pkg.Shoot, Args: map[string]interface{}{"x": "3", "y": "3"}})
+
+		// then
+		var actions []string
+		for _, call := range sender.Calls {
+			if call.Arguments.Get(1) == firstConn {
+				actions = append(actions, call.Arguments.Get(0).(web.Response).Action)
+			}
+		}
+		assert.Equal(t, []string{pkg.ShootOutcome, pkg.Win, pkg.Rematch}, actions)
+	})
 }
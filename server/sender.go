@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg"
 	"github.com/StanislavStefanov/Battleships/pkg/web"
 	"github.com/StanislavStefanov/Battleships/server/player"
 	"github.com/gorilla/websocket"
@@ -30,3 +31,40 @@ func (s *Sender) SendResponse(response web.Response, conn player.Connection) {
 		return
 	}
 }
+
+//replySender wraps ResponseSender used while a request is processed. The id of the request is set to
+//every response sent to the connection of the player who has made it. If the request asks for
+//acknowledgement and no response has been sent to the player, Acknowledge sends Response with status Ack.
+type replySender struct {
+	ResponseSender
+	conn    player.Connection
+	request web.Request
+	replied bool
+}
+
+func newReplySender(sender ResponseSender, conn player.Connection, request web.Request) *replySender {
+	return &replySender{
+		ResponseSender: sender,
+		conn:           conn,
+		request:        request,
+	}
+}
+
+//SendResponse sends the response through the wrapped sender. If the response is sent to the player who
+//has made the request it carries the id of the request.
+func (s *replySender) SendResponse(response web.Response, conn player.Connection) {
+	if s.conn != nil && conn == s.conn {
+		response.RequestId = s.request.GetRequestId()
+		s.replied = true
+	}
+	s.ResponseSender.SendResponse(response, conn)
+}
+
+//Acknowledge sends Response with status Ack to the player who has made the request if he has asked for
+//it and nothing else has been sent to him yet.
+func (s *replySender) Acknowledge() {
+	if !s.request.Ack || s.replied || s.conn == nil {
+		return
+	}
+	s.SendResponse(web.BuildResponse(pkg.Ack, "", nil), s.conn)
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"encoding/json"
 	"errors"
+	"github.com/StanislavStefanov/Battleships/pkg"
 	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/StanislavStefanov/Battleships/server/automock"
 	connection "github.com/StanislavStefanov/Battleships/server/player/automock"
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
@@ -65,3 +67,63 @@ func TestSender_SendResponse(t *testing.T) {
 		con.AssertExpectations(t)
 	})
 }
+
+func TestReplySender(t *testing.T) {
+	t.Run("set request id only to responses sent to the requester", func(t *testing.T) {
+		// when
+		requesterConn := &connection.Connection{}
+		otherConn := &connection.Connection{}
+		request := web.BuildRequest("id", pkg.Chat, nil)
+		request.RequestId = "42"
+
+		resp := web.BuildResponse(pkg.Chat, "hello", nil)
+		reply := resp
+		reply.RequestId = "42"
+
+		sender := &automock.ResponseSender{}
+		sender.On("SendResponse", reply, requesterConn).Return(nil).Once()
+		sender.On("SendResponse", resp, otherConn).Return(nil).Once()
+
+		s := newReplySender(sender, requesterConn, request)
+		s.SendResponse(resp, otherConn)
+		s.SendResponse(resp, requesterConn)
+		s.Acknowledge()
+
+		// then
+		sender.AssertExpectations(t)
+		assert.Equal(t, 1, countSent(sender, requesterConn))
+		assert.Equal(t, 1, countSent(sender, otherConn))
+	})
+	t.Run("acknowledge request without response", func(t *testing.T) {
+		// when
+		conn := &connection.Connection{}
+		request := web.BuildRequest("id", pkg.Chat, nil)
+		request.RequestId = "42"
+		request.Ack = true
+
+		ack := web.BuildResponse(pkg.Ack, "", nil)
+		ack.RequestId = "42"
+
+		sender := &automock.ResponseSender{}
+		sender.On("SendResponse", ack, conn).Return(nil).Once()
+
+		s := newReplySender(sender, conn, request)
+		s.Acknowledge()
+		s.Acknowledge()
+
+		// then
+		sender.AssertExpectations(t)
+		assert.Equal(t, 1, countSent(sender, conn))
+	})
+	t.Run("do not acknowledge request which is not asking for it", func(t *testing.T) {
+		// when
+		conn := &connection.Connection{}
+		sender := &automock.ResponseSender{}
+
+		s := newReplySender(sender, conn, web.BuildRequest("id", pkg.Chat, nil))
+		s.Acknowledge()
+
+		// then
+		assert.Equal(t, 0, countSent(sender, conn))
+	})
+}
@@ -11,19 +11,66 @@ import (
 	"github.com/gorilla/websocket"
 	"log"
 	"net/http"
+	"sort"
 	"sync"
+	"time"
 )
 
+//maxEmptyRooms is the maximal count of rooms opened through OpenRoom which may wait for their first
+//player at the same time.
+const maxEmptyRooms = 100
+
+//defaultEmptyRoomTimeout is the time after which the room opened through OpenRoom is closed if nobody
+//has joined it.
+const defaultEmptyRoomTimeout = 10 * time.Minute
+
 type Server struct {
-	clients     map[string]*player.Player
-	rooms       map[string]*Room
-	connectRoom map[string]chan *player.Player
-	register    chan *websocket.Conn
-	done        chan struct{}
-	sender      ResponseSender
+	clients          map[string]*player.Player
+	rooms            map[string]*Room
+	connectRoom      map[string]chan *player.Player
+	register         chan player.Connection
+	done             chan struct{}
+	sender           ResponseSender
+	history          *History
+	guests           int
+	emptyRoomTimeout time.Duration
+	mu               sync.RWMutex
+	roomsMu          sync.RWMutex
 	uuid.UUID
 }
 
+//NewServer returns Server without any clients and rooms. The server doesn't register any clients until
+//it is started with Start. The clients can then connect through ServeWs, ServeTCP or Connect, so the
+//server can be used without HTTP as well, e.g. embedded into other program or in tests.
+func NewServer() *Server {
+	return &Server{
+		clients:     make(map[string]*player.Player, 0),
+		rooms:       make(map[string]*Room, 0),
+		connectRoom: make(map[string]chan *player.Player, 0),
+		register:    make(chan player.Connection),
+		done:        make(chan struct{}),
+		sender:      &Sender{},
+		history:     NewHistory(),
+		UUID:        uuid.UUID{},
+
+		emptyRoomTimeout: defaultEmptyRoomTimeout,
+	}
+}
+
+//Start starts registering the connected clients in new goroutine.
+func (s *Server) Start() {
+	go s.run()
+}
+
+//Connect connects new client to the server through in-memory pipe and returns the client's end of the
+//pipe. The client is registered the same way as the websocket clients, so the first message read from
+//the pipe is the register response. The server must be started.
+func (s *Server) Connect() *player.PipeConnection {
+	serverEnd, clientEnd := player.Pipe()
+	s.register <- player.NewProtocolConnection(player.NewSyncConnection(serverEnd))
+	return clientEnd
+}
+
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
@@ -39,7 +86,7 @@ func ServeWs(s *Server, w http.ResponseWriter, r *http.Request) {
 		log.Println(err)
 		return
 	}
-	s.register <- conn
+	s.register <- player.NewProtocolConnection(player.NewSyncConnection(conn))
 }
 
 func (s *Server) run() {
@@ -55,169 +102,368 @@ func (s *Server) run() {
 }
 
 //RegisterClient wraps the provided connection into Player and stores it into the server. The PLayer
-//is assigned id(string). After the player is created the id is send back through the connection in
+//is assigned id(string) and unique name (anonymous-1, anonymous-2 and so on) until he chooses his own. After the player is created the id is send back through the connection in
 //args (key: "id") of a Response with action "register".
-func (s *Server) RegisterClient(conn *websocket.Conn) *player.Player {
+func (s *Server) RegisterClient(conn player.Connection) *player.Player {
 	playerId := uuid.New().String()
 	fmt.Printf("register %s \n", playerId)
 
 	pl := &player.Player{
-		Conn:  conn,
-		Board: game.InitBoard(),
-		Id:    playerId}
+		Conn:        conn,
+		Board:       game.InitBoard(),
+		Id:          playerId,
+		ChatLimiter: player.NewRateLimiter(chatLimit, chatWindow)}
+	s.mu.Lock()
+	s.guests++
+	pl.Name = fmt.Sprintf("%s-%d", anonymousPrefix, s.guests)
 	s.clients[playerId] = pl
+	s.mu.Unlock()
 
-	resp := web.BuildResponse("register", "Connected to server.", map[string]interface{}{"id": playerId})
+	resp := web.BuildResponse(pkg.Register, "Connected to server.", map[string]interface{}{"id": playerId})
 	s.sender.SendResponse(resp, conn)
 
 	return pl
 }
 
-//ReadLoop reads requests send by the player and calls server methods based of the action stated into the request.
-//All valid actions are: exit, ls-rooms, create-room,join-room,join-random. If there is something wrong with the
-//request or the command is not recognised by the server Response with status Retry is sent back through the connection.
+//ReadLoop reads requests send by the player and processes them through ProcessLobbyRequest until the player
+//leaves the lobby.
 func ReadLoop(player *player.Player, s *Server) {
 	for {
-		_, bytes, err := player.Conn.ReadMessage()
+		request, err := s.readRequest(player)
 		if err != nil {
 			fmt.Println("while read: ", err)
 			s.deletePlayer(player.Id)
 			return
 		}
 
-		var request web.Request
-		err = json.Unmarshal(bytes, &request)
+		if s.ProcessLobbyRequest(player, request) {
+			return
+		}
+	}
+}
+
+//readRequest reads the next request sent by the player. Requests which are not valid JSON or don't match
+//the schema of their action are answered with Response with status Retry and the code of the failure and
+//the next request is read instead. An error is returned only if reading from the connection fails.
+func (s *Server) readRequest(pl *player.Player) (web.Request, error) {
+	for {
+		_, bytes, err := pl.Conn.ReadMessage()
 		if err != nil {
-			fmt.Println("while unmarshal: ", err)
+			return web.Request{}, err
 		}
-		action := request.Action
 
-		switch action {
-		case pkg.Exit:
-			s.deletePlayer(player.Id)
-			_ = player.Conn.Close()
-			return
-		case pkg.ListRooms:
-			rooms := s.ListRooms()
-			resp := web.BuildResponse(pkg.Info, "Rooms: ", rooms)
-			s.sender.SendResponse(resp, player.Conn)
-		case pkg.CreateRoom:
-			room := s.CreateRoom(player.Id)
-			go s.RunRoom(room, s.connectRoom[room.Id])
-			return
-		case pkg.JoinRoom:
-			roomId, ok := request.Args["roomId"].(string)
-			if !ok {
-				resp := web.BuildResponse(pkg.Retry, "Invalid room ID", nil)
-				s.sender.SendResponse(resp, player.Conn)
-				continue
-			}
-			if s.JoinRoom(roomId, player) {
-				return
-			}
+		var request web.Request
+		if err = json.Unmarshal(bytes, &request); err != nil {
+			resp := web.BuildErrorResponse(web.NewError(web.InvalidRequest, "request is not valid JSON"))
+			s.sender.SendResponse(resp, pl.Conn)
+			continue
+		}
+		if validationErr := web.ValidateRequest(request); validationErr != nil {
+			resp := web.BuildErrorResponse(validationErr)
+			resp.RequestId = request.GetRequestId()
+			s.sender.SendResponse(resp, pl.Conn)
+			continue
+		}
+		return request, nil
+	}
+}
 
-		case pkg.JoinRandom:
-			if s.JoinRandomRoom(player) {
-				return
-			}
-		default:
-			resp := web.BuildResponse(pkg.Retry, "unknown", nil)
-			s.sender.SendResponse(resp, player.Conn)
+//ProcessLobbyRequest calls server methods based of the action stated into the request. All valid actions are:
+//exit, ls-rooms, create-room,join-room,join-random,spectate,chat,mute,state. If there is something wrong with the
+//request or the command is not recognised by the server Response with status Retry is sent back through the
+//connection. All responses sent to the player while the request is processed carry the id of the request and
+//if he has asked for acknowledgement and there is no other response, Response with status Ack is sent to him.
+//Returns true if the player has left the lobby, false otherwise.
+func (s *Server) ProcessLobbyRequest(player *player.Player, request web.Request) bool {
+	sender := newReplySender(s.sender, player.Conn, request)
+	defer sender.Acknowledge()
+
+	switch request.Action {
+	case pkg.Exit:
+		sender.Acknowledge()
+		s.deletePlayer(player.Id)
+		_ = player.Conn.Close()
+		return true
+	case pkg.ListRooms:
+		var payload web.ListRoomsPayload
+		if err := web.DecodeArgs(request, &payload); err != nil {
+			resp := buildErrorResponse(err)
+			sender.SendResponse(resp, player.Conn)
+			return false
+		}
+		filter, err := getRoomFilter(payload)
+		if err != nil {
+			resp := buildErrorResponse(err)
+			sender.SendResponse(resp, player.Conn)
+			return false
+		}
+		rooms, total := s.ListRooms(filter)
+		resp := web.BuildResponse(pkg.Info, "Rooms: ", map[string]interface{}{
+			"rooms":    rooms,
+			"total":    total,
+			"page":     filter.Page,
+			"pageSize": filter.PageSize,
+		})
+		sender.SendResponse(resp, player.Conn)
+	case pkg.CreateRoom:
+		var payload web.CreateRoomPayload
+		if err := web.DecodeArgs(request, &payload); err != nil {
+			resp := buildErrorResponse(err)
+			sender.SendResponse(resp, player.Conn)
+			return false
 		}
+		rules, err := getRules(payload.Rules)
+		if err != nil {
+			resp := buildErrorResponse(err)
+			sender.SendResponse(resp, player.Conn)
+			return false
+		}
+		if err := s.claimPlayerName(player, payload.Name); err != nil {
+			resp := buildErrorResponse(err)
+			sender.SendResponse(resp, player.Conn)
+			return false
+		}
+		room := s.CreateRoom(player.Id, rules)
+		go s.RunRoom(room, s.getConnectRoom(room.Id))
+		return true
+	case pkg.JoinRoom:
+		var payload web.JoinRoomPayload
+		if err := web.DecodeArgs(request, &payload); err != nil {
+			resp := buildErrorResponse(err)
+			sender.SendResponse(resp, player.Conn)
+			return false
+		}
+		if err := s.claimPlayerName(player, payload.Name); err != nil {
+			resp := buildErrorResponse(err)
+			sender.SendResponse(resp, player.Conn)
+			return false
+		}
+		return s.JoinRoom(payload.RoomId, player, sender)
+	case pkg.JoinRandom:
+		return s.JoinRandomRoom(player, sender)
+	case pkg.Chat:
+		s.LobbyChat(player, request, sender)
+	case pkg.Mute:
+		processMute(player, request, sender)
+	case pkg.State:
+		resp := web.BuildResponse(pkg.State, "", map[string]interface{}{"phase": pkg.Lobby, "turn": false})
+		sender.SendResponse(resp, player.Conn)
+	case pkg.Spectate:
+		var payload web.SpectatePayload
+		if err := web.DecodeArgs(request, &payload); err != nil {
+			resp := buildErrorResponse(err)
+			sender.SendResponse(resp, player.Conn)
+			return false
+		}
+		return s.Spectate(payload.RoomId, player, payload.Full, sender)
+	default:
+		resp := web.BuildErrorResponse(web.NewError(web.UnknownAction, "unknown"))
+		sender.SendResponse(resp, player.Conn)
 	}
+	return false
 }
 
 func (s *Server) deletePlayer(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	delete(s.clients, id)
 }
 
-//ListRooms returns structured information about the rooms. The keys value pairs of the returned map contain:
-//key = room id`s and values count of player in the room. The possible values are: 1 - there is only one player
-//in the room and tha game hasn't started yet, 2 - the room is full and the game is in progress
-func (s *Server) ListRooms() map[string]interface{} {
-	roomsInfo := make(map[string]interface{})
+//ListRooms returns the page of rooms described by the filter, ordered by their creation time, together
+//with the count of all rooms matching the filter. Each room is described by its id, creator, count of
+//players, rules, board size, fleet, creation time and phase. The possible player counts are: 1 - there
+//is only one player in the room and tha game hasn't started yet, 2 - the room is full and the game is
+//in progress.
+func (s *Server) ListRooms(filter RoomFilter) ([]web.RoomInfo, int) {
+	var matching []web.RoomInfo
+	s.roomsMu.RLock()
 	for _, r := range s.rooms {
-		name, playersCount := r.GetRoomInfo()
-		roomsInfo[name] = playersCount
+		info := r.Info()
+		if filter.Match(info) {
+			matching = append(matching, info)
+		}
+	}
+	s.roomsMu.RUnlock()
+
+	sort.Slice(matching, func(i, j int) bool {
+		if matching[i].CreatedAt.Equal(matching[j].CreatedAt) {
+			return matching[i].Id < matching[j].Id
+		}
+		return matching[i].CreatedAt.Before(matching[j].CreatedAt)
+	})
+
+	start := (filter.Page - 1) * filter.PageSize
+	if start >= len(matching) {
+		return []web.RoomInfo{}, len(matching)
 	}
-	return roomsInfo
+	end := start + filter.PageSize
+	if end > len(matching) {
+		end = len(matching)
+	}
+	return matching[start:end], len(matching)
 }
 
-//CreateRoom creates new room and sets the player corresponding to the provided id as First to play.
-//The player is removed from the list of clients stored on the server as he is already room`s responsibility.
-func (s *Server) CreateRoom(clientId string) *Room {
-	roomID := uuid.New().String()
+//CreateRoom creates new room played by the provided rules and sets the player corresponding to the
+//provided id as First to play. The player is removed from the list of clients stored on the server as
+//he is already room`s responsibility.
+func (s *Server) CreateRoom(clientId string, rules game.Rules) *Room {
+	s.mu.RLock()
 	p := s.clients[clientId]
-	room := CreateRoom(roomID, p, make(chan struct{}, 1))
-	s.rooms[roomID] = &room
-
-	connect := make(chan *player.Player)
-	s.connectRoom[roomID] = connect
+	s.mu.RUnlock()
+	room := s.addRoom(p, rules)
+	room.Creator = p.GetName()
 	s.deletePlayer(clientId)
+	return room
+}
+
+//OpenRoom creates new room played by the provided rules without any players. The room waits for two
+//players to join it and the first one who joins is First to play. It is used to create rooms from
+//outside of the lobby, e.g. through the HTTP API, so nobody is bound to the room: if there are already
+//maxEmptyRooms rooms without players error with code TooManyRooms is returned and the running room is
+//closed if nobody joins it in time (see RunRoom).
+func (s *Server) OpenRoom(rules game.Rules) (*Room, error) {
+	room := s.newRoom(nil, rules)
+
+	s.roomsMu.Lock()
+	defer s.roomsMu.Unlock()
+	empty := 0
+	for _, r := range s.rooms {
+		if r.Info().Players == 0 {
+			empty++
+		}
+	}
+	if empty >= maxEmptyRooms {
+		return nil, web.NewError(web.TooManyRooms, fmt.Sprintf("there are already %d rooms waiting for players", maxEmptyRooms))
+	}
+	s.rooms[room.Id] = room
+	s.connectRoom[room.Id] = make(chan *player.Player)
+	return room, nil
+}
+
+func (s *Server) addRoom(p *player.Player, rules game.Rules) *Room {
+	room := s.newRoom(p, rules)
+
+	s.roomsMu.Lock()
+	defer s.roomsMu.Unlock()
+	s.rooms[room.Id] = room
+	s.connectRoom[room.Id] = make(chan *player.Player)
+	return room
+}
+
+//newRoom returns room with new id played by the provided rules. The room is not stored into the server.
+func (s *Server) newRoom(p *player.Player, rules game.Rules) *Room {
+	room := CreateRoom(uuid.New().String(), p, make(chan struct{}, 1))
+	room.ApplyRules(rules)
+	room.History = s.history
+	room.publishInfo()
 	return &room
 }
 
+//getRoom returns the room with the provided id or nil if there is no such room.
+func (s *Server) getRoom(id string) *Room {
+	s.roomsMu.RLock()
+	defer s.roomsMu.RUnlock()
+	return s.rooms[id]
+}
+
+func (s *Server) getConnectRoom(id string) chan *player.Player {
+	s.roomsMu.RLock()
+	defer s.roomsMu.RUnlock()
+	return s.connectRoom[id]
+}
+
 //JoinRoom connects the player to the desired room. This will set him as Second to play and
-//will notify the First player that he can make his turn. If the room doesn't exist or if it is
-//already full the player will be notified with Response with status Retry and appropriate message.
-func (s *Server) JoinRoom(roomID string, player *player.Player) bool {
-	room := s.rooms[roomID]
+//will notify the First player that he can make his turn. If the room has no players yet the player
+//is set as First to play and waits for his opponent. If the room doesn't exist, if it is
+//already full or if it is closed before it receives the player, the player will be notified through
+//the sender with Response with status Retry and appropriate message. The count of players read here
+//may be out of date, so the seat is taken by the goroutine running the room, which rejects the player
+//if someone else has taken the last seat meanwhile.
+func (s *Server) JoinRoom(roomID string, player *player.Player, sender ResponseSender) bool {
+	room := s.getRoom(roomID)
 	if room == nil {
-		resp := web.BuildResponse(pkg.Retry, fmt.Sprintf("room with id %s doesnt exist", roomID), nil)
-		s.sender.SendResponse(resp, player.Conn)
+		resp := web.BuildErrorResponse(web.NewError(web.RoomNotFound, fmt.Sprintf("room with id %s doesnt exist", roomID)))
+		sender.SendResponse(resp, player.Conn)
 		return false
 	}
 
-	_, playersCount := room.GetRoomInfo()
-	if playersCount == 2 {
-		resp := web.BuildResponse(pkg.Retry, fmt.Sprintf("room %s is already full", roomID), nil)
-		s.sender.SendResponse(resp, player.Conn)
+	if room.Info().Players == 2 {
+		resp := web.BuildErrorResponse(web.NewError(web.RoomFull, fmt.Sprintf("room %s is already full", roomID)))
+		sender.SendResponse(resp, player.Conn)
+		return false
+	}
+
+	connect := s.getConnectRoom(roomID)
+	if connect == nil {
+		resp := web.BuildErrorResponse(web.NewError(web.RoomNotFound, fmt.Sprintf("room with id %s doesnt exist", roomID)))
+		sender.SendResponse(resp, player.Conn)
 		return false
 	}
 
-	connect := s.connectRoom[roomID]
-	connect <- player
 	s.deletePlayer(player.Id)
-	delete(s.connectRoom, roomID)
-	return true
+	select {
+	case connect <- player:
+		return true
+	case <-room.Closed:
+		s.restoreClient(player)
+		resp := web.BuildErrorResponse(web.NewError(web.RoomClosed, fmt.Sprintf("room %s is already closed", roomID)))
+		sender.SendResponse(resp, player.Conn)
+		return false
+	}
+}
+
+//restoreClient stores the player back into the server after he has failed to join a room, so he stays
+//in the lobby.
+func (s *Server) restoreClient(p *player.Player) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[p.Id] = p
 }
 
 //JoinRandomRoom searches for room with free place. If such room is found the player will join it.
-//If there is no free room the player will receive Response with action Retry and appropriate message.
-func (s *Server) JoinRandomRoom(player *player.Player) bool {
+//If there is no free room the player will receive Response with action Retry and appropriate message through
+//the sender.
+func (s *Server) JoinRandomRoom(player *player.Player, sender ResponseSender) bool {
 	roomID := s.findRoom()
 	if roomID == "" {
-		resp := web.BuildResponse(pkg.Retry, "there are no free rooms at the moment", nil)
-		s.sender.SendResponse(resp, player.Conn)
+		resp := web.BuildErrorResponse(web.NewError(web.NoFreeRooms, "there are no free rooms at the moment"))
+		sender.SendResponse(resp, player.Conn)
 		return false
 	}
 
-	return s.JoinRoom(roomID, player)
+	return s.JoinRoom(roomID, player, sender)
 }
 
 func (s *Server) findRoom() string {
+	s.roomsMu.RLock()
+	defer s.roomsMu.RUnlock()
 	for id, r := range s.rooms {
-		_, playerCount := r.GetRoomInfo()
-		if playerCount == 1 {
+		if r.Info().Players < 2 {
 			return id
 		}
 	}
 	return ""
 }
 
-//RunRoom starts new room. Separate goroutines are spawned for the players. The room listens for commands on
-//it's channels(one for each player) and on the provided join channel, where the second player should be received.
+//RunRoom starts new room. Separate goroutines are spawned for the players and the spectators. The room listens
+//for commands on it's channels(one for each player and one shared by the spectators), on the provided join channel,
+//where the second player should be received, and on the watch channel, where the spectators are received. When the
+//game is over the players and the spectators who are still connected are handed back to the lobby. The room started
+//without players is closed the same way if nobody joins it within the empty room timeout of the server.
 func (s *Server) RunRoom(r *Room, join chan *player.Player) {
-	var wg = &sync.WaitGroup{}
 	fmt.Println("Start room")
 
-	wg.Add(1)
-	go PlayerReadLoop(r.Current.Conn, r.First, wg, r.FirstExit)
+	var idle <-chan time.Time
+	if r.Current != nil {
+		go s.PlayerReadLoop(r.Current, r.First, r.FirstExit, r.Closed)
 
-	resp := web.BuildResponse(pkg.Wait,
-		fmt.Sprintf("You have created room %s. Wait for an opponent to join the room.", r.Id),
-		map[string]interface{}{"id": r.Id})
-	s.sender.SendResponse(resp, r.Current.Conn)
+		resp := web.BuildResponse(pkg.Wait,
+			fmt.Sprintf("You have created room %s. Wait for an opponent to join the room.", r.Id),
+			map[string]interface{}{"id": r.Id, "rules": r.Rules.Name})
+		s.sender.SendResponse(resp, r.Current.Conn)
+	} else if s.emptyRoomTimeout > 0 {
+		idle = time.After(s.emptyRoomTimeout)
+	}
 
 	for {
 		select {
@@ -227,65 +473,115 @@ func (s *Server) RunRoom(r *Room, join chan *player.Player) {
 		case request := <-r.Second:
 			r.ProcessCommand(request)
 		case secondPlayer := <-join:
-			s.joinRunningRoom(r, secondPlayer, wg, r.SecondExit)
+			s.joinRunningRoom(r, secondPlayer, r.SecondExit)
+		case spectator := <-r.Watch:
+			r.addSpectator(spectator)
+			go s.SpectatorReadLoop(spectator.Player, r.Watching, r.Closed)
+		case request := <-r.Watching:
+			r.ProcessSpectatorCommand(request)
 		case <-r.Done:
-			r.closeRoom()
+			s.releaseRoom(r)
 			s.deleteRoom(r.Id)
-			wg.Wait()
 			return
+		case <-idle:
+			idle = nil
+			if r.Current == nil {
+				s.releaseRoom(r)
+				s.deleteRoom(r.Id)
+				return
+			}
 		}
+		r.publishInfo()
 	}
 }
 
 func (s *Server) deleteRoom(id string) {
+	s.roomsMu.Lock()
+	defer s.roomsMu.Unlock()
 	delete(s.rooms, id)
 	delete(s.connectRoom, id)
 }
 
-func (s *Server) joinRunningRoom(r *Room, secondPlayer *player.Player, wg *sync.WaitGroup, secondExit chan struct{}) {
+//joinRunningRoom seats the player who has joined the room. If both seats are already taken, e.g. two
+//players have tried to take the last seat at the same time, the player is rejected with Response with
+//code ROOM_FULL and handed back to the lobby.
+func (s *Server) joinRunningRoom(r *Room, secondPlayer *player.Player, secondExit chan struct{}) {
+	if r.Current == nil {
+		r.Current = secondPlayer
+		r.Creator = secondPlayer.GetName()
+
+		resp := web.BuildResponse(pkg.Wait,
+			fmt.Sprintf("You have joined room %s. Wait for an opponent to join the room.", r.Id),
+			map[string]interface{}{"id": r.Id, "rules": r.Rules.Name})
+		s.sender.SendResponse(resp, secondPlayer.Conn)
+
+		go s.PlayerReadLoop(secondPlayer, r.First, r.FirstExit, r.Closed)
+		return
+	}
 	if r.Next == nil {
 		r.Next = secondPlayer
-		wg.Add(1)
 
 		resp := web.BuildResponse(pkg.Wait,
 			fmt.Sprintf("You have joined room %s. Wait for your opponent to make his turn.", r.Id),
-			nil)
+			map[string]interface{}{"rules": r.Rules.Name})
 		s.sender.SendResponse(resp, secondPlayer.Conn)
 
-		go PlayerReadLoop(secondPlayer.Conn, r.Second, wg, secondExit)
+		go s.PlayerReadLoop(secondPlayer, r.Second, secondExit, r.Closed)
 
 		r.Phase = pkg.PlaceShip
+		r.startedAt = time.Now()
+		r.resetStats()
 
-		resp = web.BuildResponse(pkg.PlaceShip,
-			fmt.Sprintf("Select where to place ship with length %d", r.NextShipSize),
-			nil)
+		resp = placeShipResponse(r.Placement.NextShip())
 		r.Sender.SendResponse(resp, r.Current.Conn)
+		return
 	}
+
+	resp := web.BuildErrorResponse(web.NewError(web.RoomFull, fmt.Sprintf("room %s is already full", r.Id)))
+	s.sender.SendResponse(resp, secondPlayer.Conn)
+	s.restoreClient(secondPlayer)
+	go ReadLoop(secondPlayer, s)
 }
 
 //PlayerReadLoop reads requests send by the player through it's connection and forwards the
 //to the room through the play channel. The function will exit it's body if a message is sent
-//through the exit channel.
-func PlayerReadLoop(conn player.Connection, play chan web.Request, wg *sync.WaitGroup, exit chan struct{}) {
+//through the exit channel. If the room is closed while the player is still connected, the
+//player is back in the lobby, so his requests are processed by the server again.
+func (s *Server) PlayerReadLoop(pl *player.Player, play chan web.Request, exit chan struct{}, closed chan struct{}) {
 	fmt.Println("start Current read loop")
-	defer wg.Done()
 
+	req, released := s.forwardRequests(pl, play, exit, closed)
+	if released && !s.ProcessLobbyRequest(pl, req) {
+		ReadLoop(pl, s)
+	}
+}
+
+//forwardRequests forwards the valid requests read from the player's connection through the play channel until
+//a message is sent through the exit channel, exit request is forwarded or the closed channel is closed. The id of
+//the player is set to every request, so he can't act on behalf of his opponent. If reading fails request with
+//action "disconnect" is forwarded instead. In case the closed channel is closed while the
+//player is still connected the request which couldn't be forwarded and true are returned.
+func (s *Server) forwardRequests(pl *player.Player, play chan web.Request, exit chan struct{}, closed chan struct{}) (web.Request, bool) {
 	for {
 		select {
 		case <-exit:
-			return
+			return web.Request{}, false
 		default:
-			_, bytes, err := conn.ReadMessage()
+			req, err := s.readRequest(pl)
 			if err != nil {
 				log.Println(err)
-				return
+				req = web.BuildRequest(pl.Id, pkg.Disconnect, nil)
+			} else {
+				req.PlayerId = pl.Id
 			}
 
-			var req web.Request
-			_ = json.Unmarshal(bytes, &req)
-			play <- req
-			if req.Action == pkg.Exit {
-				return
+			select {
+			case play <- req:
+			case <-closed:
+				return req, req.Action != pkg.Disconnect
+			}
+			if req.Action == pkg.Exit || req.Action == pkg.Disconnect {
+				return web.Request{}, false
 			}
 		}
 	}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
 	"github.com/StanislavStefanov/Battleships/pkg/web"
 	"github.com/StanislavStefanov/Battleships/server/automock"
 	"github.com/StanislavStefanov/Battleships/server/player"
@@ -12,7 +13,6 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
-	"sync"
 	"testing"
 	"time"
 )
@@ -63,44 +63,129 @@ func TestServer_DeleteRoom(t *testing.T) {
 }
 
 func TestServer_ListRooms(t *testing.T) {
-	t.Run("list rooms", func(t *testing.T) {
-		// when
-		id1 := "room1"
+	now := time.Now()
+	classic := game.DefaultRules()
+	compact, _ := game.GetRules(game.Compact)
+	getServer := func() *Server {
 		r1 := &Room{
-			Id: id1,
+			Id:        "room1",
+			Current:   &player.Player{},
+			Rules:     classic,
+			Creator:   "first",
+			CreatedAt: now,
+			Phase:     pkg.Wait,
 		}
-
-		id2 := "room2"
 		r2 := &Room{
-			Id:      id2,
-			Current: &player.Player{},
+			Id:        "room2",
+			Current:   &player.Player{},
+			Next:      &player.Player{},
+			Rules:     classic,
+			Creator:   "second",
+			CreatedAt: now.Add(time.Second),
+			Phase:     pkg.Shoot,
 		}
-
-		id3 := "room3"
 		r3 := &Room{
-			Id:      id3,
-			Current: &player.Player{},
-			Next:    &player.Player{},
-		}
-
-		s := Server{
+			Id:        "room3",
+			Current:   &player.Player{},
+			Rules:     compact,
+			Creator:   "third",
+			CreatedAt: now.Add(2 * time.Second),
+			Phase:     pkg.Wait,
+		}
+		return &Server{
 			clients: map[string]*player.Player{},
-			rooms:   map[string]*Room{id1: r1, id2: r2, id3: r3},
+			rooms:   map[string]*Room{"room1": r1, "room2": r2, "room3": r3},
 		}
+	}
+
+	t.Run("list all rooms", func(t *testing.T) {
+		// when
+		s := getServer()
 
 		// then
-		rooms := s.ListRooms()
-		plCount, ok := rooms[id1]
-		assert.True(t, ok)
-		assert.Equal(t, 0, plCount)
+		rooms, total := s.ListRooms(RoomFilter{Page: 1, PageSize: defaultPageSize})
+		assert.Equal(t, 3, total)
+		assert.Equal(t, []web.RoomInfo{
+			{
+				Id:        "room1",
+				Creator:   "first",
+				Players:   1,
+				Rules:     game.Classic,
+				BoardSize: game.BoardSize,
+				Fleet:     classic.Fleet,
+				CreatedAt: now,
+				Phase:     pkg.Waiting,
+			},
+			{
+				Id:        "room2",
+				Creator:   "second",
+				Players:   2,
+				Rules:     game.Classic,
+				BoardSize: game.BoardSize,
+				Fleet:     classic.Fleet,
+				CreatedAt: now.Add(time.Second),
+				Phase:     pkg.Shooting,
+			},
+			{
+				Id:        "room3",
+				Creator:   "third",
+				Players:   1,
+				Rules:     game.Compact,
+				BoardSize: game.BoardSize,
+				Fleet:     compact.Fleet,
+				CreatedAt: now.Add(2 * time.Second),
+				Phase:     pkg.Waiting,
+			},
+		}, rooms)
+	})
+	t.Run("list open rooms", func(t *testing.T) {
+		// when
+		s := getServer()
 
-		plCount, ok = rooms[id2]
-		assert.True(t, ok)
-		assert.Equal(t, 1, plCount)
+		// then
+		rooms, total := s.ListRooms(RoomFilter{OpenOnly: true, Page: 1, PageSize: defaultPageSize})
+		assert.Equal(t, 2, total)
+		assert.Equal(t, "room1", rooms[0].Id)
+		assert.Equal(t, "room3", rooms[1].Id)
+	})
+	t.Run("list rooms without players as open", func(t *testing.T) {
+		// when
+		s := getServer()
+		s.rooms["room4"] = &Room{Id: "room4", Rules: classic, CreatedAt: now.Add(3 * time.Second), Phase: pkg.Wait}
 
-		plCount, ok = rooms[id3]
-		assert.True(t, ok)
-		assert.Equal(t, 2, plCount)
+		// then
+		rooms, total := s.ListRooms(RoomFilter{OpenOnly: true, Page: 1, PageSize: defaultPageSize})
+		assert.Equal(t, 3, total)
+		assert.Equal(t, "room4", rooms[2].Id)
+		assert.Equal(t, 0, rooms[2].Players)
+	})
+	t.Run("list rooms by rules", func(t *testing.T) {
+		// when
+		s := getServer()
+
+		// then
+		rooms, total := s.ListRooms(RoomFilter{Rules: game.Compact, Page: 1, PageSize: defaultPageSize})
+		assert.Equal(t, 1, total)
+		assert.Equal(t, "room3", rooms[0].Id)
+	})
+	t.Run("list second page", func(t *testing.T) {
+		// when
+		s := getServer()
+
+		// then
+		rooms, total := s.ListRooms(RoomFilter{Page: 2, PageSize: 2})
+		assert.Equal(t, 3, total)
+		assert.Equal(t, 1, len(rooms))
+		assert.Equal(t, "room3", rooms[0].Id)
+	})
+	t.Run("list page after the last one", func(t *testing.T) {
+		// when
+		s := getServer()
+
+		// then
+		rooms, total := s.ListRooms(RoomFilter{Page: 3, PageSize: 2})
+		assert.Equal(t, 3, total)
+		assert.Equal(t, 0, len(rooms))
 	})
 }
 
@@ -111,6 +196,7 @@ func TestServer_CreateRoom(t *testing.T) {
 			Conn:  nil,
 			Board: nil,
 			Id:    "player",
+			Name:  "name",
 		}
 
 		s := Server{
@@ -118,15 +204,19 @@ func TestServer_CreateRoom(t *testing.T) {
 			rooms:       map[string]*Room{},
 			connectRoom: map[string]chan *player.Player{},
 		}
+		rules, _ := game.GetRules(game.Compact)
 
 		// then
-		room := s.CreateRoom("player")
+		room := s.CreateRoom("player", rules)
 		_, ok := s.rooms[room.Id]
 		assert.True(t, ok)
 		_, ok = s.connectRoom[room.Id]
 		assert.True(t, ok)
 		assert.Equal(t, "player", room.Current.Id)
 		assert.Nil(t, room.Next)
+		assert.Equal(t, "name", room.Creator)
+		assert.Equal(t, rules, room.Rules)
+		assert.Equal(t, game.NewPlacement(rules), room.Placement)
 	})
 }
 
@@ -138,6 +228,7 @@ func TestServer_JoinRoom(t *testing.T) {
 			Action:  pkg.Retry,
 			Message: "room with id nonexisting doesnt exist",
 			Args:    nil,
+			Code:    web.RoomNotFound,
 		}
 		responseSender :=
 			func() *automock.ResponseSender {
@@ -154,7 +245,7 @@ func TestServer_JoinRoom(t *testing.T) {
 			Conn: con,
 		}
 		// then
-		result := s.JoinRoom("nonexisting", pl)
+		result := s.JoinRoom("nonexisting", pl, s.sender)
 		assert.False(t, result)
 	})
 	t.Run("fail when room is already full", func(t *testing.T) {
@@ -164,6 +255,7 @@ func TestServer_JoinRoom(t *testing.T) {
 			Action:  pkg.Retry,
 			Message: "room room is already full",
 			Args:    nil,
+			Code:    web.RoomFull,
 		}
 		responseSender :=
 			func() *automock.ResponseSender {
@@ -187,7 +279,7 @@ func TestServer_JoinRoom(t *testing.T) {
 			Conn: con,
 		}
 		// then
-		result := s.JoinRoom("room", pl)
+		result := s.JoinRoom("room", pl, s.sender)
 		assert.False(t, result)
 	})
 	t.Run("success", func(t *testing.T) {
@@ -213,11 +305,94 @@ func TestServer_JoinRoom(t *testing.T) {
 		}
 
 		// then
-		result := s.JoinRoom("room", pl)
+		result := s.JoinRoom("room", pl, s.sender)
 		assert.True(t, result)
 		joined := <-connect
 		assert.Equal(t, pl, joined)
 	})
+	t.Run("fail when room is released", func(t *testing.T) {
+		// when
+		con := &websocket.Conn{}
+		resp := web.BuildErrorResponse(web.NewError(web.RoomNotFound, "room with id room doesnt exist"))
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", resp, con).Return(nil).Once()
+
+		pl := &player.Player{Conn: con, Id: "player"}
+		s := Server{
+			clients:     map[string]*player.Player{"player": pl},
+			rooms:       map[string]*Room{"room": {Current: &player.Player{}, Id: "room"}},
+			connectRoom: map[string]chan *player.Player{},
+			sender:      responseSender,
+		}
+
+		// then
+		result := s.JoinRoom("room", pl, s.sender)
+		assert.False(t, result)
+		assert.Equal(t, pl, s.clients["player"])
+		responseSender.AssertExpectations(t)
+	})
+	t.Run("fail when room is closed before receiving the player", func(t *testing.T) {
+		// when
+		con := &websocket.Conn{}
+		resp := web.BuildErrorResponse(web.NewError(web.RoomClosed, "room room is already closed"))
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", resp, con).Return(nil).Once()
+
+		closed := make(chan struct{})
+		close(closed)
+		pl := &player.Player{Conn: con, Id: "player"}
+		s := Server{
+			clients:     map[string]*player.Player{"player": pl},
+			rooms:       map[string]*Room{"room": {Current: &player.Player{}, Id: "room", Closed: closed}},
+			connectRoom: map[string]chan *player.Player{"room": make(chan *player.Player)},
+			sender:      responseSender,
+		}
+
+		// then
+		result := s.JoinRoom("room", pl, s.sender)
+		assert.False(t, result)
+		assert.Equal(t, pl, s.clients["player"])
+		responseSender.AssertExpectations(t)
+	})
+}
+
+func TestServer_JoinRunningRoom(t *testing.T) {
+	t.Run("reject player when both seats are taken", func(t *testing.T) {
+		// when
+		read := make(chan struct{})
+		conn := &connection.Connection{}
+		conn.On("ReadMessage").Return(0, nil, errors.New("closed")).Run(func(mock.Arguments) {
+			close(read)
+		}).Once()
+		resp := web.BuildErrorResponse(web.NewError(web.RoomFull, "room room is already full"))
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", resp, mock.MatchedBy(func(c player.Connection) bool {
+			return c == conn
+		})).Return(nil).Once()
+
+		late := &player.Player{Id: "late", Conn: conn}
+		r := &Room{
+			Current: &player.Player{Id: "first"},
+			Next:    &player.Player{Id: "second"},
+			Id:      "room",
+			Phase:   pkg.PlaceShip,
+		}
+		s := &Server{
+			clients: map[string]*player.Player{},
+			sender:  responseSender,
+		}
+
+		// then
+		s.joinRunningRoom(r, late, r.SecondExit)
+		assert.Equal(t, "second", r.Next.Id)
+		assert.Equal(t, pkg.PlaceShip, r.Phase)
+		assert.Equal(t, 1, len(responseSender.Calls))
+		select {
+		case <-read:
+		case <-time.After(time.Second):
+			t.Fatal("the player is not back in the lobby")
+		}
+	})
 }
 
 func TestServer_JoinRandomRoom(t *testing.T) {
@@ -228,6 +403,7 @@ func TestServer_JoinRandomRoom(t *testing.T) {
 			Action:  pkg.Retry,
 			Message: "there are no free rooms at the moment",
 			Args:    nil,
+			Code:    web.NoFreeRooms,
 		}
 		responseSender :=
 			func() *automock.ResponseSender {
@@ -244,7 +420,7 @@ func TestServer_JoinRandomRoom(t *testing.T) {
 			Conn: con,
 		}
 		// then
-		result := s.JoinRandomRoom(pl)
+		result := s.JoinRandomRoom(pl, s.sender)
 		assert.False(t, result)
 	})
 	t.Run("fail when there are no free places in the rooms", func(t *testing.T) {
@@ -254,6 +430,7 @@ func TestServer_JoinRandomRoom(t *testing.T) {
 			Action:  pkg.Retry,
 			Message: "there are no free rooms at the moment",
 			Args:    nil,
+			Code:    web.NoFreeRooms,
 		}
 		responseSender :=
 			func() *automock.ResponseSender {
@@ -274,7 +451,7 @@ func TestServer_JoinRandomRoom(t *testing.T) {
 			Conn: con,
 		}
 		// then
-		result := s.JoinRandomRoom(pl)
+		result := s.JoinRandomRoom(pl, s.sender)
 		assert.False(t, result)
 	})
 	t.Run("success", func(t *testing.T) {
@@ -300,7 +477,7 @@ func TestServer_JoinRandomRoom(t *testing.T) {
 		}
 
 		// then
-		result := s.JoinRandomRoom(pl)
+		result := s.JoinRandomRoom(pl, s.sender)
 		assert.True(t, result)
 		joined := <-connect
 		assert.Equal(t, pl, joined)
@@ -339,10 +516,15 @@ func TestServer_ReadLoop(t *testing.T) {
 	t.Run("list rooms", func(t *testing.T) {
 		// when
 
-		req := web.BuildRequest("id", pkg.ListRooms, nil)
+		req := web.BuildRequest("id", pkg.ListRooms, map[string]interface{}{"open": true, "pageSize": "1"})
 		listRooms, _ := json.Marshal(req)
 
-		roomsInfo := map[string]interface{}{"room1": 0, "room2": 1, "room3": 2}
+		roomsInfo := map[string]interface{}{
+			"rooms":    []web.RoomInfo{{Id: "room1", Players: 0, Phase: pkg.Waiting}},
+			"total":    2,
+			"page":     1,
+			"pageSize": 1,
+		}
 
 		resp := web.BuildResponse(pkg.Info, "Rooms: ", roomsInfo)
 		rooms, _ := json.Marshal(resp)
@@ -397,6 +579,55 @@ func TestServer_ReadLoop(t *testing.T) {
 		assert.False(t, ok)
 		con.AssertExpectations(t)
 	})
+	t.Run("echo request id and acknowledge request without response", func(t *testing.T) {
+		// when
+		req := web.BuildRequest("id", pkg.Chat, map[string]interface{}{"text": "hello"})
+		req.RequestId = "1"
+		req.Ack = true
+		chat, _ := json.Marshal(req)
+
+		ack := web.BuildResponse(pkg.Ack, "", nil)
+		ack.RequestId = "1"
+		ackResp, _ := json.Marshal(ack)
+
+		req = web.BuildRequest("id", pkg.JoinRoom, map[string]interface{}{"roomId": "room"})
+		req.RequestId = "2"
+		req.Ack = true
+		join, _ := json.Marshal(req)
+
+		notFound := web.BuildErrorResponse(web.NewError(web.RoomNotFound, "room with id room doesnt exist"))
+		notFound.RequestId = "2"
+		notFoundResp, _ := json.Marshal(notFound)
+
+		req = web.BuildRequest("id", pkg.Exit, nil)
+		exit, _ := json.Marshal(req)
+
+		con := func() *connection.Connection {
+			con := &connection.Connection{}
+			con.On("ReadMessage").Return(0, chat, nil).Once()
+			con.On("WriteMessage", websocket.BinaryMessage, ackResp).Return(nil).Once()
+			con.On("ReadMessage").Return(0, join, nil).Once()
+			con.On("WriteMessage", websocket.BinaryMessage, notFoundResp).Return(nil).Once()
+			con.On("ReadMessage").Return(0, exit, nil).Once()
+			con.On("Close").Return(nil).Once()
+			return con
+		}()
+
+		pl := &player.Player{
+			Id:   "player",
+			Conn: con,
+		}
+
+		s := &Server{
+			clients: map[string]*player.Player{"player": pl},
+			sender:  &Sender{},
+			rooms:   map[string]*Room{},
+		}
+
+		// then
+		ReadLoop(pl, s)
+		con.AssertExpectations(t)
+	})
 	t.Run("create room", func(t *testing.T) {
 		// when
 		req := web.BuildRequest("player", pkg.CreateRoom, nil)
@@ -407,10 +638,9 @@ func TestServer_ReadLoop(t *testing.T) {
 		con := func() *connection.Connection {
 			con := &connection.Connection{}
 			con.On("ReadMessage").Return(0, create, nil).Once()
-			con.On("WriteMessage", websocket.BinaryMessage, mock.Anything).Return(nil).Once()
+			con.On("WriteMessage", websocket.BinaryMessage, mock.Anything).Return(nil).Twice()
 			con.On("ReadMessage").Return(0, exit, nil).Once()
-
-			con.On("Close").Return(nil).Once()
+			con.On("ReadMessage").Return(0, nil, errors.New("connection closed")).Once()
 			return con
 		}()
 
@@ -430,14 +660,11 @@ func TestServer_ReadLoop(t *testing.T) {
 		// then
 		ReadLoop(pl, s)
 
-		assert.Equal(t, 1, len(s.rooms))
-
-		//for _, r := range s.rooms {
-		//	r.Done <- struct{}{}
-		//}
-
-		assert.Equal(t, 1, len(s.connectRoom))
-		time.Sleep(2 * time.Second)
+		assert.Eventually(t, func() bool {
+			s.roomsMu.RLock()
+			defer s.roomsMu.RUnlock()
+			return len(s.rooms) == 0 && len(s.connectRoom) == 0
+		}, 2*time.Second, 10*time.Millisecond)
 		con.AssertExpectations(t)
 
 	})
@@ -485,7 +712,7 @@ func TestServer_ReadLoop(t *testing.T) {
 		assert.False(t, ok)
 
 		assert.Equal(t, 1, len(s.rooms))
-		assert.Equal(t, 0, len(s.connectRoom))
+		assert.Equal(t, pl, <-s.connectRoom["room"])
 		assert.Equal(t, 0, len(s.clients))
 
 		con.AssertExpectations(t)
@@ -496,7 +723,7 @@ func TestServer_ReadLoop(t *testing.T) {
 		req := web.BuildRequest("id", pkg.JoinRoom, map[string]interface{}{"roomId": 2})
 		joinRoom, _ := json.Marshal(req)
 
-		resp := web.BuildResponse(pkg.Retry, "Invalid room ID", nil)
+		resp := web.BuildErrorResponse(web.NewError(web.InvalidArgument, "invalid value for roomId"))
 		errorResp, _ := json.Marshal(resp)
 
 		req = web.BuildRequest("id", pkg.Exit, nil)
@@ -571,7 +798,7 @@ func TestServer_ReadLoop(t *testing.T) {
 		assert.False(t, ok)
 
 		assert.Equal(t, 1, len(s.rooms))
-		assert.Equal(t, 0, len(s.connectRoom))
+		assert.Equal(t, pl, <-s.connectRoom["room"])
 		assert.Equal(t, 0, len(s.clients))
 
 		con.AssertExpectations(t)
@@ -582,7 +809,7 @@ func TestServer_ReadLoop(t *testing.T) {
 		req := web.BuildRequest("id", pkg.JoinRandom, nil)
 		joinRandom, _ := json.Marshal(req)
 
-		resp := web.BuildResponse(pkg.Retry, "there are no free rooms at the moment", nil)
+		resp := web.BuildErrorResponse(web.NewError(web.NoFreeRooms, "there are no free rooms at the moment"))
 		errorResp, _ := json.Marshal(resp)
 
 		req = web.BuildRequest("id", pkg.Exit, nil)
@@ -619,7 +846,7 @@ func TestServer_ReadLoop(t *testing.T) {
 		req := web.BuildRequest("id", "invalid action", nil)
 		invalidAction, _ := json.Marshal(req)
 
-		resp := web.BuildResponse(pkg.Retry, "unknown", nil)
+		resp := web.BuildErrorResponse(web.NewError(web.UnknownAction, `unknown action "invalid action"`))
 		unknown, _ := json.Marshal(resp)
 
 		req = web.BuildRequest("id", pkg.Exit, nil)
@@ -653,6 +880,77 @@ func TestServer_ReadLoop(t *testing.T) {
 		assert.False(t, ok)
 		con.AssertExpectations(t)
 	})
+	t.Run("send the lobby state", func(t *testing.T) {
+		// when
+		req := web.BuildRequest("id", pkg.State, nil)
+		state, _ := json.Marshal(req)
+
+		resp := web.BuildResponse(pkg.State, "", map[string]interface{}{"phase": pkg.Lobby, "turn": false})
+		lobbyState, _ := json.Marshal(resp)
+
+		req = web.BuildRequest("id", pkg.Exit, nil)
+		exit, _ := json.Marshal(req)
+
+		con := func() *connection.Connection {
+			con := &connection.Connection{}
+			con.On("ReadMessage").Return(0, state, nil).Once()
+			con.On("WriteMessage", websocket.BinaryMessage, lobbyState).Return(nil).Once()
+			con.On("ReadMessage").Return(0, exit, nil).Once()
+
+			con.On("Close").Return(nil).Once()
+			return con
+		}()
+
+		pl := &player.Player{
+			Id:   "player",
+			Conn: con,
+		}
+
+		s := &Server{
+			clients: map[string]*player.Player{"player": pl},
+			sender:  &Sender{},
+			UUID:    uuid.UUID{},
+		}
+
+		// then
+		ReadLoop(pl, s)
+
+		con.AssertExpectations(t)
+	})
+	t.Run("reject request which is not valid JSON", func(t *testing.T) {
+		// when
+		resp := web.BuildErrorResponse(web.NewError(web.InvalidRequest, "request is not valid JSON"))
+		invalid, _ := json.Marshal(resp)
+
+		req := web.BuildRequest("id", pkg.Exit, nil)
+		exit, _ := json.Marshal(req)
+
+		con := func() *connection.Connection {
+			con := &connection.Connection{}
+			con.On("ReadMessage").Return(0, []byte(`{"action":`), nil).Once()
+			con.On("WriteMessage", websocket.BinaryMessage, invalid).Return(nil).Once()
+			con.On("ReadMessage").Return(0, exit, nil).Once()
+
+			con.On("Close").Return(nil).Once()
+			return con
+		}()
+
+		pl := &player.Player{
+			Id:   "player",
+			Conn: con,
+		}
+
+		s := &Server{
+			clients: map[string]*player.Player{"player": pl},
+			sender:  &Sender{},
+			UUID:    uuid.UUID{},
+		}
+
+		// then
+		ReadLoop(pl, s)
+
+		con.AssertExpectations(t)
+	})
 	t.Run("fail when error occurs while reading from connection", func(t *testing.T) {
 		// when
 		con := func() *connection.Connection {
@@ -682,22 +980,64 @@ func TestServer_ReadLoop(t *testing.T) {
 }
 
 func TestServer_RunRoom(t *testing.T) {
+	t.Run("close room without players when nobody joins it in time", func(t *testing.T) {
+		// when
+		s := NewServer()
+		s.emptyRoomTimeout = 10 * time.Millisecond
+		room, err := s.OpenRoom(game.DefaultRules())
+		assert.Nil(t, err)
+		s.RunRoom(room, s.getConnectRoom(room.Id))
+
+		// then
+		assert.Nil(t, s.getRoom(room.Id))
+		_, open := <-room.Closed
+		assert.False(t, open)
+	})
+	t.Run("keep room without players open after its first player joins", func(t *testing.T) {
+		// when
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", mock.Anything, mock.Anything).Return(nil)
+
+		conn := &connection.Connection{}
+		read := make(chan time.Time)
+		conn.On("ReadMessage").WaitUntil(read).Return(0, nil, errors.New("connection closed"))
+		conn.On("Close").Return(nil)
+
+		s := NewServer()
+		s.sender = responseSender
+		s.emptyRoomTimeout = 10 * time.Millisecond
+		room, _ := s.OpenRoom(game.DefaultRules())
+		join := s.getConnectRoom(room.Id)
+		go s.RunRoom(room, join)
+		join <- &player.Player{Id: "first", Conn: conn}
+		time.Sleep(50 * time.Millisecond)
+
+		// then
+		assert.Equal(t, room, s.getRoom(room.Id))
+		assert.Equal(t, 1, room.Info().Players)
+		close(read)
+		<-room.Closed
+	})
 	t.Run("success when receive actions from first Player", func(t *testing.T) {
 		// when
-		createdRoom := web.BuildResponse(pkg.Wait, "You have created room room. Wait for an opponent to join the room.", map[string]interface{}{"id": "room"})
+		createdRoom := web.BuildResponse(pkg.Wait, "You have created room room. Wait for an opponent to join the room.", map[string]interface{}{"id": "room", "rules": game.Classic})
 		createdRoomMarshal, _ := json.Marshal(createdRoom)
 
-		resp := web.BuildResponse(pkg.Retry, "Invalid action during Phase: phase.", nil)
+		resp := web.BuildErrorResponse(web.NewError(web.WrongPhase, "Invalid action during Phase: phase."))
 		marshal, _ := json.Marshal(resp)
 
+		lobby := web.BuildResponse(pkg.Lobby, "You are back in the lobby.", nil)
+		lobbyMarshal, _ := json.Marshal(lobby)
+
 		exit := web.BuildRequest("first", pkg.Exit, nil)
 		exitMarshal, _ := json.Marshal(exit)
 		firstConn := func() *connection.Connection {
 			con := &connection.Connection{}
 			con.On("WriteMessage", websocket.BinaryMessage, createdRoomMarshal).Return(nil).Once()
 			con.On("WriteMessage", websocket.BinaryMessage, marshal).Return(nil).Once()
+			con.On("WriteMessage", websocket.BinaryMessage, lobbyMarshal).Return(nil).Once()
 			con.On("ReadMessage").Return(0, exitMarshal, nil).Once()
-			con.On("Close").Return(nil).Once()
+			con.On("ReadMessage").Return(0, nil, errors.New("connection closed")).Once()
 			return con
 		}()
 
@@ -707,12 +1047,13 @@ func TestServer_RunRoom(t *testing.T) {
 			Id:    "first",
 		}
 
-		win := web.BuildResponse(pkg.Win, "Your opponent exited the game. Congratulations, you win!", nil)
+		win := web.BuildResponse(pkg.Win, "Your opponent exited the game. Congratulations, you win!", map[string]interface{}{
+			"winner": web.PlayerSummary{Name: "anonymous"}, "loser": web.PlayerSummary{Name: "anonymous"}})
 		winMarshal, _ := json.Marshal(win)
 		secondConn := func() *connection.Connection {
 			con := &connection.Connection{}
 			con.On("WriteMessage", websocket.BinaryMessage, winMarshal).Return(nil).Once()
-			con.On("Close").Return(nil).Once()
+			con.On("WriteMessage", websocket.BinaryMessage, lobbyMarshal).Return(nil).Once()
 			return con
 		}()
 
@@ -723,24 +1064,24 @@ func TestServer_RunRoom(t *testing.T) {
 		}
 
 		room := &Room{
-			Current:         first,
-			Next:            second,
-			First:           make(chan web.Request, 2),
-			Second:          make(chan web.Request),
-			Done:            make(chan struct{}, 1),
-			Phase:           "phase",
-			ShipSizeToCount: nil,
-			NextShipSize:    0,
-			Id:              "room",
-			Sender:          &Sender{},
+			Current: first,
+			Next:    second,
+			First:   make(chan web.Request, 2),
+			Second:  make(chan web.Request),
+			Done:    make(chan struct{}, 1),
+			Phase:   "phase",
+			Id:      "room",
+			Rules:   game.DefaultRules(),
+			Sender:  &Sender{},
 		}
 		create := web.BuildRequest("first", "test", nil)
 		room.First <- create
 
 		s := &Server{
-			rooms:  map[string]*Room{"room": room},
-			sender: &Sender{},
-			UUID:   uuid.UUID{},
+			clients: map[string]*player.Player{},
+			rooms:   map[string]*Room{"room": room},
+			sender:  &Sender{},
+			UUID:    uuid.UUID{},
 		}
 
 		// then
@@ -748,23 +1089,27 @@ func TestServer_RunRoom(t *testing.T) {
 
 		assert.Equal(t, 0, len(s.rooms))
 		assert.Equal(t, 0, len(s.connectRoom))
+		waitForClients(t, s, map[string]*player.Player{"second": second})
 
 		firstConn.AssertExpectations(t)
 		secondConn.AssertExpectations(t)
 	})
 	t.Run("success join second user", func(t *testing.T) {
 		// when
-		createdRoom := web.BuildResponse(pkg.Wait, "You have created room room. Wait for an opponent to join the room.", map[string]interface{}{"id": "room"})
+		createdRoom := web.BuildResponse(pkg.Wait, "You have created room room. Wait for an opponent to join the room.", map[string]interface{}{"id": "room", "rules": game.Classic})
 		createdRoomMarshal, _ := json.Marshal(createdRoom)
 
-		resp := web.BuildResponse(pkg.PlaceShip, "Select where to place ship with length 5", nil)
+		resp := web.BuildResponse(pkg.PlaceShip, "Select where to place ship with length 5", map[string]interface{}{"length": 5})
 		place, _ := json.Marshal(resp)
 
+		lobby := web.BuildResponse(pkg.Lobby, "You are back in the lobby.", nil)
+		lobbyMarshal, _ := json.Marshal(lobby)
+
 		firstConn := func() *connection.Connection {
 			con := &connection.Connection{}
 			con.On("WriteMessage", websocket.BinaryMessage, createdRoomMarshal).Return(nil).Once()
 			con.On("WriteMessage", websocket.BinaryMessage, place).Return(nil).Once()
-			con.On("Close").Return(nil).Once()
+			con.On("WriteMessage", websocket.BinaryMessage, lobbyMarshal).Return(nil).Once()
 			return con
 		}()
 
@@ -774,13 +1119,13 @@ func TestServer_RunRoom(t *testing.T) {
 			Id:    "first",
 		}
 
-		resp = web.BuildResponse(pkg.Wait, "You have joined room room. Wait for your opponent to make his turn.", nil)
+		resp = web.BuildResponse(pkg.Wait, "You have joined room room. Wait for your opponent to make his turn.", map[string]interface{}{"rules": game.Classic})
 		joined, _ := json.Marshal(resp)
 
 		secondConn := func() *connection.Connection {
 			con := &connection.Connection{}
 			con.On("WriteMessage", websocket.BinaryMessage, joined).Return(nil).Once()
-			con.On("Close").Return(nil).Once()
+			con.On("WriteMessage", websocket.BinaryMessage, lobbyMarshal).Return(nil).Once()
 			return con
 		}()
 
@@ -807,13 +1152,15 @@ func TestServer_RunRoom(t *testing.T) {
 			FirstExit:  firstExit,
 			SecondExit: secondExit,
 			Id:         "room",
+			Rules:      game.DefaultRules(),
 			Done:       done,
-			NextShipSize: destroyer,
+			Placement:  game.NewPlacement(game.DefaultRules()),
 		}
 
 		s := &Server{
-			sender: &Sender{},
-			UUID:   uuid.UUID{},
+			clients: map[string]*player.Player{},
+			sender:  &Sender{},
+			UUID:    uuid.UUID{},
 		}
 
 		join := make(chan *player.Player, 1)
@@ -827,16 +1174,19 @@ func TestServer_RunRoom(t *testing.T) {
 	})
 	t.Run("success receive action from second user", func(t *testing.T) {
 		// when
-		createdRoom := web.BuildResponse(pkg.Wait, "You have created room room. Wait for an opponent to join the room.", map[string]interface{}{"id": "room"})
+		createdRoom := web.BuildResponse(pkg.Wait, "You have created room room. Wait for an opponent to join the room.", map[string]interface{}{"id": "room", "rules": game.Classic})
 		createdRoomMarshal, _ := json.Marshal(createdRoom)
 
-		win := web.BuildResponse(pkg.Win, "Your opponent exited the game. Congratulations, you win!", nil)
+		win := web.BuildResponse(pkg.Win, "Your opponent exited the game. Congratulations, you win!", map[string]interface{}{
+			"winner": web.PlayerSummary{Name: "anonymous"}, "loser": web.PlayerSummary{Name: "anonymous"}})
 		winMarshal, _ := json.Marshal(win)
+		lobby := web.BuildResponse(pkg.Lobby, "You are back in the lobby.", nil)
+		lobbyMarshal, _ := json.Marshal(lobby)
 		firstConn := func() *connection.Connection {
 			con := &connection.Connection{}
 			con.On("WriteMessage", websocket.BinaryMessage, createdRoomMarshal).Return(nil).Once()
 			con.On("WriteMessage", websocket.BinaryMessage, winMarshal).Return(nil).Once()
-			con.On("Close").Return(nil).Once()
+			con.On("WriteMessage", websocket.BinaryMessage, lobbyMarshal).Return(nil).Once()
 			return con
 		}()
 
@@ -848,7 +1198,8 @@ func TestServer_RunRoom(t *testing.T) {
 
 		secondConn := func() *connection.Connection {
 			con := &connection.Connection{}
-			con.On("Close").Return(nil).Once()
+			con.On("WriteMessage", websocket.BinaryMessage, lobbyMarshal).Return(nil).Once()
+			con.On("ReadMessage").Return(0, nil, errors.New("connection closed")).Once()
 			return con
 		}()
 
@@ -875,17 +1226,20 @@ func TestServer_RunRoom(t *testing.T) {
 			FirstExit:  firstExit,
 			SecondExit: secondExit,
 			Id:         "room",
+			Rules:      game.DefaultRules(),
 			Done:       make(chan struct{}, 1),
 		}
 
 		s := &Server{
-			sender: &Sender{},
-			UUID:   uuid.UUID{},
+			clients: map[string]*player.Player{},
+			sender:  &Sender{},
+			UUID:    uuid.UUID{},
 		}
 
 		// then
 		s.RunRoom(room, nil)
 
+		waitForClients(t, s, map[string]*player.Player{"first": first})
 		firstConn.AssertExpectations(t)
 		secondConn.AssertExpectations(t)
 	})
@@ -894,7 +1248,7 @@ func TestServer_RunRoom(t *testing.T) {
 func TestServer_joinRunningRoom(t *testing.T) {
 	t.Run("join", func(t *testing.T) {
 		// when
-		resp := web.BuildResponse(pkg.PlaceShip, "Select where to place ship with length 5", nil)
+		resp := web.BuildResponse(pkg.PlaceShip, "Select where to place ship with length 5", map[string]interface{}{"length": 5})
 		place, _ := json.Marshal(resp)
 		firstConn := func() *connection.Connection {
 			con := &connection.Connection{}
@@ -908,7 +1262,7 @@ func TestServer_joinRunningRoom(t *testing.T) {
 			Id:    "first",
 		}
 
-		resp = web.BuildResponse(pkg.Wait, "You have joined room room. Wait for your opponent to make his turn.", nil)
+		resp = web.BuildResponse(pkg.Wait, "You have joined room room. Wait for your opponent to make his turn.", map[string]interface{}{"rules": game.Classic})
 		joined, _ := json.Marshal(resp)
 		secondConn := func() *connection.Connection {
 			con := &connection.Connection{}
@@ -923,10 +1277,11 @@ func TestServer_joinRunningRoom(t *testing.T) {
 		}
 
 		room := &Room{
-			Current: first,
-			Sender:  &Sender{},
-			Id:      "room",
-			NextShipSize: destroyer,
+			Current:   first,
+			Sender:    &Sender{},
+			Id:        "room",
+			Rules:     game.DefaultRules(),
+			Placement: game.NewPlacement(game.DefaultRules()),
 		}
 
 		s := &Server{
@@ -937,9 +1292,32 @@ func TestServer_joinRunningRoom(t *testing.T) {
 		channel := make(chan struct{}, 1)
 		channel <- struct{}{}
 		// then
-		s.joinRunningRoom(room, second, &sync.WaitGroup{}, channel)
+		s.joinRunningRoom(room, second, channel)
 
 		firstConn.AssertExpectations(t)
 		secondConn.AssertExpectations(t)
 	})
 }
+
+func TestServer_ForwardRequests(t *testing.T) {
+	t.Run("forward requests on behalf of the player who sent them", func(t *testing.T) {
+		// given
+		shoot, _ := json.Marshal(web.BuildRequest("second", pkg.Shoot, map[string]interface{}{"x": 1, "y": 2}))
+		conn := &connection.Connection{}
+		conn.On("ReadMessage").Return(websocket.BinaryMessage, shoot, nil).Once()
+		conn.On("ReadMessage").Return(0, nil, errors.New("read failure"))
+
+		s := &Server{sender: &automock.ResponseSender{}}
+		play := make(chan web.Request, 2)
+
+		// when
+		_, ok := s.forwardRequests(&player.Player{Id: "first", Conn: conn}, play, make(chan struct{}), make(chan struct{}))
+
+		// then
+		assert.False(t, ok)
+		req := <-play
+		assert.Equal(t, "first", req.PlayerId)
+		assert.Equal(t, pkg.Shoot, req.Action)
+		assert.Equal(t, web.BuildRequest("first", pkg.Disconnect, nil), <-play)
+	})
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/StanislavStefanov/Battleships/server/player"
+)
+
+//spectatorDelay is the count of shots by which the events sent to spectators with full view are
+//delayed, so they can't reveal the position of the ships to the players while the game is going.
+const spectatorDelay = 3
+
+//Spectator is a player who watches the game in a room without taking part in it. Spectators
+//with FullView see both boards, but the events they receive are delayed by spectatorDelay shots.
+//Spectators without FullView receive the events immediately, but the fleets are hidden from them
+//until the game ends. Spectators with EventsOnly watch the room through the event stream, so they
+//receive only the feed of the game - the shots, their outcomes, the turns and the result, but not the
+//chat, and they aren't handed back to the lobby when the room is closed.
+type Spectator struct {
+	*player.Player
+	FullView   bool
+	EventsOnly bool
+}
+
+//Spectate attaches the player as spectator to the room with the provided id. If the room doesn't
+//exist or is already closed the player will be notified through the sender with Response with
+//status Retry and appropriate message.
+func (s *Server) Spectate(roomID string, pl *player.Player, fullView bool, sender ResponseSender) bool {
+	room := s.getRoom(roomID)
+	if room == nil {
+		resp := web.BuildErrorResponse(web.NewError(web.RoomNotFound, fmt.Sprintf("room with id %s doesnt exist", roomID)))
+		sender.SendResponse(resp, pl.Conn)
+		return false
+	}
+
+	select {
+	case room.Watch <- &Spectator{Player: pl, FullView: fullView}:
+	case <-room.Closed:
+		resp := web.BuildErrorResponse(web.NewError(web.RoomClosed, fmt.Sprintf("room %s is already closed", roomID)))
+		sender.SendResponse(resp, pl.Conn)
+		return false
+	}
+
+	s.deletePlayer(pl.Id)
+	return true
+}
+
+//SpectatorReadLoop reads the valid requests sent by the spectator through his connection and forwards
+//them to the room through the watching channel. The id of the spectator is set to every request, so
+//spectators can't act on behalf of the players. If reading from the connection fails disconnect
+//request is forwarded instead. After exit request is forwarded the spectator is handed back to the
+//lobby. If the closed channel is closed while the spectator is still connected, he is already back
+//in the lobby, so his requests are processed by the server again, unless he has disconnected, in which
+//case he is removed from the server.
+func (s *Server) SpectatorReadLoop(spectator *player.Player, watching chan web.Request, closed chan struct{}) {
+	for {
+		req, err := s.readRequest(spectator)
+		if err != nil {
+			req = web.BuildRequest(spectator.Id, pkg.Disconnect, nil)
+		} else {
+			req.PlayerId = spectator.Id
+		}
+
+		select {
+		case watching <- req:
+		case <-closed:
+			if req.Action == pkg.Disconnect {
+				s.deletePlayer(spectator.Id)
+			} else if !s.ProcessLobbyRequest(spectator, req) {
+				ReadLoop(spectator, s)
+			}
+			return
+		}
+		switch req.Action {
+		case pkg.Disconnect:
+			return
+		case pkg.Exit:
+			s.returnToLobby(spectator)
+			ReadLoop(spectator, s)
+			return
+		}
+	}
+}
+
+//ProcessSpectatorCommand processes requests sent by spectators. The spectators are allowed to chat
+//with each other, to mute other players and to exit, after which they leave the room. The connection
+//of a disconnected spectator is closed. Any other request is rejected with Response with status Retry.
+//The responses sent to the spectator carry the id of the request.
+func (r *Room) ProcessSpectatorCommand(request web.Request) {
+	spectator, ok := r.Spectators[request.GetId()]
+	if !ok {
+		return
+	}
+	defer r.replyTo(spectator.Conn, request)()
+
+	switch request.GetAction() {
+	case pkg.Exit:
+		r.removeSpectator(spectator)
+		return
+	case pkg.Disconnect:
+		r.removeSpectator(spectator)
+		_ = spectator.Conn.Close()
+		return
+	case pkg.Chat:
+		r.processSpectatorChat(spectator, request)
+		return
+	case pkg.Mute:
+		processMute(spectator.Player, request, r.Sender)
+		return
+	}
+
+	resp := web.BuildErrorResponse(web.NewError(web.ActionNotAllowed, "Spectators can only watch the game."))
+	r.Sender.SendResponse(resp, spectator.Conn)
+}
+
+func (r *Room) addSpectator(spectator *Spectator) {
+	if r.Spectators == nil {
+		r.Spectators = make(map[string]*Spectator)
+	}
+	r.Spectators[spectator.Id] = spectator
+
+	resp := web.BuildResponse(pkg.Spectate,
+		fmt.Sprintf("You are watching room %s.", r.Id),
+		map[string]interface{}{"id": r.Id, "fullView": spectator.FullView, "phase": r.getPhaseInfo()})
+	r.Sender.SendResponse(resp, spectator.Conn)
+
+	r.notifySpectatorsCount()
+}
+
+func (r *Room) removeSpectator(spectator *Spectator) {
+	delete(r.Spectators, spectator.Id)
+
+	r.notifySpectatorsCount()
+}
+
+func (r *Room) notifySpectatorsCount() {
+	resp := web.BuildResponse(pkg.Info,
+		fmt.Sprintf("Spectators watching: %d.", len(r.Spectators)),
+		map[string]interface{}{"spectators": len(r.Spectators)})
+	for _, p := range []*player.Player{r.Current, r.Next} {
+		if p != nil {
+			r.Sender.SendResponse(resp, p.Conn)
+		}
+	}
+}
+
+//broadcastShot notifies the spectators about the shot of the current player and about the player who
+//shoots next (arg turn), unless the shot has ended the game. The spectators without full view are
+//notified immediately. The event with both boards is queued for the spectators with full
+//view and the events older than spectatorDelay shots are released to them.
+func (r *Room) broadcastShot(position game.Position, hit, sunk bool) {
+	message := fmt.Sprintf("%s shot at %c%d.", r.Current.GetName(), 'A'+position.X, position.Y)
+	args := map[string]interface{}{
+		"shooter": r.Current.GetName(),
+		"target":  r.Next.GetName(),
+		"x":       position.X,
+		"y":       position.Y,
+		"hit":     hit,
+		"sunk":    sunk,
+	}
+	if !hit || !r.Next.Board.IsBeaten() {
+		args["turn"] = r.Next.GetName()
+	}
+	fullArgs := map[string]interface{}{
+		"shooterFields": getFields(r.Current),
+		"targetFields":  getFields(r.Next),
+	}
+	for k, v := range args {
+		fullArgs[k] = v
+	}
+
+	resp := web.BuildResponse(pkg.Event, message, args)
+	for _, spectator := range r.Spectators {
+		if !spectator.FullView {
+			r.Sender.SendResponse(resp, spectator.Conn)
+		}
+	}
+
+	r.delayed = append(r.delayed, web.BuildResponse(pkg.Event, message, fullArgs))
+	if len(r.delayed) > spectatorDelay {
+		r.releaseDelayed(len(r.delayed) - spectatorDelay)
+	}
+}
+
+//broadcastTurn notifies all spectators that the ships are placed and the current player shoots first.
+//The event reveals nothing about the fleets, so it isn't delayed for the spectators with full view.
+func (r *Room) broadcastTurn() {
+	resp := web.BuildResponse(pkg.Event,
+		fmt.Sprintf("All ships are placed. %s shoots first.", r.Current.GetName()),
+		map[string]interface{}{"turn": r.Current.GetName()})
+	for _, spectator := range r.Spectators {
+		r.Sender.SendResponse(resp, spectator.Conn)
+	}
+}
+
+//broadcastResult releases all delayed events, records the game in the history of the room and reveals both
+//fleets to the spectators.
+func (r *Room) broadcastResult(winner, loser *player.Player) {
+	r.releaseDelayed(len(r.delayed))
+	r.recordGame(winner, loser)
+
+	resp := web.BuildResponse(pkg.GameOver,
+		fmt.Sprintf("%s wins the game.", winner.GetName()),
+		map[string]interface{}{
+			"winner":       winner.GetName(),
+			"loser":        loser.GetName(),
+			"winnerFields": getFields(winner),
+			"loserFields":  getFields(loser),
+		})
+	for _, spectator := range r.Spectators {
+		r.Sender.SendResponse(resp, spectator.Conn)
+	}
+}
+
+func (r *Room) releaseDelayed(count int) {
+	for _, resp := range r.delayed[:count] {
+		for _, spectator := range r.Spectators {
+			if spectator.FullView {
+				r.Sender.SendResponse(resp, spectator.Conn)
+			}
+		}
+	}
+	r.delayed = r.delayed[count:]
+}
+
+func getFields(p *player.Player) []string {
+	if p.Board == nil {
+		return nil
+	}
+	return p.Board.GetOwnFields()
+}
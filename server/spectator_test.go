@@ -0,0 +1,389 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/StanislavStefanov/Battleships/server/automock"
+	"github.com/StanislavStefanov/Battleships/server/player"
+	connection "github.com/StanislavStefanov/Battleships/server/player/automock"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func TestServer_Spectate(t *testing.T) {
+	t.Run("fail when room does not exist", func(t *testing.T) {
+		// when
+		con := &websocket.Conn{}
+		resp := web.BuildErrorResponse(web.NewError(web.RoomNotFound, "room with id nonexisting doesnt exist"))
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", resp, con).Return(nil).Once()
+
+		s := Server{
+			rooms:  map[string]*Room{},
+			sender: responseSender,
+		}
+		pl := &player.Player{
+			Conn: con,
+		}
+
+		// then
+		result := s.Spectate("nonexisting", pl, false, s.sender)
+		assert.False(t, result)
+		responseSender.AssertExpectations(t)
+	})
+	t.Run("fail when room is closed", func(t *testing.T) {
+		// when
+		con := &websocket.Conn{}
+		resp := web.BuildErrorResponse(web.NewError(web.RoomClosed, "room room is already closed"))
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", resp, con).Return(nil).Once()
+
+		closed := make(chan struct{})
+		close(closed)
+		s := Server{
+			rooms:  map[string]*Room{"room": {Id: "room", Closed: closed}},
+			sender: responseSender,
+		}
+		pl := &player.Player{
+			Conn: con,
+		}
+
+		// then
+		result := s.Spectate("room", pl, false, s.sender)
+		assert.False(t, result)
+		responseSender.AssertExpectations(t)
+	})
+	t.Run("success", func(t *testing.T) {
+		// when
+		pl := &player.Player{
+			Id: "spectator",
+		}
+
+		watch := make(chan *Spectator, 1)
+		s := Server{
+			clients: map[string]*player.Player{"spectator": pl},
+			rooms:   map[string]*Room{"room": {Id: "room", Watch: watch}},
+		}
+
+		// then
+		result := s.Spectate("room", pl, true, s.sender)
+		assert.True(t, result)
+		assert.Equal(t, &Spectator{Player: pl, FullView: true}, <-watch)
+		assert.Equal(t, 0, len(s.clients))
+	})
+}
+
+func TestServer_SpectatorReadLoop(t *testing.T) {
+	t.Run("forward requests with the id of the spectator and return him to the lobby after exit", func(t *testing.T) {
+		// when
+		req := web.BuildRequest("first", pkg.Shoot, map[string]interface{}{"x": "1", "y": "1"})
+		shoot, _ := json.Marshal(req)
+		req = web.BuildRequest("first", pkg.Exit, nil)
+		exit, _ := json.Marshal(req)
+
+		con := &connection.Connection{}
+		con.On("ReadMessage").Return(0, shoot, nil).Once()
+		con.On("ReadMessage").Return(0, exit, nil).Once()
+		con.On("ReadMessage").Return(0, nil, errors.New("read failure")).Once()
+
+		lobby := web.BuildResponse(pkg.Lobby, "You are back in the lobby.", nil)
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", lobby, con).Return(nil).Once()
+
+		spectator := &player.Player{
+			Id:   "spectator",
+			Conn: con,
+		}
+		watching := make(chan web.Request, 2)
+		s := &Server{
+			clients: map[string]*player.Player{},
+			sender:  responseSender,
+		}
+
+		// then
+		s.SpectatorReadLoop(spectator, watching, make(chan struct{}))
+		assert.Equal(t, web.BuildRequest("spectator", pkg.Shoot, map[string]interface{}{"x": "1", "y": "1"}), <-watching)
+		assert.Equal(t, web.BuildRequest("spectator", pkg.Exit, nil), <-watching)
+		assert.Equal(t, 0, len(s.clients))
+		con.AssertExpectations(t)
+		responseSender.AssertExpectations(t)
+	})
+	t.Run("forward disconnect when error occurs while reading from connection", func(t *testing.T) {
+		// when
+		con := &connection.Connection{}
+		con.On("ReadMessage").Return(0, nil, errors.New("read failure")).Once()
+
+		spectator := &player.Player{
+			Id:   "spectator",
+			Conn: con,
+		}
+		watching := make(chan web.Request, 1)
+
+		// then
+		(&Server{}).SpectatorReadLoop(spectator, watching, make(chan struct{}))
+		assert.Equal(t, web.BuildRequest("spectator", pkg.Disconnect, nil), <-watching)
+		con.AssertExpectations(t)
+	})
+	t.Run("stop when room is closed and spectator is disconnected", func(t *testing.T) {
+		// when
+		con := &connection.Connection{}
+		con.On("ReadMessage").Return(0, nil, errors.New("read failure")).Once()
+
+		spectator := &player.Player{
+			Id:   "spectator",
+			Conn: con,
+		}
+		closed := make(chan struct{})
+		close(closed)
+
+		// then
+		(&Server{}).SpectatorReadLoop(spectator, make(chan web.Request), closed)
+		con.AssertExpectations(t)
+	})
+	t.Run("pass requests to the lobby when room is closed", func(t *testing.T) {
+		// when
+		req := web.BuildRequest("spectator", pkg.JoinRandom, nil)
+		joinRandom, _ := json.Marshal(req)
+
+		con := &connection.Connection{}
+		con.On("ReadMessage").Return(0, joinRandom, nil).Once()
+		con.On("ReadMessage").Return(0, nil, errors.New("read failure")).Once()
+
+		resp := web.BuildErrorResponse(web.NewError(web.NoFreeRooms, "there are no free rooms at the moment"))
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", resp, con).Return(nil).Once()
+
+		spectator := &player.Player{
+			Id:   "spectator",
+			Conn: con,
+		}
+		closed := make(chan struct{})
+		close(closed)
+		s := &Server{
+			clients: map[string]*player.Player{"spectator": spectator},
+			sender:  responseSender,
+		}
+
+		// then
+		s.SpectatorReadLoop(spectator, make(chan web.Request), closed)
+		assert.Equal(t, 0, len(s.clients))
+		con.AssertExpectations(t)
+		responseSender.AssertExpectations(t)
+	})
+}
+
+func TestRoom_ProcessSpectatorCommand(t *testing.T) {
+	t.Run("reject game actions", func(t *testing.T) {
+		// when
+		spectatorConn := &connection.Connection{}
+		resp := web.BuildErrorResponse(web.NewError(web.ActionNotAllowed, "Spectators can only watch the game."))
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", resp, spectatorConn).Return(nil).Once()
+
+		room := &Room{
+			Current:    &player.Player{Id: "first"},
+			Next:       &player.Player{Id: "second"},
+			Phase:      pkg.Shoot,
+			Spectators: map[string]*Spectator{"spectator": {Player: &player.Player{Id: "spectator", Conn: spectatorConn}}},
+			Sender:     responseSender,
+		}
+
+		// then
+		room.ProcessSpectatorCommand(web.BuildRequest("spectator", pkg.Shoot, map[string]interface{}{"x": "1", "y": "1"}))
+		assert.Equal(t, "first", room.Current.Id)
+		assert.Equal(t, pkg.Shoot, room.Phase)
+		responseSender.AssertExpectations(t)
+	})
+	t.Run("spectator exits", func(t *testing.T) {
+		// when
+		spectatorConn := &connection.Connection{}
+
+		resp := web.BuildResponse(pkg.Info, "Spectators watching: 0.", map[string]interface{}{"spectators": 0})
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", resp, firstConn).Return(nil).Once()
+		responseSender.On("SendResponse", resp, secondConn).Return(nil).Once()
+
+		room := &Room{
+			Current:    &player.Player{Id: "first", Conn: firstConn},
+			Next:       &player.Player{Id: "second", Conn: secondConn},
+			Spectators: map[string]*Spectator{"spectator": {Player: &player.Player{Id: "spectator", Conn: spectatorConn}}},
+			Sender:     responseSender,
+		}
+
+		// then
+		room.ProcessSpectatorCommand(web.BuildRequest("spectator", pkg.Exit, nil))
+		assert.Equal(t, 0, len(room.Spectators))
+		spectatorConn.AssertExpectations(t)
+		responseSender.AssertExpectations(t)
+	})
+	t.Run("spectator disconnects", func(t *testing.T) {
+		// when
+		spectatorConn := &connection.Connection{}
+		spectatorConn.On("Close").Return(nil).Once()
+
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", mock.Anything, mock.Anything).Return(nil).Twice()
+
+		room := &Room{
+			Current:    &player.Player{Id: "first", Conn: firstConn},
+			Next:       &player.Player{Id: "second", Conn: secondConn},
+			Spectators: map[string]*Spectator{"spectator": {Player: &player.Player{Id: "spectator", Conn: spectatorConn}}},
+			Sender:     responseSender,
+		}
+
+		// then
+		room.ProcessSpectatorCommand(web.BuildRequest("spectator", pkg.Disconnect, nil))
+		assert.Equal(t, 0, len(room.Spectators))
+		assert.Equal(t, 0, countSent(responseSender, spectatorConn))
+		spectatorConn.AssertExpectations(t)
+		responseSender.AssertExpectations(t)
+	})
+}
+
+func TestRoom_AddSpectator(t *testing.T) {
+	t.Run("notify spectator and players", func(t *testing.T) {
+		// when
+		spectatorConn := &connection.Connection{}
+		spectate := web.BuildResponse(pkg.Spectate, "You are watching room room.",
+			map[string]interface{}{"id": "room", "fullView": false, "phase": pkg.Shooting})
+		count := web.BuildResponse(pkg.Info, "Spectators watching: 1.", map[string]interface{}{"spectators": 1})
+
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", spectate, spectatorConn).Return(nil).Once()
+		responseSender.On("SendResponse", count, firstConn).Return(nil).Once()
+		responseSender.On("SendResponse", count, secondConn).Return(nil).Once()
+
+		room := &Room{
+			Current: &player.Player{Id: "first", Conn: firstConn},
+			Next:    &player.Player{Id: "second", Conn: secondConn},
+			Id:      "room",
+			Phase:   pkg.Shoot,
+			Sender:  responseSender,
+		}
+
+		// then
+		room.addSpectator(&Spectator{Player: &player.Player{Id: "spectator", Conn: spectatorConn}})
+		assert.Equal(t, 1, room.GetInfo().Spectators)
+		responseSender.AssertExpectations(t)
+	})
+}
+
+func TestRoom_BroadcastShot(t *testing.T) {
+	t.Run("delay events for spectators with full view", func(t *testing.T) {
+		// when
+		limitedConn := &connection.Connection{}
+		fullConn := &connection.Connection{}
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", mock.Anything, mock.Anything).Return(nil).Times(spectatorDelay + 2)
+
+		room := &Room{
+			Current: &player.Player{Id: "first", Name: "first", Board: game.InitBoard()},
+			Next:    &player.Player{Id: "second", Name: "second", Board: getBoard()},
+			Spectators: map[string]*Spectator{
+				"limited": {Player: &player.Player{Id: "limited", Conn: limitedConn}},
+				"full":    {Player: &player.Player{Id: "full", Conn: fullConn}, FullView: true},
+			},
+			Sender: responseSender,
+		}
+
+		// then
+		for i := 0; i <= spectatorDelay; i++ {
+			room.broadcastShot(game.Position{X: i, Y: 0}, false, false)
+		}
+		assert.Equal(t, spectatorDelay, len(room.delayed))
+		responseSender.AssertExpectations(t)
+
+		var limited, full []web.Response
+		for _, call := range responseSender.Calls {
+			switch call.Arguments.Get(1) {
+			case limitedConn:
+				limited = append(limited, call.Arguments.Get(0).(web.Response))
+			case fullConn:
+				full = append(full, call.Arguments.Get(0).(web.Response))
+			}
+		}
+		assert.Equal(t, spectatorDelay+1, len(limited))
+		assert.Equal(t, web.BuildResponse(pkg.Event, "first shot at A0.", map[string]interface{}{
+			"shooter": "first",
+			"target":  "second",
+			"x":       0,
+			"y":       0,
+			"hit":     false,
+			"sunk":    false,
+			"turn":    "second",
+		}), limited[0])
+
+		assert.Equal(t, 1, len(full))
+		assert.Equal(t, "first shot at A0.", full[0].Message)
+		assert.Equal(t, getBoard().GetOwnFields(), full[0].Args["targetFields"])
+	})
+}
+
+func TestRoom_BroadcastResult(t *testing.T) {
+	t.Run("release delayed events and reveal fleets", func(t *testing.T) {
+		// when
+		spectatorConn := &connection.Connection{}
+		delayed := web.BuildResponse(pkg.Event, "first shot at A0.", nil)
+		winnerBoard := game.InitBoard()
+		loserBoard := getBoard()
+		gameOver := web.BuildResponse(pkg.GameOver, "first wins the game.", map[string]interface{}{
+			"winner":       "first",
+			"loser":        "second",
+			"winnerFields": winnerBoard.GetOwnFields(),
+			"loserFields":  loserBoard.GetOwnFields(),
+		})
+
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", delayed, spectatorConn).Return(nil).Once()
+		responseSender.On("SendResponse", gameOver, spectatorConn).Return(nil).Once()
+
+		winner := &player.Player{Id: "first", Name: "first", Board: winnerBoard}
+		loser := &player.Player{Id: "second", Name: "second", Board: loserBoard}
+		room := &Room{
+			Current:    winner,
+			Next:       loser,
+			Spectators: map[string]*Spectator{"spectator": {Player: &player.Player{Id: "spectator", Conn: spectatorConn}, FullView: true}},
+			Sender:     responseSender,
+			delayed:    []web.Response{delayed},
+		}
+
+		// then
+		room.broadcastResult(winner, loser)
+		assert.Equal(t, 0, len(room.delayed))
+		responseSender.AssertExpectations(t)
+	})
+}
+
+func TestRoom_BroadcastTurn(t *testing.T) {
+	t.Run("notify all spectators who shoots first", func(t *testing.T) {
+		// when
+		limitedConn := &connection.Connection{}
+		fullConn := &connection.Connection{}
+		resp := web.BuildResponse(pkg.Event, "All ships are placed. first shoots first.",
+			map[string]interface{}{"turn": "first"})
+
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", resp, mock.Anything).Return(nil).Twice()
+
+		room := &Room{
+			Current: &player.Player{Id: "first", Name: "first"},
+			Next:    &player.Player{Id: "second", Name: "second"},
+			Spectators: map[string]*Spectator{
+				"limited": {Player: &player.Player{Id: "limited", Conn: limitedConn}},
+				"full":    {Player: &player.Player{Id: "full", Conn: fullConn}, FullView: true},
+			},
+			Sender: responseSender,
+		}
+
+		// then
+		room.broadcastTurn()
+		responseSender.AssertExpectations(t)
+		assert.Equal(t, 1, countSent(responseSender, limitedConn))
+		assert.Equal(t, 1, countSent(responseSender, fullConn))
+	})
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/StanislavStefanov/Battleships/server/player"
+)
+
+//processState sends the player Response with status State, the snapshot of his game kept by the
+//room: the phase, whether he is on turn, his own and enemy fields, the length of the ship he has to
+//place now, the ships he has yet to place and which are not sunk yet and the tally of the enemy ships
+//once the shooting has started. The clients use it to resync their boards if some of the responses
+//has been lost. It can be requested at any time, regardless of whose turn it is.
+func (r *Room) processState(p *player.Player) {
+	turn := r.Current == p && r.Next != nil && (r.Phase == pkg.PlaceShip || r.Phase == pkg.Shoot)
+	phase := r.getPhaseInfo()
+	if r.Phase == pkg.Rematch {
+		phase = pkg.Rematch
+	}
+
+	placed, afloat := p.Board.CountShips()
+	toPlace := make(map[int]int)
+	for length, count := range r.Rules.Fleet {
+		if left := count - placed[length]; left > 0 {
+			toPlace[length] = left
+		}
+	}
+
+	args := map[string]interface{}{
+		"phase":       phase,
+		"rules":       r.Rules.Name,
+		"turn":        turn,
+		"ownFields":   p.Board.GetOwnFields(),
+		"enemyFields": p.Board.GetEnemyFields(),
+		"toPlace":     toPlace,
+		"afloat":      afloat,
+	}
+	if turn && r.Phase == pkg.PlaceShip {
+		args["nextShip"] = r.Placement.NextShip()
+	}
+	if _, enemy := r.getPlayers(p.Id); enemy != nil && (r.Phase == pkg.Shoot || r.Phase == pkg.Rematch) {
+		args["fleet"] = toFleet(enemy.Board.Tally())
+	}
+	r.Sender.SendResponse(web.BuildResponse(pkg.State, "", args), p.Conn)
+}
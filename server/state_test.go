@@ -0,0 +1,106 @@
+package main
+
+import (
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/StanislavStefanov/Battleships/server/automock"
+	"github.com/StanislavStefanov/Battleships/server/player"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRoom_ProcessState(t *testing.T) {
+	t.Run("send the ship to place to the player on turn", func(t *testing.T) {
+		// when
+		board := getBoard()
+		expected := web.BuildResponse(pkg.State, "", map[string]interface{}{
+			"phase":       pkg.Placing,
+			"rules":       game.Classic,
+			"turn":        true,
+			"ownFields":   board.GetOwnFields(),
+			"enemyFields": board.GetEnemyFields(),
+			"toPlace":     map[int]int{5: 1, 4: 1, 3: 3, 2: 4},
+			"afloat":      map[int]int{4: 1},
+			"nextShip":    destroyer,
+		})
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", expected, firstConn).Return(nil).Once()
+
+		room := &Room{
+			Current: &player.Player{Id: "first", Conn: firstConn, Board: board},
+			Next:    &player.Player{Id: "second", Conn: secondConn, Board: game.InitBoard()},
+			Phase:   pkg.PlaceShip,
+			Sender:  responseSender,
+		}
+		room.ApplyRules(game.DefaultRules())
+
+		// then
+		room.ProcessCommand(web.BuildRequest("first", pkg.State, nil))
+		responseSender.AssertExpectations(t)
+	})
+	t.Run("send the state to the player who waits for his turn", func(t *testing.T) {
+		// when
+		board := getBoard()
+		_ = board.Attack(game.Position{X: 0, Y: 0}, false)
+		_, _, _ = board.ReceiveAttack(game.Position{X: 3, Y: 3})
+		compact, _ := game.GetRules(game.Compact)
+		expected := web.BuildResponse(pkg.State, "", map[string]interface{}{
+			"phase":       pkg.Shooting,
+			"rules":       game.Compact,
+			"turn":        false,
+			"ownFields":   board.GetOwnFields(),
+			"enemyFields": board.GetEnemyFields(),
+			"toPlace":     map[int]int{5: 1, 3: 2, 2: 1},
+			"afloat":      map[int]int{4: 1},
+			"fleet":       []web.FleetClass{{Class: "battleship", Length: 4, Afloat: 1}},
+		})
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", expected, secondConn).Return(nil).Once()
+
+		room := &Room{
+			Current: &player.Player{Id: "first", Conn: firstConn, Board: getBoard()},
+			Next:    &player.Player{Id: "second", Conn: secondConn, Board: board},
+			Phase:   pkg.Shoot,
+			Rules:   compact,
+			Sender:  responseSender,
+		}
+
+		// then
+		room.ProcessCommand(web.BuildRequest("second", pkg.State, nil))
+		responseSender.AssertExpectations(t)
+	})
+	t.Run("send the state after the game", func(t *testing.T) {
+		// when
+		board := getBoard()
+		for x := 3; x < 7; x++ {
+			_, _, _ = board.ReceiveAttack(game.Position{X: x, Y: 3})
+		}
+		expected := web.BuildResponse(pkg.State, "", map[string]interface{}{
+			"phase":       pkg.Rematch,
+			"rules":       game.Classic,
+			"turn":        false,
+			"ownFields":   board.GetOwnFields(),
+			"enemyFields": board.GetEnemyFields(),
+			"toPlace":     map[int]int{5: 1, 4: 1, 3: 3, 2: 4},
+			"afloat":      map[int]int{},
+			"fleet":       []web.FleetClass{},
+		})
+		responseSender := &automock.ResponseSender{}
+		responseSender.On("SendResponse", expected, firstConn).Return(nil).Once()
+
+		room := &Room{
+			Current: &player.Player{Id: "first", Conn: firstConn, Board: board},
+			Next:    &player.Player{Id: "second", Conn: secondConn, Board: game.InitBoard()},
+			Phase:   pkg.Rematch,
+			Rules:   game.DefaultRules(),
+			Sender:  responseSender,
+			rematch: map[string]bool{},
+		}
+
+		// then
+		room.ProcessCommand(web.BuildRequest("first", pkg.State, nil))
+		assert.Equal(t, pkg.Rematch, room.Phase)
+		responseSender.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//staticFiles contains the browser client, which is embedded into the server binary, so it can be
+//served without any files next to the binary.
+//go:embed static
+var staticFiles embed.FS
+
+//StaticHandler returns handler serving the browser client. The client shows the lobby, the own and the
+//enemy grids with drag-and-drop ship placement and speaks the same protocol over /ws as the console client.
+func StaticHandler() http.Handler {
+	files, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServer(http.FS(files))
+}
@@ -0,0 +1,413 @@
+// Browser client of the Battleships server. It speaks the same protocol (version 1, JSON) over /ws as
+// the console client: every request is {"playerId", "action", "args"} and every response is
+// {"action", "message", "args", "code"}.
+(function () {
+    "use strict";
+
+    const boardSize = 10;
+    const rows = "ABCDEFGHIJ";
+
+    const state = {
+        socket: null,
+        id: "",
+        view: "connecting",
+        shipLength: 0,
+        vertical: false,
+        myTurn: false,
+        lastShot: null,
+    };
+
+    const $ = (id) => document.getElementById(id);
+
+    function send(action, args) {
+        if (!state.socket || state.socket.readyState !== WebSocket.OPEN) {
+            log("Not connected to the server.", "error");
+            return;
+        }
+        state.socket.send(JSON.stringify({playerId: state.id, action: action, args: args || {}}));
+    }
+
+    function log(text, kind) {
+        const item = document.createElement("li");
+        item.textContent = text;
+        if (kind) {
+            item.className = kind;
+        }
+        const list = $("log");
+        list.appendChild(item);
+        list.scrollTop = list.scrollHeight;
+    }
+
+    function setStatus(text) {
+        $("status").textContent = text;
+    }
+
+    function show(view) {
+        state.view = view;
+        $("lobby").hidden = view !== "lobby";
+        $("game").hidden = view !== "game";
+    }
+
+    // Grids
+
+    function buildGrid(element, onCell) {
+        element.innerHTML = "";
+        element.appendChild(label(""));
+        for (let y = 0; y < boardSize; y++) {
+            element.appendChild(label(String(y)));
+        }
+        for (let x = 0; x < boardSize; x++) {
+            element.appendChild(label(rows[x]));
+            for (let y = 0; y < boardSize; y++) {
+                const cell = document.createElement("div");
+                cell.className = "cell";
+                cell.dataset.x = String(x);
+                cell.dataset.y = String(y);
+                onCell(cell, x, y);
+                element.appendChild(cell);
+            }
+        }
+    }
+
+    function label(text) {
+        const element = document.createElement("div");
+        element.className = "label";
+        element.textContent = text;
+        return element;
+    }
+
+    function cellAt(grid, x, y) {
+        return $(grid).querySelector(`.cell[data-x="${x}"][data-y="${y}"]`);
+    }
+
+    function shipFields(x, y, direction, length) {
+        const fields = [];
+        for (let i = 0; i < length; i++) {
+            switch (direction) {
+                case "up":
+                    fields.push([x - i, y]);
+                    break;
+                case "down":
+                    fields.push([x + i, y]);
+                    break;
+                case "left":
+                    fields.push([x, y - i]);
+                    break;
+                default:
+                    fields.push([x, y + i]);
+            }
+        }
+        return fields;
+    }
+
+    function resetBoards() {
+        buildGrid($("own-grid"), (cell, x, y) => {
+            cell.addEventListener("click", () => placeShip(x, y));
+            cell.addEventListener("dragover", (event) => {
+                if (state.shipLength > 0) {
+                    event.preventDefault();
+                    preview(x, y);
+                }
+            });
+            cell.addEventListener("dragleave", clearPreview);
+            cell.addEventListener("drop", (event) => {
+                event.preventDefault();
+                clearPreview();
+                placeShip(x, y);
+            });
+        });
+        buildGrid($("enemy-grid"), (cell, x, y) => {
+            cell.addEventListener("click", () => shoot(x, y));
+        });
+        state.shipLength = 0;
+        state.myTurn = false;
+        state.lastShot = null;
+        updateControls();
+    }
+
+    function direction() {
+        return state.vertical ? "down" : "right";
+    }
+
+    function preview(x, y) {
+        clearPreview();
+        for (const [fx, fy] of shipFields(x, y, direction(), state.shipLength)) {
+            const cell = cellAt("own-grid", fx, fy);
+            if (cell) {
+                cell.classList.add("preview");
+            }
+        }
+    }
+
+    function clearPreview() {
+        for (const cell of $("own-grid").querySelectorAll(".preview")) {
+            cell.classList.remove("preview");
+        }
+    }
+
+    function placeShip(x, y) {
+        if (state.shipLength === 0) {
+            return;
+        }
+        send("place", {x: x, y: y, direction: direction()});
+    }
+
+    function shoot(x, y) {
+        const cell = cellAt("enemy-grid", x, y);
+        if (!state.myTurn || cell.classList.contains("hit") || cell.classList.contains("miss") ||
+            cell.classList.contains("sunk")) {
+            return;
+        }
+        state.lastShot = {x: x, y: y};
+        send("shoot", {x: x, y: y});
+    }
+
+    function markShot(grid, x, y, hit) {
+        const cell = cellAt(grid, x, y);
+        if (!cell) {
+            return;
+        }
+        cell.classList.remove("ship");
+        cell.classList.add(hit ? "hit" : "miss");
+    }
+
+    // markSunk marks all hit fields connected to the field as sunk, as they belong to the same ship.
+    function markSunk(grid, x, y) {
+        const queue = [[x, y]];
+        while (queue.length > 0) {
+            const [cx, cy] = queue.pop();
+            const cell = cellAt(grid, cx, cy);
+            if (!cell || !cell.classList.contains("hit")) {
+                continue;
+            }
+            cell.classList.remove("hit");
+            cell.classList.add("sunk");
+            queue.push([cx - 1, cy], [cx + 1, cy], [cx, cy - 1], [cx, cy + 1]);
+        }
+    }
+
+    function updateControls() {
+        $("placement").hidden = state.shipLength === 0;
+        $("enemy-grid").classList.toggle("active", state.myTurn);
+        $("dock").classList.toggle("vertical", state.vertical);
+        $("dock").innerHTML = "";
+        for (let i = 0; i < state.shipLength; i++) {
+            const cell = document.createElement("div");
+            cell.className = "cell";
+            $("dock").appendChild(cell);
+        }
+    }
+
+    function enterGame() {
+        if (state.view !== "game") {
+            resetBoards();
+            $("rematch").hidden = true;
+            show("game");
+        }
+    }
+
+    // Lobby
+
+    function listRooms() {
+        send("ls-rooms", {});
+    }
+
+    function renderRooms(rooms) {
+        const body = $("rooms").querySelector("tbody");
+        body.innerHTML = "";
+        $("no-rooms").hidden = rooms.length > 0;
+        for (const room of rooms) {
+            const row = document.createElement("tr");
+            for (const value of [room.creator, room.rules, `${room.players}/2`, room.spectators, room.phase]) {
+                const cell = document.createElement("td");
+                cell.textContent = String(value);
+                row.appendChild(cell);
+            }
+            const actions = document.createElement("td");
+            if (room.players < 2) {
+                actions.appendChild(button("Join", () => send("join-room", {roomId: room.id})));
+            }
+            actions.appendChild(button("Watch", () => send("spectate", {roomId: room.id})));
+            row.appendChild(actions);
+            body.appendChild(row);
+        }
+    }
+
+    function button(text, onClick) {
+        const element = document.createElement("button");
+        element.type = "button";
+        element.textContent = text;
+        element.addEventListener("click", onClick);
+        return element;
+    }
+
+    // Responses
+
+    function handle(resp) {
+        const args = resp.args || {};
+        switch (resp.action) {
+            case "register":
+                state.id = args.id;
+                setStatus("Connected");
+                show("lobby");
+                listRooms();
+                break;
+            case "info":
+                if (Array.isArray(args.rooms)) {
+                    renderRooms(args.rooms);
+                } else if (resp.message) {
+                    log(resp.message);
+                }
+                break;
+            case "wait":
+                enterGame();
+                log(resp.message, resp.code ? "error" : "");
+                setStatus(resp.message);
+                break;
+            case "place":
+                state.shipLength = Number(args.length) || 0;
+                updateControls();
+                setStatus(resp.message);
+                break;
+            case "placed":
+                for (const [x, y] of shipFields(args.x, args.y, args.direction, args.length)) {
+                    cellAt("own-grid", x, y).classList.add("ship");
+                }
+                state.shipLength = 0;
+                updateControls();
+                setStatus("Wait for your opponent.");
+                break;
+            case "shoot":
+                if (args.x !== undefined) {
+                    markShot("own-grid", args.x, args.y, args.hit);
+                    log(`Opponent shot at ${rows[args.x]}${args.y}: ${args.hit ? (args.sunk ? "sunk" : "hit") : "miss"}.`);
+                }
+                state.shipLength = 0;
+                state.myTurn = true;
+                updateControls();
+                setStatus("Your turn - click a field in the enemy waters.");
+                break;
+            case "shoot-outcome":
+                markShot("enemy-grid", args.x, args.y, args.hit);
+                if (args.sunk) {
+                    markSunk("enemy-grid", args.x, args.y);
+                }
+                log(`You shot at ${rows[args.x]}${args.y}: ${args.hit ? (args.sunk ? "sunk" : "hit") : "miss"}.`);
+                state.myTurn = false;
+                updateControls();
+                setStatus("Wait for your opponent.");
+                break;
+            case "win":
+                if (state.lastShot) {
+                    markShot("enemy-grid", state.lastShot.x, state.lastShot.y, true);
+                    markSunk("enemy-grid", state.lastShot.x, state.lastShot.y);
+                }
+                state.myTurn = false;
+                updateControls();
+                log(resp.message);
+                setStatus(resp.message);
+                break;
+            case "lose":
+                log(resp.message);
+                setStatus(resp.message);
+                break;
+            case "rematch":
+                $("rematch").hidden = false;
+                log(resp.message);
+                break;
+            case "lobby":
+                show("lobby");
+                setStatus("Connected");
+                log(resp.message);
+                listRooms();
+                break;
+            case "spectate":
+                enterGame();
+                setStatus(resp.message);
+                break;
+            case "event":
+            case "game-over":
+                log(resp.message);
+                break;
+            case "chat":
+                log(`${args.from} (${args.channel}): ${resp.message}`, "chat");
+                break;
+            case "retry":
+                log(`${resp.message}${resp.code ? ` [${resp.code}]` : ""}`, "error");
+                break;
+            case "ack":
+                break;
+            default:
+                if (resp.message) {
+                    log(resp.message);
+                }
+        }
+    }
+
+    function decode(data) {
+        if (typeof data === "string") {
+            return JSON.parse(data);
+        }
+        return JSON.parse(new TextDecoder().decode(data));
+    }
+
+    function connect() {
+        const protocol = location.protocol === "https:" ? "wss:" : "ws:";
+        const socket = new WebSocket(`${protocol}//${location.host}/ws`);
+        socket.binaryType = "arraybuffer";
+        socket.addEventListener("message", (event) => {
+            try {
+                handle(decode(event.data));
+            } catch (err) {
+                log(`Invalid message from the server: ${err}`, "error");
+            }
+        });
+        socket.addEventListener("close", () => {
+            setStatus("Disconnected - reload the page to connect again.");
+            show("connecting");
+        });
+        state.socket = socket;
+    }
+
+    // Controls
+
+    $("create-form").addEventListener("submit", (event) => {
+        event.preventDefault();
+        const args = {rules: $("rules").value};
+        const name = $("name").value.trim();
+        if (name) {
+            args.name = name;
+        }
+        send("create-room", args);
+    });
+    $("join-random").addEventListener("click", () => send("join-random", {}));
+    $("refresh").addEventListener("click", listRooms);
+    $("leave").addEventListener("click", () => send("exit", {}));
+    $("rotate").addEventListener("click", () => {
+        state.vertical = !state.vertical;
+        updateControls();
+    });
+    $("dock").draggable = true;
+    $("dock").addEventListener("dragstart", (event) => {
+        event.dataTransfer.setData("text/plain", String(state.shipLength));
+    });
+    $("accept-rematch").addEventListener("click", () => {
+        $("rematch").hidden = true;
+        resetBoards();
+        send("rematch", {accept: true});
+    });
+    $("decline-rematch").addEventListener("click", () => {
+        $("rematch").hidden = true;
+        send("rematch", {accept: false});
+    });
+    $("chat-form").addEventListener("submit", (event) => {
+        event.preventDefault();
+        const text = $("chat-text").value.trim();
+        if (text) {
+            send("chat", {text: text});
+            $("chat-text").value = "";
+        }
+    });
+
+    connect();
+})();
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStaticHandler(t *testing.T) {
+	tests := []struct {
+		path        string
+		contentType string
+		contains    string
+	}{
+		{"/", "text/html; charset=utf-8", "<title>Battleships</title>"},
+		{"/app.js", "text/javascript; charset=utf-8", "/ws"},
+		{"/style.css", "text/css; charset=utf-8", ".grid"},
+	}
+	for _, test := range tests {
+		t.Run("serve "+test.path, func(t *testing.T) {
+			// when
+			rec := httptest.NewRecorder()
+			StaticHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+			// then
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, test.contentType, rec.Header().Get("Content-Type"))
+			assert.Contains(t, rec.Body.String(), test.contains)
+		})
+	}
+	t.Run("fail when the file doesn't exist", func(t *testing.T) {
+		// when
+		rec := httptest.NewRecorder()
+		StaticHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing.js", nil))
+
+		// then
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
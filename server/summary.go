@@ -0,0 +1,90 @@
+package main
+
+import (
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/StanislavStefanov/Battleships/server/player"
+	"time"
+)
+
+//gameStats counts the moves of the player in the current game: the shots he has fired, the hits, the
+//current and the longest run of hits in a row, his turns, both placements and shots, and the time he
+//has spent on them.
+type gameStats struct {
+	shots         int
+	hits          int
+	streak        int
+	longestStreak int
+	turns         int
+	timeSpent     time.Duration
+}
+
+//resetStats forgets the stats of the previous game and starts measuring the time of the first turn.
+func (r *Room) resetStats() {
+	r.stats = make(map[string]*gameStats)
+	r.turnStartedAt = time.Now()
+}
+
+func (r *Room) getStats(p *player.Player) *gameStats {
+	if r.stats == nil {
+		r.stats = make(map[string]*gameStats)
+	}
+	stats, ok := r.stats[p.Id]
+	if !ok {
+		stats = &gameStats{}
+		r.stats[p.Id] = stats
+	}
+	return stats
+}
+
+//endTurn counts the turn of the current player and the time he has spent on it. The time of the next
+//turn is measured from now.
+func (r *Room) endTurn() {
+	stats := r.getStats(r.Current)
+	stats.turns++
+	now := time.Now()
+	if !r.turnStartedAt.IsZero() {
+		stats.timeSpent += now.Sub(r.turnStartedAt)
+	}
+	r.turnStartedAt = now
+}
+
+//recordShot counts the shot of the current player.
+func (r *Room) recordShot(hit bool) {
+	stats := r.getStats(r.Current)
+	stats.shots++
+	if !hit {
+		stats.streak = 0
+		return
+	}
+	stats.hits++
+	stats.streak++
+	if stats.streak > stats.longestStreak {
+		stats.longestStreak = stats.streak
+	}
+}
+
+//buildSummary returns the args of win and lose responses, which reveal the boards of both players
+//together with their stats.
+func (r *Room) buildSummary(winner, loser *player.Player) map[string]interface{} {
+	return map[string]interface{}{
+		"winner": r.summarize(winner),
+		"loser":  r.summarize(loser),
+	}
+}
+
+func (r *Room) summarize(p *player.Player) web.PlayerSummary {
+	stats := r.getStats(p)
+	summary := web.PlayerSummary{
+		Name:          p.GetName(),
+		Fields:        getFields(p),
+		Shots:         stats.shots,
+		Hits:          stats.hits,
+		LongestStreak: stats.longestStreak,
+		Turns:         stats.turns,
+		TimeSpent:     stats.timeSpent.Seconds(),
+	}
+	if stats.shots > 0 {
+		summary.HitPercentage = 100 * float64(stats.hits) / float64(stats.shots)
+	}
+	return summary
+}
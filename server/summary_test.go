@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/StanislavStefanov/Battleships/pkg/game"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/StanislavStefanov/Battleships/server/player"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRoom_BuildSummary(t *testing.T) {
+	t.Run("count the shots and the longest run of hits", func(t *testing.T) {
+		// when
+		first := &player.Player{Id: "first", Name: "Alice", Board: getBoard()}
+		second := &player.Player{Id: "second", Board: game.InitBoard()}
+		room := &Room{Current: first, Next: second}
+		room.resetStats()
+		for _, hit := range []bool{true, false, true, true, true, false} {
+			room.recordShot(hit)
+			room.endTurn()
+		}
+		room.switchPlayers()
+		room.recordShot(false)
+		room.endTurn()
+		summary := room.buildSummary(first, second)
+
+		// then
+		winner := summary["winner"].(web.PlayerSummary)
+		loser := summary["loser"].(web.PlayerSummary)
+		assert.Equal(t, "Alice", winner.Name)
+		assert.Equal(t, getBoard().GetOwnFields(), winner.Fields)
+		assert.Equal(t, 6, winner.Shots)
+		assert.Equal(t, 4, winner.Hits)
+		assert.InDelta(t, 66.7, winner.HitPercentage, 0.1)
+		assert.Equal(t, 3, winner.LongestStreak)
+		assert.Equal(t, 6, winner.Turns)
+		assert.Equal(t, web.PlayerSummary{Name: "anonymous", Fields: game.InitBoard().GetOwnFields(), Shots: 1, Turns: 1,
+			TimeSpent: loser.TimeSpent}, loser)
+	})
+	t.Run("measure the time spent on the turns", func(t *testing.T) {
+		// when
+		first := &player.Player{Id: "first", Board: game.InitBoard()}
+		second := &player.Player{Id: "second", Board: game.InitBoard()}
+		room := &Room{Current: first, Next: second}
+		room.resetStats()
+		room.turnStartedAt = time.Now().Add(-2 * time.Second)
+		room.endTurn()
+		summary := room.buildSummary(first, second)
+
+		// then
+		assert.InDelta(t, 2, summary["winner"].(web.PlayerSummary).TimeSpent, 0.5)
+		assert.Zero(t, summary["loser"].(web.PlayerSummary).TimeSpent)
+	})
+}
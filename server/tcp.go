@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"github.com/StanislavStefanov/Battleships/server/player"
+	"net"
+)
+
+//ListenTCP listens for raw TCP connections on the provided address and serves them through ServeTCP.
+func (s *Server) ListenTCP(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.ServeTCP(listener)
+}
+
+//ServeTCP accepts connections from the listener and registers their clients the same way as the
+//websocket clients. The messages on these connections are delimited by new lines. The method returns
+//when the listener fails to accept connection, e.g. because it is closed.
+func (s *Server) ServeTCP(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		fmt.Println("tcp connection has arrived")
+		s.register <- player.NewTextProtocolConnection(player.NewSyncConnection(player.NewTCPConnection(conn)))
+	}
+}
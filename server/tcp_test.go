@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"github.com/StanislavStefanov/Battleships/pkg"
+	"github.com/StanislavStefanov/Battleships/pkg/web"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+)
+
+func TestServer_ServeTCP(t *testing.T) {
+	t.Run("register tcp client and process his requests", func(t *testing.T) {
+		// when
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(t, err)
+
+		s := NewServer()
+		s.Start()
+		served := make(chan error)
+		go func() {
+			served <- s.ServeTCP(listener)
+		}()
+
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		assert.Nil(t, err)
+		reader := bufio.NewReader(conn)
+
+		// then
+		line, err := reader.ReadBytes('\n')
+		assert.Nil(t, err)
+		resp, _, err := web.DecodeResponse(line)
+		assert.Nil(t, err)
+		assert.Equal(t, pkg.Register, resp.GetAction())
+
+		_, err = conn.Write([]byte(`{"action":"ls-rooms"}` + "\n"))
+		assert.Nil(t, err)
+		line, err = reader.ReadBytes('\n')
+		assert.Nil(t, err)
+		resp, _, err = web.DecodeResponse(line)
+		assert.Nil(t, err)
+		assert.Equal(t, pkg.Info, resp.GetAction())
+		assert.Equal(t, float64(0), resp.GetArgs()["total"])
+
+		_, err = conn.Write([]byte(`{"action":"exit"}` + "\n"))
+		assert.Nil(t, err)
+		_, err = reader.ReadBytes('\n')
+		assert.NotNil(t, err)
+
+		assert.Nil(t, listener.Close())
+		assert.NotNil(t, <-served)
+	})
+}